@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/handlers"
+	"wsicrmrest/internal/logger"
+	"wsicrmrest/internal/webhook"
+
+	"go.uber.org/zap"
+)
+
+// runCommand executa subcomandos de manutenção sem iniciar o servidor HTTP
+// Retorna handled = false quando args não corresponde a um subcomando conhecido
+func runCommand(args []string) (handled bool, exitCode int) {
+	switch args[0] {
+	case "rehash-secrets":
+		return true, withDatabase(func(cfg *config.Config, db *database.Database, log *zap.SugaredLogger) int {
+			return rehashSecrets(db, log, args[1:])
+		})
+	case "replay-webhooks":
+		return true, withDatabase(func(cfg *config.Config, db *database.Database, log *zap.SugaredLogger) int {
+			return replayWebhooks(cfg, db, log, args[1:])
+		})
+	default:
+		return false, 0
+	}
+}
+
+// withDatabase carrega dbinit.ini, logger e conexão com o banco para um subcomando
+func withDatabase(fn func(cfg *config.Config, db *database.Database, log *zap.SugaredLogger) int) int {
+	cfg, err := config.LoadConfig("dbinit.ini")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar configurações: %v\n", err)
+		return 1
+	}
+
+	log, err := logger.NewLogger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao inicializar logger: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	db, err := database.NewDatabase(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao conectar ao banco de dados: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	return fn(cfg, db, log)
+}
+
+// rehashSecrets converte os client_secret em texto plano de WSAPLICACOES para hash argon2id
+// Uso: wsicrmrest rehash-secrets [--dry-run]
+func rehashSecrets(db *database.Database, log *zap.SugaredLogger, args []string) int {
+	flags := flag.NewFlagSet("rehash-secrets", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "apenas lista as aplicações que seriam convertidas")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	secrets, err := db.ListClientSecrets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao ler WSAPLICACOES: %v\n", err)
+		return 1
+	}
+
+	var convertidos, ignorados, falhas int
+	for _, secret := range secrets {
+		if secret.Secret == "" || auth.IsHashedSecret(secret.Secret) {
+			ignorados++
+			continue
+		}
+
+		if *dryRun {
+			fmt.Printf("[dry-run] %s seria convertido\n", secret.ClientID)
+			convertidos++
+			continue
+		}
+
+		hash, err := auth.HashSecret(secret.Secret)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao gerar hash de %s: %v\n", secret.ClientID, err)
+			falhas++
+			continue
+		}
+
+		updated, err := db.ReplaceClientSecret(secret.ClientID, secret.Secret, hash)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao atualizar %s: %v\n", secret.ClientID, err)
+			falhas++
+			continue
+		}
+		if !updated {
+			// client_secret alterado por outro processo durante a conversão
+			fmt.Printf("%s alterado durante a conversão, ignorado\n", secret.ClientID)
+			ignorados++
+			continue
+		}
+
+		fmt.Printf("%s convertido\n", secret.ClientID)
+		log.Infow("Client_secret convertido para hash", "client_id", secret.ClientID)
+		convertidos++
+	}
+
+	fmt.Printf("Total: %d convertidos, %d ignorados, %d falhas\n", convertidos, ignorados, falhas)
+	log.Infow("rehash-secrets finalizado",
+		"dry_run", *dryRun,
+		"convertidos", convertidos,
+		"ignorados", ignorados,
+		"falhas", falhas)
+
+	if falhas > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replayWebhooks reprocessa requisições de webhook gravadas em WSREQUISICOES
+// Uso: wsicrmrest replay-webhooks [--uuid U] [--from DATA] [--to DATA] [--endpoint E] [--code N] [--limit N] [--dry-run]
+func replayWebhooks(cfg *config.Config, db *database.Database, log *zap.SugaredLogger, args []string) int {
+	flags := flag.NewFlagSet("replay-webhooks", flag.ContinueOnError)
+	uuid := flags.String("uuid", "", "UUID da requisição (WSREQUUID)")
+	from := flags.String("from", "", "recebidas a partir de (RFC 3339 ou \"2006-01-02 15:04:05\")")
+	to := flags.String("to", "", "recebidas até (RFC 3339 ou \"2006-01-02 15:04:05\")")
+	endpoint := flags.String("endpoint", "", "endpoint do webhook, ex: /webhook/zenvia/email")
+	code := flags.Int("code", 0, "código HTTP da resposta original")
+	limit := flags.Int("limit", handlers.WebhookReplayDefaultLimit, "máximo de requisições")
+	dryRun := flags.Bool("dry-run", false, "apenas simula o reprocessamento, sem gravar no banco")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := database.WebhookRequestFilter{
+		UUID:        *uuid,
+		Endpoint:    *endpoint,
+		CodResposta: *code,
+		Limite:      *limit,
+	}
+	var err error
+	if filter.De, err = parseReplayTime(*from); err != nil {
+		fmt.Fprintf(os.Stderr, "--from inválido: %v\n", err)
+		return 2
+	}
+	if filter.Ate, err = parseReplayTime(*to); err != nil {
+		fmt.Fprintf(os.Stderr, "--to inválido: %v\n", err)
+		return 2
+	}
+	if msg := handlers.ValidateWebhookReplayFilter(&filter); msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+		return 2
+	}
+
+	// Mesmas regras de status do servidor; a deduplicação evita aplicar duas vezes um evento já processado
+	webhook.NewBounceClassifier(cfg.Bounce, log)
+	webhook.NewStatusMap(db, time.Duration(cfg.Webhook.StatusMapRefreshSeconds)*time.Second, log)
+	if !*dryRun {
+		db.InitKeyAllocation()
+		webhook.NewDeduplicator(db,
+			time.Duration(cfg.Webhook.DedupCacheMinutes)*time.Minute,
+			time.Duration(cfg.Webhook.DedupRetentionHours)*time.Hour,
+			log)
+	}
+
+	requests, err := db.ListWebhookRequests(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao ler WSREQUISICOES: %v\n", err)
+		return 1
+	}
+
+	prefixo := ""
+	if *dryRun {
+		prefixo = "[dry-run] "
+	}
+
+	results := handlers.ReplayWebhookRequests(context.Background(), db, log, requests, *dryRun)
+	for _, result := range results {
+		fmt.Printf("%s%s %s %s (%d)\n", prefixo, result.UUID, result.Data.Format("2006-01-02 15:04:05"), result.Endpoint, result.CodResposta)
+		if result.Resultado != "" {
+			fmt.Printf("    %s: %s\n", result.Resultado, result.Message)
+		}
+		for _, event := range result.Events {
+			fmt.Printf("    %s %s: %s", event.MessageID, event.Evento, event.Resultado)
+			if event.Transicao != "" {
+				fmt.Printf(" (status %d, %s", event.Status, event.Transicao)
+				if event.Ocorrencia {
+					fmt.Print(", ocorrência")
+				}
+				fmt.Print(")")
+			}
+			if event.Message != "" {
+				fmt.Printf(" - %s", event.Message)
+			}
+			fmt.Println()
+		}
+	}
+
+	totals := handlers.WebhookReplayTotals(results)
+	fmt.Printf("Total: %d requisições, %d aplicados, %d simulados, %d duplicados, %d não encontrados, %d ignorados, %d falhas\n",
+		len(results),
+		totals[handlers.ReplayAplicado],
+		totals[handlers.ReplaySimulado],
+		totals[handlers.ReplayDuplicado],
+		totals[handlers.ReplayNaoEncontrado],
+		totals[handlers.ReplayIgnorado],
+		totals[handlers.ReplayErro])
+	log.Infow("replay-webhooks finalizado",
+		"dry_run", *dryRun,
+		"requisicoes", len(results),
+		"totais", totals)
+
+	if totals[handlers.ReplayErro] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseReplayTime interpreta a data de --from/--to (vazio = sem filtro)
+// Datas sem fuso horário são interpretadas no horário local
+func parseReplayTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 	"time"
+	"wsicrmrest/internal/auth"
 	"wsicrmrest/internal/config"
 	"wsicrmrest/internal/database"
 	"wsicrmrest/internal/logger"
@@ -64,6 +65,13 @@ import (
 // @tag.description Endpoints for receiving Zenvia webhook events (email and SMS status updates)
 
 func main() {
+	// Subcomandos de manutenção (ex: wsicrmrest rehash-secrets)
+	if len(os.Args) > 1 {
+		if handled, exitCode := runCommand(os.Args[1:]); handled {
+			os.Exit(exitCode)
+		}
+	}
+
 	// No Windows, verificar se está rodando como serviço
 	if runtime.GOOS == "windows" {
 		// Tentar executar como Windows Service
@@ -99,6 +107,18 @@ func main() {
 		"version_date", config.VersionDate,
 		"build_time", config.BuildTime)
 
+	// Carregar chaves de assinatura JWT (RS256/ES256)
+	keyStore, err := auth.LoadKeyStore(cfg)
+	if err != nil {
+		log.Error("Erro ao carregar chaves JWT", "error", err)
+		os.Exit(1)
+	}
+	log.Info("Assinatura JWT configurada",
+		"signing_algorithm", cfg.JWT.SigningAlgorithm,
+		"signing_kid", cfg.JWT.SigningKeyID,
+		"keys", len(keyStore.JWKS().Keys),
+		"legacy_hs256", cfg.JWT.LegacyHS256)
+
 	// Inicializar conexão com banco de dados
 	db, err := database.NewDatabase(cfg, log)
 	if err != nil {
@@ -120,6 +140,9 @@ func main() {
 		"codigo", cfg.Organization.Codigo,
 		"nome", cfg.Organization.Nome)
 
+	// Estratégia de geração de chave de Ocorrencia e LogsApiHistorico (sequence, lock ou retry)
+	db.InitKeyAllocation()
+
 	// Log configurações CORS
 	if len(cfg.CORS.AllowedOrigins) == 0 {
 		log.Info("CORS configurado para permitir TODAS as origens (*) - Modo Desenvolvimento")
@@ -141,6 +164,12 @@ func main() {
 	router := gin.New()
 	router.Use(gin.Recovery())
 
+	// Proxies confiáveis ([server] trusted_proxies); sem proxies o IP de origem é o da conexão
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error("Erro ao configurar proxies confiáveis", "error", err)
+		os.Exit(1)
+	}
+
 	// Aplicar middlewares de segurança
 	router.Use(middleware.SecurityMiddleware(cfg))
 
@@ -211,6 +240,16 @@ func main() {
 			os.Exit(1)
 		}
 
+		// Habilitar verificação de certificados de cliente (mTLS), se configurado
+		if cfg.TLS.ClientCAFile != "" {
+			if err := tlsloader.ConfigureClientCA(tlsConfig, cfg.TLS.ClientCAFile); err != nil {
+				log.Error("Erro ao carregar CA de clientes", "error", err)
+				os.Exit(1)
+			}
+			log.Info("🔐 mTLS habilitado", "client_ca_file", cfg.TLS.ClientCAFile,
+				"connect", cfg.MTLS.Connect, "admin", cfg.MTLS.Admin, "webhook", cfg.MTLS.Webhook)
+		}
+
 		// Criar servidor HTTP customizado com TLS
 		server := &http.Server{
 			Addr:      ":" + tlsPort,
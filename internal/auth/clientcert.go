@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// certFingerprintPrefix prefixo das impressões digitais SPKI gravadas em WSAPLCERTIFICADO
+const certFingerprintPrefix = "sha256:"
+
+// CertificateFingerprint retorna a impressão digital SHA-256 da chave pública (SPKI) do certificado
+// no formato gravado em WSAPLCERTIFICADO: "sha256:<hex minúsculo>"
+// A SPKI permanece a mesma quando o certificado é renovado com a mesma chave.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return certFingerprintPrefix + hex.EncodeToString(sum[:])
+}
+
+// CertificateSubject retorna o subject do certificado no formato gravado em WSAPLCERTIFICADO
+// (ex: "CN=parceiro.example.com,O=Parceiro,C=BR")
+func CertificateSubject(cert *x509.Certificate) string {
+	return cert.Subject.String()
+}
+
+// NormalizeCertificateMapping valida e normaliza o valor de WSAPLCERTIFICADO
+// Aceita "sha256:<64 dígitos hex>" (convertido para minúsculas) ou o subject do certificado.
+// Vazio remove o mapeamento.
+func NormalizeCertificateMapping(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(strings.ToLower(value), certFingerprintPrefix) {
+		return value, nil
+	}
+
+	digest := strings.ToLower(value[len(certFingerprintPrefix):])
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("impressão digital inválida: esperado sha256:<64 dígitos hexadecimais>")
+	}
+
+	return certFingerprintPrefix + digest, nil
+}
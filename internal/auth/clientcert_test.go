@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeCertificateMapping cobre subject, impressão digital SPKI e valores inválidos
+func TestNormalizeCertificateMapping(t *testing.T) {
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{" CN=parceiro,O=Parceiro,C=BR ", "CN=parceiro,O=Parceiro,C=BR", false},
+		{"SHA256:" + strings.ToUpper(digest), "sha256:" + digest, false},
+		{"sha256:9f86d0", "", true},
+		{"sha256:" + digest[:62] + "zz", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeCertificateMapping(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeCertificateMapping(%q) = %q, %v; esperado %q (erro: %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+	"wsicrmrest/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// ParseIPAllowlist interpreta o conteúdo de WSAPLIPPERMITIDOS
+// Aceita IPs e faixas CIDR (IPv4 ou IPv6) separados por vírgula, ponto e vírgula ou espaço
+func ParseIPAllowlist(value string) ([]netip.Prefix, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	prefixes := make([]netip.Prefix, 0, len(fields))
+	for _, field := range fields {
+		if strings.Contains(field, "/") {
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, fmt.Errorf("faixa CIDR inválida: %s", field)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("IP inválido: %s", field)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// IPInAllowlist verifica se o IP pertence a alguma das faixas
+func IPInAllowlist(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIPAllowlist verifica o IP contra o valor de WSAPLIPPERMITIDOS
+// Valor sem nenhum IP (vazio, espaços ou apenas separadores) não restringe;
+// valor inválido bloqueia todos os IPs (fail closed)
+func CheckIPAllowlist(value, ip string) (bool, error) {
+	prefixes, err := ParseIPAllowlist(value)
+	if err != nil {
+		return false, err
+	}
+	if len(prefixes) == 0 {
+		return true, nil
+	}
+	return IPInAllowlist(prefixes, ip), nil
+}
+
+// IPAllowlistCache mantém em memória os IPs permitidos de cada aplicação (WSAPLIPPERMITIDOS)
+// para a validação de tokens Bearer sem consulta ao banco a cada requisição
+type IPAllowlistCache struct {
+	db        *database.Database
+	logger    *zap.SugaredLogger
+	interval  time.Duration
+	allowlist map[string][]netip.Prefix // client_id -> faixas (ausente = sem restrição)
+	mu        sync.RWMutex
+}
+
+var (
+	ipAllowlistCache     *IPAllowlistCache
+	ipAllowlistCacheOnce sync.Once
+)
+
+// NewIPAllowlistCache cria (uma única vez) o cache de IPs permitidos e inicia a atualização periódica
+func NewIPAllowlistCache(db *database.Database, interval time.Duration, logger *zap.SugaredLogger) *IPAllowlistCache {
+	ipAllowlistCacheOnce.Do(func() {
+		ipAllowlistCache = &IPAllowlistCache{
+			db:        db,
+			logger:    logger,
+			interval:  interval,
+			allowlist: make(map[string][]netip.Prefix),
+		}
+
+		ipAllowlistCache.refresh()
+		go ipAllowlistCache.refreshRoutine()
+
+		logger.Infow("Cache de IPs permitidos por aplicação inicializado",
+			"refresh_interval", interval)
+	})
+
+	return ipAllowlistCache
+}
+
+// GetIPAllowlistCache retorna a instância singleton (nil se não inicializada)
+func GetIPAllowlistCache() *IPAllowlistCache {
+	return ipAllowlistCache
+}
+
+// refreshRoutine recarrega o cache periodicamente
+func (a *IPAllowlistCache) refreshRoutine() {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.refresh()
+	}
+}
+
+// refresh recarrega as faixas de WSAPLICACOES
+// Em caso de falha o estado anterior é mantido
+func (a *IPAllowlistCache) refresh() {
+	rows, err := a.db.ListIPAllowlists()
+	if err != nil {
+		a.logger.Errorw("Falha ao atualizar cache de IPs permitidos", "error", err)
+		return
+	}
+
+	allowlist := make(map[string][]netip.Prefix, len(rows))
+	for clientID, value := range rows {
+		prefixes, err := ParseIPAllowlist(value)
+		if err != nil {
+			// Valor inválido bloqueia a aplicação até ser corrigido (lista vazia)
+			a.logger.Errorw("WSAPLIPPERMITIDOS inválido - acesso bloqueado",
+				"client_id", clientID,
+				"error", err)
+			prefixes = []netip.Prefix{}
+		} else if len(prefixes) == 0 {
+			// Sem nenhum IP (ex: apenas espaços): sem restrição, como em CheckIPAllowlist
+			continue
+		}
+		allowlist[clientID] = prefixes
+	}
+
+	a.mu.Lock()
+	a.allowlist = allowlist
+	a.mu.Unlock()
+}
+
+// Allowed verifica se o IP pode usar tokens da aplicação
+func (a *IPAllowlistCache) Allowed(clientID, ip string) bool {
+	a.mu.RLock()
+	prefixes, restricted := a.allowlist[clientID]
+	a.mu.RUnlock()
+
+	if !restricted {
+		return true
+	}
+	return IPInAllowlist(prefixes, ip)
+}
+
+// Refresh força a recarga imediata do cache (usado após alterações pela API de administração)
+func (a *IPAllowlistCache) Refresh() {
+	a.refresh()
+}
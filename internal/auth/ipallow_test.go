@@ -0,0 +1,31 @@
+package auth
+
+import "testing"
+
+// TestCheckIPAllowlist cobre IPs isolados, faixas CIDR, IPv6 e valores inválidos
+func TestCheckIPAllowlist(t *testing.T) {
+	tests := []struct {
+		value   string
+		ip      string
+		allowed bool
+	}{
+		{"", "203.0.113.10", true},
+		{"   ", "203.0.113.10", true},
+		{" , ;", "203.0.113.10", true},
+		{"203.0.113.0/24", "203.0.113.10", true},
+		{"203.0.113.0/24", "198.51.100.1", false},
+		{"198.51.100.1, 203.0.113.0/24", "198.51.100.1", true},
+		{"198.51.100.1;10.0.0.0/8", "10.20.30.40", true},
+		{"203.0.113.0/24", "::ffff:203.0.113.10", true},
+		{"2001:db8::/32", "2001:db8::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"203.0.113.0/24, invalido", "203.0.113.10", false},
+	}
+
+	for _, tt := range tests {
+		allowed, _ := CheckIPAllowlist(tt.value, tt.ip)
+		if allowed != tt.allowed {
+			t.Errorf("CheckIPAllowlist(%q, %q) = %v; esperado %v", tt.value, tt.ip, allowed, tt.allowed)
+		}
+	}
+}
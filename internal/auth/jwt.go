@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/utils"
+)
+
+// clockSkew tolerância (em segundos) aplicada às validações de nbf e exp
+// para compensar pequenas diferenças de relógio entre instâncias
+const clockSkew int64 = 30
+
+// Erros retornados na validação de tokens
+var (
+	ErrTokenMalformed    = errors.New("token malformado")
+	ErrTokenAlgorithm    = errors.New("algoritmo do token não suportado")
+	ErrTokenSignature    = errors.New("assinatura do token inválida")
+	ErrTokenIssuer       = errors.New("emissor do token inválido")
+	ErrTokenExpired      = errors.New("token expirado")
+	ErrTokenNotYetValid  = errors.New("token ainda não é válido")
+	ErrTokenMissingClaim = errors.New("token sem client_id")
+	ErrTokenKeyID        = errors.New("kid do token desconhecido")
+)
+
+// Header representa o cabeçalho de um JWT
+type Header struct {
+	Typ string `json:"typ"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Claims representa o payload dos tokens emitidos por generateJWT
+type Claims struct {
+	Iss       string `json:"iss"`
+	Nbf       int64  `json:"nbf"`
+	Exp       int64  `json:"exp"`
+	ClientID  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	Aplicacao string `json:"aplicacao"`
+}
+
+// ParseToken valida um token JWT e retorna suas claims
+// Verifica assinatura (HS256 com cfg.JWT.SecretKey ou RS256/ES256 pelo kid), iss, nbf e exp
+func ParseToken(cfg *config.Config, token string) (*Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, ErrTokenMalformed
+	}
+
+	headerJSON, err := DecodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	var header Header
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	signature, err := DecodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	// A assinatura é calculada sobre os segmentos exatamente como foram emitidos
+	if err := verifySignature(cfg, &header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	// Header e payload são cobertos pela assinatura, mas o segmento da assinatura aceita variações
+	// de encoding ("=", alfabeto url-safe) que gerariam outro texto para o mesmo token; apenas o
+	// formato emitido é aceito, de modo que revogação e WSAPLLOGTOKEN possam comparar o texto do token
+	if parts[2] != encodeSignature(header.Alg, signature) {
+		return nil, ErrTokenMalformed
+	}
+
+	payloadJSON, err := DecodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := validateClaims(cfg, &claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// verifySignature valida a assinatura conforme o algoritmo do header
+// HS256 só é aceito quando é o algoritmo configurado ou com legacy_hs256 habilitado
+func verifySignature(cfg *config.Config, header *Header, signingInput string, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if cfg.JWT.SigningAlgorithm != "" && cfg.JWT.SigningAlgorithm != "HS256" && !cfg.JWT.LegacyHS256 {
+			return ErrTokenAlgorithm
+		}
+		h := hmac.New(sha256.New, []byte(cfg.JWT.SecretKey))
+		h.Write([]byte(signingInput))
+		if !hmac.Equal(h.Sum(nil), signature) {
+			return ErrTokenSignature
+		}
+		return nil
+	case "RS256", "ES256":
+		ks := GetKeyStore()
+		if ks == nil {
+			return ErrTokenKeyID
+		}
+		key := ks.Key(header.Kid)
+		if key == nil {
+			return ErrTokenKeyID
+		}
+		if key.Alg != header.Alg {
+			return ErrTokenAlgorithm
+		}
+		if !key.Verify(signingInput, signature) {
+			return ErrTokenSignature
+		}
+		return nil
+	default:
+		return ErrTokenAlgorithm
+	}
+}
+
+// encodeSignature codifica a assinatura no formato emitido por SignToken
+// HS256: base64 padrão sem padding com "+" trocado por "-"; RS256/ES256: base64url sem padding
+func encodeSignature(alg string, signature []byte) string {
+	if alg == "HS256" {
+		return strings.ReplaceAll(base64.RawStdEncoding.EncodeToString(signature), "+", "-")
+	}
+	return base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validateClaims valida iss, nbf, exp e client_id
+func validateClaims(cfg *config.Config, claims *Claims) error {
+	if claims.Iss != cfg.JWT.Issuer {
+		return ErrTokenIssuer
+	}
+	if claims.ClientID == "" {
+		return ErrTokenMissingClaim
+	}
+
+	// Mesmo cálculo de timestamp usado na geração do token
+	now := utils.CalcTimeStampUnix(time.Now(), cfg.JWT.Timezone)
+
+	if claims.Nbf > 0 && now+clockSkew < claims.Nbf {
+		return ErrTokenNotYetValid
+	}
+	if claims.Exp <= 0 || now-clockSkew >= claims.Exp {
+		return ErrTokenExpired
+	}
+
+	return nil
+}
+
+// DecodeSegment decodifica um segmento do token
+// generateJWT mistura base64 padrão (com "+" e "/") e substituições url-safe ("-"),
+// além de remover o padding em alguns segmentos. Normalizamos tudo para base64
+// padrão sem padding antes de decodificar, aceitando ambos os formatos.
+func DecodeSegment(segment string) ([]byte, error) {
+	segment = strings.TrimRight(segment, "=")
+	segment = strings.ReplaceAll(segment, "-", "+")
+	segment = strings.ReplaceAll(segment, "_", "/")
+	return base64.RawStdEncoding.DecodeString(segment)
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"wsicrmrest/internal/config"
+	tlsloader "wsicrmrest/internal/tls"
+)
+
+// SigningKey representa uma chave assimétrica de assinatura de tokens
+type SigningKey struct {
+	KID     string
+	Alg     string // RS256 ou ES256 (derivado do tipo da chave)
+	private crypto.Signer
+}
+
+// KeyStore mantém as chaves de assinatura carregadas do dbinit.ini
+// A chave ativa assina novos tokens; as demais permanecem disponíveis apenas
+// para validação (rotação) e são publicadas no JWKS
+type KeyStore struct {
+	keys   map[string]*SigningKey
+	active *SigningKey
+}
+
+// JWK representa uma chave pública no formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS representa o documento publicado em /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keyStore   *KeyStore
+	keyStoreMu sync.RWMutex
+)
+
+// LoadKeyStore carrega as chaves PEM configuradas em [jwt] e as torna disponíveis globalmente
+// Com signing_algorithm = HS256 e sem key_files, o keystore fica vazio (somente HS256)
+func LoadKeyStore(cfg *config.Config) (*KeyStore, error) {
+	ks := &KeyStore{keys: make(map[string]*SigningKey)}
+
+	for _, keyFile := range cfg.JWT.KeyFiles {
+		privateKey, err := tlsloader.LoadPrivateKey(keyFile.File, cfg.JWT.KeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar chave JWT %s: %w", keyFile.KID, err)
+		}
+
+		key, err := newSigningKey(keyFile.KID, privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := ks.keys[key.KID]; exists {
+			return nil, fmt.Errorf("kid duplicado em key_files: %s", key.KID)
+		}
+		ks.keys[key.KID] = key
+	}
+
+	if cfg.JWT.SigningAlgorithm != "HS256" {
+		active, exists := ks.keys[cfg.JWT.SigningKeyID]
+		if !exists {
+			return nil, fmt.Errorf("signing_kid %s não encontrado em key_files", cfg.JWT.SigningKeyID)
+		}
+		if active.Alg != cfg.JWT.SigningAlgorithm {
+			return nil, fmt.Errorf("chave %s é %s, mas signing_algorithm é %s", active.KID, active.Alg, cfg.JWT.SigningAlgorithm)
+		}
+		ks.active = active
+	}
+
+	keyStoreMu.Lock()
+	keyStore = ks
+	keyStoreMu.Unlock()
+
+	return ks, nil
+}
+
+// GetKeyStore retorna o keystore carregado (nil se LoadKeyStore não foi chamado)
+func GetKeyStore() *KeyStore {
+	keyStoreMu.RLock()
+	defer keyStoreMu.RUnlock()
+	return keyStore
+}
+
+// newSigningKey identifica o algoritmo a partir do tipo da chave
+func newSigningKey(kid string, privateKey crypto.PrivateKey) (*SigningKey, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("chave RSA %s com menos de 2048 bits", kid)
+		}
+		return &SigningKey{KID: kid, Alg: "RS256", private: key}, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("chave EC %s deve usar a curva P-256 (ES256)", kid)
+		}
+		return &SigningKey{KID: kid, Alg: "ES256", private: key}, nil
+	default:
+		return nil, fmt.Errorf("tipo de chave não suportado para JWT: %s", kid)
+	}
+}
+
+// Active retorna a chave usada para assinar novos tokens (nil quando HS256)
+func (ks *KeyStore) Active() *SigningKey {
+	return ks.active
+}
+
+// Key retorna a chave pelo kid (nil se não existir)
+func (ks *KeyStore) Key(kid string) *SigningKey {
+	return ks.keys[kid]
+}
+
+// JWKS retorna as chaves públicas de todas as chaves carregadas (ativa e em rotação)
+func (ks *KeyStore) JWKS() JWKS {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		jwks.Keys = append(jwks.Keys, ks.keys[kid].JWK())
+	}
+	return jwks
+}
+
+// Sign assina o conteúdo (header.payload) com SHA-256
+// ES256 utiliza o formato R||S de 64 bytes exigido pela RFC 7518
+func (k *SigningKey) Sign(signingInput string) ([]byte, error) {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch key := k.private.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	default:
+		return nil, errors.New("tipo de chave não suportado")
+	}
+}
+
+// Verify valida a assinatura do conteúdo (header.payload)
+func (k *SigningKey) Verify(signingInput string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch key := k.private.(type) {
+	case *rsa.PrivateKey:
+		return rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PrivateKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(&key.PublicKey, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+// JWK retorna a chave pública no formato JSON Web Key
+func (k *SigningKey) JWK() JWK {
+	switch key := k.private.(type) {
+	case *rsa.PrivateKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.KID,
+			Use: "sig",
+			Alg: k.Alg,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return JWK{
+			Kty: "EC",
+			Kid: k.KID,
+			Use: "sig",
+			Alg: k.Alg,
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(x),
+			Y:   base64.RawURLEncoding.EncodeToString(y),
+		}
+	default:
+		return JWK{Kid: k.KID}
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// refreshTokenBytes quantidade de bytes aleatórios de um refresh token
+const refreshTokenBytes = 32
+
+// NewRefreshToken gera um refresh token opaco e o hash que deve ser armazenado no banco
+// O valor em texto nunca é persistido, apenas o hash SHA-256
+func NewRefreshToken() (token string, hash string, err error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken calcula o hash SHA-256 (hexadecimal) de um refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+	"wsicrmrest/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// RevocationCache mantém em memória os tokens revogados em WSAPLLOGTOKEN
+// O estado é recarregado periodicamente do banco, de modo que uma revogação
+// feita em qualquer instância passa a valer em todas dentro do intervalo configurado
+type RevocationCache struct {
+	db       *database.Database
+	logger   *zap.SugaredLogger
+	interval time.Duration
+	revoked  map[[sha256.Size]byte]struct{}
+	mu       sync.RWMutex
+}
+
+var (
+	revocationCache     *RevocationCache
+	revocationCacheOnce sync.Once
+)
+
+// NewRevocationCache cria (uma única vez) o cache de revogação e inicia a atualização periódica
+func NewRevocationCache(db *database.Database, interval time.Duration, logger *zap.SugaredLogger) *RevocationCache {
+	revocationCacheOnce.Do(func() {
+		revocationCache = &RevocationCache{
+			db:       db,
+			logger:   logger,
+			interval: interval,
+			revoked:  make(map[[sha256.Size]byte]struct{}),
+		}
+
+		revocationCache.refresh()
+		go revocationCache.refreshRoutine()
+
+		logger.Infow("Cache de revogação de tokens inicializado",
+			"refresh_interval", interval)
+	})
+
+	return revocationCache
+}
+
+// GetRevocationCache retorna a instância singleton (nil se não inicializada)
+func GetRevocationCache() *RevocationCache {
+	return revocationCache
+}
+
+// refreshRoutine recarrega o cache periodicamente
+func (r *RevocationCache) refreshRoutine() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.refresh()
+	}
+}
+
+// refresh recarrega a lista de tokens revogados (incluindo os de aplicações desabilitadas)
+// Em caso de falha o estado anterior é mantido
+func (r *RevocationCache) refresh() {
+	tokens, err := r.db.ListRevokedTokens()
+	if err != nil {
+		r.logger.Errorw("Falha ao atualizar cache de tokens revogados", "error", err)
+		return
+	}
+
+	revoked := make(map[[sha256.Size]byte]struct{}, len(tokens))
+	for _, token := range tokens {
+		revoked[sha256.Sum256([]byte(token))] = struct{}{}
+	}
+
+	r.mu.Lock()
+	r.revoked = revoked
+	r.mu.Unlock()
+}
+
+// IsRevoked verifica se o token está revogado
+func (r *RevocationCache) IsRevoked(token string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, revoked := r.revoked[sha256.Sum256([]byte(token))]
+	return revoked
+}
+
+// MarkRevoked adiciona um token ao cache local imediatamente após a revogação no banco
+func (r *RevocationCache) MarkRevoked(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[sha256.Sum256([]byte(token))] = struct{}{}
+}
+
+// Refresh força a recarga imediata do cache (usado após revogações em massa)
+func (r *RevocationCache) Refresh() {
+	r.refresh()
+}
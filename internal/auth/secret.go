@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Parâmetros argon2id usados em novos hashes de client_secret
+// Hashes existentes guardam seus próprios parâmetros no formato PHC e continuam válidos se estes mudarem
+const (
+	argon2Memory  uint32 = 64 * 1024 // KiB
+	argon2Time    uint32 = 3
+	argon2Threads uint8  = 2
+	argon2SaltLen        = 16
+	argon2KeyLen  uint32 = 32
+)
+
+// DefaultSecretVerifyConcurrency verificações argon2id simultâneas quando [auth] secret_verify_concurrency não é informado
+const DefaultSecretVerifyConcurrency = 4
+
+// secretVerifySlots limita as verificações argon2id simultâneas: cada uma aloca a memória do hash
+// (64 MiB nos parâmetros atuais) e a emissão de token não exige autenticação prévia
+var secretVerifySlots = make(chan struct{}, DefaultSecretVerifyConcurrency)
+
+// SetSecretVerifyConcurrency define o limite de verificações argon2id simultâneas
+// Deve ser chamado na inicialização, antes de o servidor aceitar requisições
+func SetSecretVerifyConcurrency(n int) {
+	if n > 0 {
+		secretVerifySlots = make(chan struct{}, n)
+	}
+}
+
+// HashSecret gera o hash argon2id de um client_secret no formato PHC
+// Ex: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	hash := argon2.IDKey([]byte(secret), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// NewClientSecret gera um client_secret aleatório (32 bytes, base64url sem padding)
+// O valor em claro é retornado uma única vez; apenas o hash é gravado em WSAPLICACOES
+func NewClientSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// IsHashedSecret indica se o valor armazenado em WSAPLCLIENTSECRET é um hash (argon2id ou bcrypt)
+func IsHashedSecret(stored string) bool {
+	return strings.HasPrefix(stored, "$argon2id$") ||
+		strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// VerifySecret compara o client_secret informado com o valor armazenado
+// Aceita hashes argon2id e bcrypt e, durante a migração, o texto plano legado
+// (legacy = true indica que o valor armazenado ainda não foi convertido)
+func VerifySecret(stored, secret string) (ok bool, legacy bool) {
+	switch {
+	case strings.HasPrefix(stored, "$argon2id$"):
+		return verifyArgon2id(stored, secret), false
+	case IsHashedSecret(stored):
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(secret)) == nil, false
+	default:
+		// Comparação em tempo constante para prevenir timing attacks
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(secret)) == 1, true
+	}
+}
+
+// verifyArgon2id valida um hash no formato $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func verifyArgon2id(stored, secret string) bool {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return false
+	}
+
+	// Excedido o limite, a verificação aguarda a liberação de uma vaga
+	slots := secretVerifySlots
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	computed := argon2.IDKey([]byte(secret), salt, time, memory, threads, uint32(len(hash)))
+	return subtle.ConstantTimeCompare(hash, computed) == 1
+}
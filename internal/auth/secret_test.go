@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestVerifySecret cobre hashes argon2id, bcrypt e o texto plano legado
+func TestVerifySecret(t *testing.T) {
+	argon, err := HashSecret("a1234567890b")
+	if err != nil {
+		t.Fatalf("HashSecret: %v", err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("a1234567890b"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		stored     string
+		secret     string
+		wantOK     bool
+		wantLegacy bool
+	}{
+		{argon, "a1234567890b", true, false},
+		{argon, "errado", false, false},
+		{string(bcryptHash), "a1234567890b", true, false},
+		{string(bcryptHash), "errado", false, false},
+		{"a1234567890b", "a1234567890b", true, true},
+		{"a1234567890b", "errado", false, true},
+		{"$argon2id$v=19$m=65536,t=3,p=2$invalido", "a1234567890b", false, false},
+	}
+
+	for _, tt := range tests {
+		ok, legacy := VerifySecret(tt.stored, tt.secret)
+		if ok != tt.wantOK || legacy != tt.wantLegacy {
+			t.Errorf("VerifySecret(%q, %q) = %v, %v; esperado %v, %v", tt.stored, tt.secret, ok, legacy, tt.wantOK, tt.wantLegacy)
+		}
+	}
+}
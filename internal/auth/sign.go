@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/utils"
+)
+
+// SignToken monta e assina um JWT com o algoritmo configurado em [jwt] signing_algorithm
+// HS256 mantém o formato legado; RS256/ES256 usam base64url sem padding e incluem o kid no header
+func SignToken(cfg *config.Config, payload map[string]interface{}) (string, error) {
+	if cfg.JWT.SigningAlgorithm == "" || cfg.JWT.SigningAlgorithm == "HS256" {
+		return signHS256(cfg, payload)
+	}
+
+	ks := GetKeyStore()
+	if ks == nil || ks.Active() == nil {
+		return "", errors.New("chave de assinatura JWT não carregada")
+	}
+	key := ks.Active()
+
+	header := map[string]string{
+		"typ": "JWT",
+		"alg": key.Alg,
+		"kid": key.KID,
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	tokenUnsigned := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	signature, err := key.Sign(tokenUnsigned)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenUnsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signHS256 gera o token no formato legado (HMAC-SHA256 com cfg.JWT.SecretKey)
+// O encoding dos segmentos é mantido para compatibilidade com os clientes existentes
+func signHS256(cfg *config.Config, payload map[string]interface{}) (string, error) {
+	// Header
+	header := map[string]string{
+		"typ": "JWT",
+		"alg": "HS256",
+	}
+	headerJSON, _ := json.Marshal(header)
+	headerB64 := base64.StdEncoding.EncodeToString(headerJSON)
+	headerB64 = utils.StringChange(headerB64, "\n", "")
+	headerB64 = utils.StringChange(headerB64, "\r", "")
+
+	// Payload
+	payloadJSON, _ := json.Marshal(payload)
+	payloadB64 := base64.StdEncoding.EncodeToString(payloadJSON)
+	payloadB64 = utils.StringChange(payloadB64, "\n", "")
+	payloadB64 = utils.StringChange(payloadB64, "\r", "")
+	payloadB64 = utils.StringChange(payloadB64, "=", "")
+
+	// Criar token sem assinatura
+	tokenUnsigned := headerB64 + "." + payloadB64
+
+	// Gerar assinatura HMAC-SHA256
+	h := hmac.New(sha256.New, []byte(cfg.JWT.SecretKey))
+	h.Write([]byte(tokenUnsigned))
+	signature := h.Sum(nil)
+	signatureB64 := base64.StdEncoding.EncodeToString(signature)
+	signatureB64 = utils.StringChange(signatureB64, "+", "-")
+	signatureB64 = utils.StringChange(signatureB64, "=", "")
+
+	// Token completo
+	return headerB64 + "." + payloadB64 + "." + signatureB64, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhookSignaturePrefix prefixo da assinatura HMAC enviada pelo provedor
+const webhookSignaturePrefix = "sha256="
+
+// VerifyWebhookToken compara o token recebido com os secrets ativos (comparação em tempo constante)
+func VerifyWebhookToken(secrets []string, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	ok := false
+	for _, secret := range secrets {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// VerifyWebhookSignature verifica a assinatura HMAC-SHA256 do body com qualquer um dos secrets ativos
+// A assinatura é o HMAC em hexadecimal, com ou sem o prefixo "sha256="
+func VerifyWebhookSignature(secrets []string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(signature)), webhookSignaturePrefix)
+	received, err := hex.DecodeString(signature)
+	if err != nil || len(received) != sha256.Size {
+		return false
+	}
+
+	ok := false
+	for _, secret := range secrets {
+		if hmac.Equal(received, WebhookSignature(secret, body)) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// WebhookSignature calcula o HMAC-SHA256 do body com o secret informado
+func WebhookSignature(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// ParseWebhookPublicKey interpreta a chave pública ECDSA de verificação de um webhook assinado
+// (ex: "Verification Key" do Signed Event Webhook da SendGrid): DER PKIX em base64 ou PEM
+func ParseWebhookPublicKey(key string) (*ecdsa.PublicKey, error) {
+	key = strings.TrimSpace(key)
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("chave pública inválida (base64): %w", err)
+		}
+		der = decoded
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("chave pública inválida: %w", err)
+	}
+
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("chave pública não é ECDSA")
+	}
+	return publicKey, nil
+}
+
+// VerifyWebhookECDSA verifica a assinatura ECDSA (DER em base64) de timestamp+body com qualquer uma das chaves
+// Formato do Signed Event Webhook da SendGrid
+func VerifyWebhookECDSA(keys []*ecdsa.PublicKey, signature, timestamp string, body []byte) bool {
+	if signature == "" || timestamp == "" {
+		return false
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(timestamp))
+	hash.Write(body)
+	digest := hash.Sum(nil)
+
+	for _, key := range keys {
+		if ecdsa.VerifyASN1(key, digest, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyWebhookTimestamp verifica se o timestamp assinado (segundos Unix) está dentro da tolerância
+// em relação a now, nos dois sentidos. Impede a reutilização de uma requisição assinada capturada.
+func VerifyWebhookTimestamp(timestamp string, now time.Time, tolerance time.Duration) bool {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	diff := now.Sub(time.Unix(seconds, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
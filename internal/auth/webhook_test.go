@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestVerifyWebhookSignature cobre rotação de secrets, prefixo e assinaturas inválidas
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"MESSAGE_STATUS"}`)
+	secrets := []string{"secret-novo", "secret-antigo"}
+	antigo := hex.EncodeToString(WebhookSignature("secret-antigo", body))
+
+	tests := []struct {
+		signature string
+		valid     bool
+	}{
+		{hex.EncodeToString(WebhookSignature("secret-novo", body)), true},
+		{"sha256=" + antigo, true},
+		{"SHA256=" + antigo, true},
+		{hex.EncodeToString(WebhookSignature("outro", body)), false},
+		{antigo[:10], false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if valid := VerifyWebhookSignature(secrets, body, tt.signature); valid != tt.valid {
+			t.Errorf("VerifyWebhookSignature(%q) = %v; esperado %v", tt.signature, valid, tt.valid)
+		}
+	}
+
+	if VerifyWebhookSignature(secrets, []byte(`{"type":"ALTERADO"}`), antigo) {
+		t.Error("assinatura aceita para body alterado")
+	}
+	if !VerifyWebhookToken(secrets, "secret-antigo") || VerifyWebhookToken(secrets, "") || VerifyWebhookToken(nil, "x") {
+		t.Error("VerifyWebhookToken retornou resultado inesperado")
+	}
+}
+
+// TestVerifyWebhookECDSA cobre a assinatura do Signed Event Webhook (timestamp + body)
+func TestVerifyWebhookECDSA(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	publicKey, err := ParseWebhookPublicKey(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatalf("ParseWebhookPublicKey: %v", err)
+	}
+
+	body := []byte(`[{"event":"delivered"}]`)
+	timestamp := "1700000000"
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	sig, _ := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	signature := base64.StdEncoding.EncodeToString(sig)
+	keys := []*ecdsa.PublicKey{publicKey}
+
+	if !VerifyWebhookECDSA(keys, signature, timestamp, body) {
+		t.Error("assinatura válida rejeitada")
+	}
+	if VerifyWebhookECDSA(keys, signature, "1700000001", body) {
+		t.Error("assinatura aceita com timestamp alterado")
+	}
+	if VerifyWebhookECDSA(keys, signature, timestamp, []byte(`[{"event":"bounce"}]`)) {
+		t.Error("assinatura aceita para body alterado")
+	}
+	if VerifyWebhookECDSA(keys, "", timestamp, body) {
+		t.Error("assinatura vazia aceita")
+	}
+}
+
+// TestVerifyWebhookTimestamp garante a rejeição de timestamps fora da tolerância (replay)
+func TestVerifyWebhookTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		timestamp string
+		valid     bool
+	}{
+		{"1700000000", true},
+		{strconv.FormatInt(now.Add(-tolerance).Unix(), 10), true},
+		{strconv.FormatInt(now.Add(tolerance).Unix(), 10), true},
+		{strconv.FormatInt(now.Add(-tolerance-time.Second).Unix(), 10), false},
+		{strconv.FormatInt(now.Add(tolerance+time.Second).Unix(), 10), false},
+		{"", false},
+		{"abc", false},
+	}
+
+	for _, tt := range tests {
+		if valid := VerifyWebhookTimestamp(tt.timestamp, now, tolerance); valid != tt.valid {
+			t.Errorf("VerifyWebhookTimestamp(%q) = %v; esperado %v", tt.timestamp, valid, tt.valid)
+		}
+	}
+}
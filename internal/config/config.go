@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"strings"
 
 	"gopkg.in/ini.v1"
 )
@@ -23,10 +25,15 @@ type Config struct {
 	JWT          JWTConfig
 	Organization OrganizationConfig
 	Application  ApplicationConfig
+	Server       ServerConfig
 	CORS         CORSConfig
 	TLS          TLSConfig
 	Security     SecurityConfig
 	Fail2Ban     Fail2BanConfig
+	Auth         AuthConfig
+	MTLS         MTLSConfig
+	Webhook      WebhookConfig
+	Bounce       BounceConfig
 }
 
 // DatabaseConfig representa as configurações do banco de dados Oracle
@@ -34,14 +41,34 @@ type DatabaseConfig struct {
 	TNSName  string
 	Username string
 	Password string
+
+	// Geração de chaves de Ocorrencia.OcoCod e LogsApiHistorico.LogsApiHisSequencial
+	// Estratégias: auto (sequence se existir), sequence, lock (apenas LogsApiHistorico) ou retry
+	OcorrenciaKey            string // Estratégia de OcoCod (chave ocorrencia_key, padrão: auto)
+	OcorrenciaSequence       string // Sequence de OcoCod (chave ocorrencia_sequence, padrão: SEQ_OCORRENCIA)
+	LogsApiHistoricoKey      string // Estratégia de LogsApiHisSequencial (chave logsapihistorico_key, padrão: auto)
+	LogsApiHistoricoSequence string // Sequence de LogsApiHisSequencial (chave logsapihistorico_sequence, padrão: SEQ_LOGSAPIHISTORICO)
 }
 
 // JWTConfig representa as configurações de JWT
 type JWTConfig struct {
-	SecretKey   string // gsKey - Chave HMAC para JWT
+	SecretKey   string // gsKey - Chave HMAC para JWT ([jwt] secret_key)
 	Issuer      string // gsIss - Issuer do JWT
 	KeyDelivery string // gsKeyDelivery - Chave adicional para delivery
 	Timezone    int    // gnFusoHorario - Fuso horário em horas (ex: -3 para Brasília, 0 para UTC)
+
+	// Assinatura assimétrica (seção [jwt] do dbinit.ini)
+	SigningAlgorithm string       // HS256 (padrão), RS256 ou ES256
+	SigningKeyID     string       // kid da chave ativa usada para assinar novos tokens
+	KeyFiles         []JWTKeyFile // Chaves PEM carregadas (ativa + chaves em rotação, apenas validação)
+	KeyPassword      string       // Senha das chaves PEM criptografadas
+	LegacyHS256      bool         // Aceitar tokens HS256 durante a migração para RS256/ES256
+}
+
+// JWTKeyFile associa um kid a um arquivo PEM de chave privada
+type JWTKeyFile struct {
+	KID  string
+	File string
 }
 
 // OrganizationConfig representa os dados da organização
@@ -90,11 +117,17 @@ type CORSConfig struct {
 
 // TLSConfig representa as configurações de HTTPS/TLS
 type TLSConfig struct {
-	Enabled     bool   // Habilitar HTTPS
-	CertFile    string // Caminho do certificado TLS
-	KeyFile     string // Caminho da chave privada TLS
-	KeyPassword string // Senha para chave privada criptografada (vazio se não criptografada)
-	Port        string // Porta HTTPS (padrão: 8443)
+	Enabled      bool   // Habilitar HTTPS
+	CertFile     string // Caminho do certificado TLS
+	KeyFile      string // Caminho da chave privada TLS
+	KeyPassword  string // Senha para chave privada criptografada (vazio se não criptografada)
+	Port         string // Porta HTTPS (padrão: 8443)
+	ClientCAFile string // CAs aceitas para certificados de cliente (mTLS); vazio = mTLS desabilitado
+}
+
+// ServerConfig representa as configurações do servidor HTTP (seção [server])
+type ServerConfig struct {
+	TrustedProxies []string // IPs/faixas CIDR de proxies confiáveis para X-Forwarded-For (padrão: nenhum)
 }
 
 // SecurityConfig representa as configurações de segurança
@@ -107,12 +140,86 @@ type SecurityConfig struct {
 
 // Fail2BanConfig representa as configurações do Fail2Ban
 type Fail2BanConfig struct {
-	Enabled             bool     // Habilitar Fail2Ban (padrão: true)
-	MaxAttempts         int      // Tentativas máximas antes de banir (padrão: 5)
-	BanDurationMinutes  int      // Duração do banimento em minutos (padrão: 30)
-	WindowDurationMinutes int    // Janela de tempo para contar tentativas em minutos (padrão: 10)
-	CleanupIntervalMinutes int   // Intervalo de limpeza em minutos (padrão: 5)
-	WhitelistIPs        []string // IPs que nunca serão banidos (padrão: 127.0.0.1, ::1)
+	Enabled                bool     // Habilitar Fail2Ban (padrão: true)
+	MaxAttempts            int      // Tentativas máximas antes de banir (padrão: 5)
+	BanDurationMinutes     int      // Duração do banimento em minutos (padrão: 30)
+	WindowDurationMinutes  int      // Janela de tempo para contar tentativas em minutos (padrão: 10)
+	CleanupIntervalMinutes int      // Intervalo de limpeza em minutos (padrão: 5)
+	WhitelistIPs           []string // IPs que nunca serão banidos (padrão: 127.0.0.1, ::1)
+}
+
+// AuthConfig representa as configurações de validação de tokens Bearer
+type AuthConfig struct {
+	RevocationRefreshSeconds  int // Intervalo de atualização do cache de tokens revogados (padrão: 5)
+	IPAllowlistRefreshSeconds int // Intervalo de atualização do cache de IPs permitidos por aplicação (padrão: 30)
+	SecretVerifyConcurrency   int // Verificações argon2id de client_secret simultâneas (padrão: 4)
+}
+
+// Modos de autenticação por certificado de cliente (mTLS) por grupo de rotas
+const (
+	MTLSOff      = "off"      // Certificado ignorado
+	MTLSOptional = "optional" // Certificado aceito como alternativa ao client_secret/Bearer
+	MTLSRequire  = "require"  // Certificado obrigatório
+)
+
+// MTLSConfig representa o modo de mTLS de cada grupo de rotas (seção [mtls])
+type MTLSConfig struct {
+	Connect string // /connect/v1 (token, introspect, wsteste)
+	Admin   string // /connect/v1/admin
+	Webhook string // /webhook
+}
+
+// Enabled indica se algum grupo de rotas aceita certificados de cliente
+func (m MTLSConfig) Enabled() bool {
+	for _, mode := range []string{m.Connect, m.Admin, m.Webhook} {
+		if mode == MTLSOptional || mode == MTLSRequire {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookConfig representa a verificação de origem dos webhooks (seção [webhook])
+// Vários secrets podem estar ativos ao mesmo tempo para permitir a rotação
+type WebhookConfig struct {
+	Secrets         []string            // Secrets aceitos por todos os provedores (chave secrets)
+	ProviderSecrets map[string][]string // Secrets específicos por provedor (chave <provedor>_secrets, ex: zenvia_secrets)
+	AllowQueryToken bool                // Aceita o token no parâmetro ?token= da URL (padrão: true)
+
+	SendGridPublicKeys         []string // Chaves públicas ECDSA do Signed Event Webhook da SendGrid (chave sendgrid_public_keys)
+	SendGridTimestampTolerance int      // Diferença máxima, em segundos, entre o timestamp assinado e o relógio do servidor (padrão: 300)
+
+	DedupCacheMinutes   int // Tempo em memória dos eventos já recebidos (padrão: 60)
+	DedupRetentionHours int // Retenção dos eventos em WSWEBHOOKEVENTOS (padrão: 72)
+
+	Async             bool   // Ingestão assíncrona: persiste o evento na fila local e responde 200 imediatamente (padrão: false)
+	QueueDir          string // Diretório da fila de ingestão (padrão: queue)
+	Workers           int    // Workers que processam a fila (padrão: 4)
+	QueueMaxAttempts  int    // Tentativas por evento antes de descartá-lo como falha (padrão: 10)
+	QueueRetrySeconds int    // Intervalo base do backoff exponencial entre tentativas (padrão: 5)
+
+	StatusMapRefreshSeconds int // Intervalo de recarga do mapa de status (WSWEBHOOKSTATUS) (padrão: 60)
+
+	DeadLetter             bool // Grava em WSWEBHOOKPENDENTES os eventos sem mensagem correspondente (padrão: true)
+	DeadLetterRetrySeconds int  // Intervalo base do backoff de reprocessamento dos pendentes (padrão: 60)
+	DeadLetterMaxAgeHours  int  // Idade máxima de um evento pendente antes de expirar (padrão: 24)
+}
+
+// SecretsFor retorna os secrets ativos de um provedor (específicos do provedor ou, na ausência, os globais)
+// Lista vazia indica verificação desabilitada
+func (w WebhookConfig) SecretsFor(provider string) []string {
+	if secrets, ok := w.ProviderSecrets[provider]; ok && len(secrets) > 0 {
+		return secrets
+	}
+	return w.Secrets
+}
+
+// BounceConfig regras de classificação de bounce por canal (seção [bounce])
+// Os padrões são trechos do motivo/detalhes da falha (sem diferenciar maiúsculas); canais sem
+// chave configurada usam as regras padrão de webhook.BounceClassifier
+type BounceConfig struct {
+	Hard map[string][]string // Padrões de bounce permanente por canal (chave <canal>_hard, ex: email_hard)
+	Soft map[string][]string // Padrões de bounce temporário por canal (chave <canal>_soft, ex: sms_soft)
 }
 
 // LoadConfig carrega as configurações do arquivo dbinit.ini
@@ -127,10 +234,16 @@ func LoadConfig(filename string) (*Config, error) {
 			TNSName:  cfg.Section("database").Key("tns_name").String(),
 			Username: cfg.Section("database").Key("username").String(),
 			Password: cfg.Section("database").Key("password").String(),
+
+			OcorrenciaKey:            strings.ToLower(cfg.Section("database").Key("ocorrencia_key").MustString("auto")),
+			OcorrenciaSequence:       cfg.Section("database").Key("ocorrencia_sequence").MustString("SEQ_OCORRENCIA"),
+			LogsApiHistoricoKey:      strings.ToLower(cfg.Section("database").Key("logsapihistorico_key").MustString("auto")),
+			LogsApiHistoricoSequence: cfg.Section("database").Key("logsapihistorico_sequence").MustString("SEQ_LOGSAPIHISTORICO"),
 		},
 		JWT: JWTConfig{
+			// gsKey: chave HMAC configurável em [jwt] secret_key (não há valor padrão)
+			SecretKey: cfg.Section("jwt").Key("secret_key").String(),
 			// Valores fixos das variáveis globais WinDev (não configuráveis)
-			SecretKey:   "CloudI0812IcrMmDB",    // gsKey
 			Issuer:      "WSCloudICrmIntellsys", // gsIss
 			KeyDelivery: "Ped2505IcrM",          // gsKeyDelivery
 			Timezone:    0,                      // gnFusoHorario (0 = UTC)
@@ -151,6 +264,28 @@ func LoadConfig(filename string) (*Config, error) {
 		TLS:      loadTLSConfig(cfg),
 		Security: loadSecurityConfig(cfg),
 		Fail2Ban: loadFail2BanConfig(cfg),
+		Auth:     loadAuthConfig(cfg),
+		Webhook:  loadWebhookConfig(cfg),
+		Bounce:   loadBounceConfig(cfg),
+	}
+
+	// Proxies confiáveis (IP de origem usado em WSAPLIPPERMITIDOS, Fail2Ban e logs)
+	serverConfig, err := loadServerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	config.Server = serverConfig
+
+	// Autenticação por certificado de cliente (mTLS)
+	mtlsConfig, err := loadMTLSConfig(cfg, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	config.MTLS = mtlsConfig
+
+	// Assinatura JWT configurável (HS256 permanece o padrão)
+	if err := loadJWTSigningConfig(cfg, &config.JWT); err != nil {
+		return nil, err
 	}
 
 	// Validações básicas
@@ -163,7 +298,8 @@ func LoadConfig(filename string) (*Config, error) {
 	if config.Database.Password == "" {
 		return nil, fmt.Errorf("password não configurado em [database]")
 	}
-	if config.JWT.SecretKey == "" {
+	// A chave HMAC é exigida quando tokens HS256 são emitidos ou aceitos (legacy_hs256)
+	if config.JWT.SecretKey == "" && (config.JWT.SigningAlgorithm == "HS256" || config.JWT.LegacyHS256) {
 		return nil, fmt.Errorf("secret_key não configurado em [jwt]")
 	}
 
@@ -189,7 +325,7 @@ func loadCORSConfig(cfg *ini.File) CORSConfig {
 	methods := corsSection.Key("AllowedMethods").MustString("GET,POST,PUT,PATCH,DELETE,OPTIONS")
 
 	// Headers permitidos
-	headers := corsSection.Key("AllowedHeaders").MustString("Origin,Content-Type,Content-Length,Accept-Encoding,Authorization,Grant_type,X-CSRF-Token")
+	headers := corsSection.Key("AllowedHeaders").MustString("Origin,Content-Type,Content-Length,Accept-Encoding,Authorization,Grant_type,Refresh_token,X-CSRF-Token")
 
 	// Allow Credentials
 	allowCredentials := corsSection.Key("AllowCredentials").MustBool(true)
@@ -256,12 +392,56 @@ func loadTLSConfig(cfg *ini.File) TLSConfig {
 	tlsSection := cfg.Section("tls")
 
 	return TLSConfig{
-		Enabled:     tlsSection.Key("enabled").MustBool(false),
-		CertFile:    tlsSection.Key("cert_file").MustString("certs/server.crt"),
-		KeyFile:     tlsSection.Key("key_file").MustString("certs/server.key"),
-		KeyPassword: tlsSection.Key("key_password").MustString(""), // Vazio = chave não criptografada
-		Port:        tlsSection.Key("port").MustString("8443"),
+		Enabled:      tlsSection.Key("enabled").MustBool(false),
+		CertFile:     tlsSection.Key("cert_file").MustString("certs/server.crt"),
+		KeyFile:      tlsSection.Key("key_file").MustString("certs/server.key"),
+		KeyPassword:  tlsSection.Key("key_password").MustString(""), // Vazio = chave não criptografada
+		Port:         tlsSection.Key("port").MustString("8443"),
+		ClientCAFile: tlsSection.Key("client_ca_file").String(),
+	}
+}
+
+// loadMTLSConfig carrega os modos de mTLS por grupo de rotas da seção [mtls]
+// Qualquer modo diferente de off exige [tls] enabled = true e client_ca_file
+func loadMTLSConfig(cfg *ini.File, tlsConfig TLSConfig) (MTLSConfig, error) {
+	mtlsSection := cfg.Section("mtls")
+
+	mtls := MTLSConfig{
+		Connect: mtlsSection.Key("connect").MustString(MTLSOff),
+		Admin:   mtlsSection.Key("admin").MustString(MTLSOff),
+		Webhook: mtlsSection.Key("webhook").MustString(MTLSOff),
+	}
+
+	for group, mode := range map[string]string{"connect": mtls.Connect, "admin": mtls.Admin, "webhook": mtls.Webhook} {
+		if mode != MTLSOff && mode != MTLSOptional && mode != MTLSRequire {
+			return mtls, fmt.Errorf("modo inválido em [mtls] %s: %s (use off, optional ou require)", group, mode)
+		}
+	}
+
+	if mtls.Enabled() && (!tlsConfig.Enabled || tlsConfig.ClientCAFile == "") {
+		return mtls, fmt.Errorf("[mtls] exige [tls] enabled = true e client_ca_file configurado")
 	}
+
+	return mtls, nil
+}
+
+// loadServerConfig carrega as configurações do servidor HTTP do arquivo ini
+// trusted_proxies vazio: X-Forwarded-For e X-Real-IP são ignorados e o IP de origem é o da conexão
+func loadServerConfig(cfg *ini.File) (ServerConfig, error) {
+	proxies := splitAndTrim(cfg.Section("server").Key("trusted_proxies").String(), ",")
+	for _, proxy := range proxies {
+		var err error
+		if strings.Contains(proxy, "/") {
+			_, err = netip.ParsePrefix(proxy)
+		} else {
+			_, err = netip.ParseAddr(proxy)
+		}
+		if err != nil {
+			return ServerConfig{}, fmt.Errorf("trusted_proxies inválido em [server]: %s", proxy)
+		}
+	}
+
+	return ServerConfig{TrustedProxies: proxies}, nil
 }
 
 // loadSecurityConfig carrega as configurações de segurança do arquivo ini
@@ -301,3 +481,152 @@ func loadFail2BanConfig(cfg *ini.File) Fail2BanConfig {
 		WhitelistIPs:           whitelist,
 	}
 }
+
+// loadAuthConfig carrega as configurações de autenticação do arquivo ini
+func loadAuthConfig(cfg *ini.File) AuthConfig {
+	authSection := cfg.Section("auth")
+
+	refresh := authSection.Key("revocation_refresh_seconds").MustInt(5)
+	if refresh <= 0 {
+		refresh = 5
+	}
+
+	allowlistRefresh := authSection.Key("ip_allowlist_refresh_seconds").MustInt(30)
+	if allowlistRefresh <= 0 {
+		allowlistRefresh = 30
+	}
+
+	verifyConcurrency := authSection.Key("secret_verify_concurrency").MustInt(4)
+	if verifyConcurrency <= 0 {
+		verifyConcurrency = 4
+	}
+
+	return AuthConfig{
+		RevocationRefreshSeconds:  refresh,
+		IPAllowlistRefreshSeconds: allowlistRefresh,
+		SecretVerifyConcurrency:   verifyConcurrency,
+	}
+}
+
+// loadWebhookConfig carrega os secrets de verificação dos webhooks do arquivo ini
+func loadWebhookConfig(cfg *ini.File) WebhookConfig {
+	webhookSection := cfg.Section("webhook")
+
+	webhook := WebhookConfig{
+		Secrets:         splitAndTrim(webhookSection.Key("secrets").String(), ","),
+		ProviderSecrets: make(map[string][]string),
+		AllowQueryToken: webhookSection.Key("allow_query_token").MustBool(true),
+
+		SendGridPublicKeys:         splitAndTrim(webhookSection.Key("sendgrid_public_keys").String(), ","),
+		SendGridTimestampTolerance: webhookSection.Key("sendgrid_timestamp_tolerance_seconds").MustInt(300),
+
+		DedupCacheMinutes:   webhookSection.Key("dedup_cache_minutes").MustInt(60),
+		DedupRetentionHours: webhookSection.Key("dedup_retention_hours").MustInt(72),
+	}
+	if webhook.SendGridTimestampTolerance <= 0 {
+		webhook.SendGridTimestampTolerance = 300
+	}
+	if webhook.DedupCacheMinutes <= 0 {
+		webhook.DedupCacheMinutes = 60
+	}
+	if webhook.DedupRetentionHours <= 0 {
+		webhook.DedupRetentionHours = 72
+	}
+
+	webhook.Async = webhookSection.Key("async").MustBool(false)
+	webhook.QueueDir = webhookSection.Key("queue_dir").MustString("queue")
+	webhook.Workers = webhookSection.Key("workers").MustInt(4)
+	webhook.QueueMaxAttempts = webhookSection.Key("queue_max_attempts").MustInt(10)
+	webhook.QueueRetrySeconds = webhookSection.Key("queue_retry_seconds").MustInt(5)
+	webhook.StatusMapRefreshSeconds = webhookSection.Key("status_map_refresh_seconds").MustInt(60)
+	webhook.DeadLetter = webhookSection.Key("dead_letter").MustBool(true)
+	webhook.DeadLetterRetrySeconds = webhookSection.Key("dead_letter_retry_seconds").MustInt(60)
+	webhook.DeadLetterMaxAgeHours = webhookSection.Key("dead_letter_max_age_hours").MustInt(24)
+	if webhook.Workers <= 0 {
+		webhook.Workers = 4
+	}
+	if webhook.QueueMaxAttempts <= 0 {
+		webhook.QueueMaxAttempts = 10
+	}
+	if webhook.QueueRetrySeconds <= 0 {
+		webhook.QueueRetrySeconds = 5
+	}
+	if webhook.StatusMapRefreshSeconds <= 0 {
+		webhook.StatusMapRefreshSeconds = 60
+	}
+	if webhook.DeadLetterRetrySeconds <= 0 {
+		webhook.DeadLetterRetrySeconds = 60
+	}
+	if webhook.DeadLetterMaxAgeHours <= 0 {
+		webhook.DeadLetterMaxAgeHours = 24
+	}
+
+	for _, key := range webhookSection.Keys() {
+		name := key.Name()
+		if name != "secrets" && strings.HasSuffix(name, "_secrets") {
+			webhook.ProviderSecrets[strings.TrimSuffix(name, "_secrets")] = splitAndTrim(key.String(), ",")
+		}
+	}
+
+	return webhook
+}
+
+// loadBounceConfig carrega as regras de classificação de bounce da seção [bounce]
+func loadBounceConfig(cfg *ini.File) BounceConfig {
+	bounce := BounceConfig{
+		Hard: make(map[string][]string),
+		Soft: make(map[string][]string),
+	}
+
+	for _, key := range cfg.Section("bounce").Keys() {
+		patterns := splitAndTrim(key.String(), ",")
+		if len(patterns) == 0 {
+			continue
+		}
+		name := key.Name()
+		if strings.HasSuffix(name, "_hard") {
+			bounce.Hard[strings.TrimSuffix(name, "_hard")] = patterns
+		} else if strings.HasSuffix(name, "_soft") {
+			bounce.Soft[strings.TrimSuffix(name, "_soft")] = patterns
+		}
+	}
+
+	return bounce
+}
+
+// loadJWTSigningConfig carrega as configurações de assinatura assimétrica da seção [jwt]
+// key_files usa o formato kid:arquivo separados por vírgula
+func loadJWTSigningConfig(cfg *ini.File, jwtConfig *JWTConfig) error {
+	jwtSection := cfg.Section("jwt")
+
+	jwtConfig.SigningAlgorithm = jwtSection.Key("signing_algorithm").MustString("HS256")
+	jwtConfig.SigningKeyID = jwtSection.Key("signing_kid").String()
+	jwtConfig.KeyPassword = jwtSection.Key("key_password").String()
+	jwtConfig.LegacyHS256 = jwtSection.Key("legacy_hs256").MustBool(false)
+
+	for _, entry := range splitAndTrim(jwtSection.Key("key_files").String(), ",") {
+		parts := splitString(entry, ":")
+		if len(parts) < 2 {
+			return fmt.Errorf("key_files inválido em [jwt]: %s (formato esperado kid:arquivo)", entry)
+		}
+		kid := trimSpace(parts[0])
+		// O caminho pode conter ":" (ex: C:\chaves\jwt.key)
+		file := trimSpace(entry[len(parts[0])+1:])
+		if kid == "" || file == "" {
+			return fmt.Errorf("key_files inválido em [jwt]: %s (formato esperado kid:arquivo)", entry)
+		}
+		jwtConfig.KeyFiles = append(jwtConfig.KeyFiles, JWTKeyFile{KID: kid, File: file})
+	}
+
+	switch jwtConfig.SigningAlgorithm {
+	case "HS256":
+	case "RS256", "ES256":
+		if jwtConfig.SigningKeyID == "" {
+			return fmt.Errorf("signing_kid não configurado em [jwt] para %s", jwtConfig.SigningAlgorithm)
+		}
+	default:
+		return fmt.Errorf("signing_algorithm inválido em [jwt]: %s (use HS256, RS256 ou ES256)", jwtConfig.SigningAlgorithm)
+	}
+
+	return nil
+}
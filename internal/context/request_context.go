@@ -4,14 +4,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// ginContextKey é a chave usada para armazenar o RequestContext no gin.Context
+const ginContextKey = "wsicrmrest.requestContext"
+
 // RequestContext armazena o contexto de uma requisição
 type RequestContext struct {
 	UUID            string
 	ClientID        string
 	NomeAplicacao   string
+	Scope           string
 	StartTime       time.Time
 	DetalheLogAPI   string
 	WSGravaLogDB    bool
@@ -30,6 +35,21 @@ func NewRequestContext() *RequestContext {
 	}
 }
 
+// FromGin retorna o contexto da requisição associado ao gin.Context.
+// Se nenhum middleware tiver criado o contexto ainda, um novo é criado e armazenado,
+// garantindo que middlewares e handlers compartilhem o mesmo UUID e dados do cliente.
+func FromGin(c *gin.Context) *RequestContext {
+	if value, exists := c.Get(ginContextKey); exists {
+		if reqCtx, ok := value.(*RequestContext); ok {
+			return reqCtx
+		}
+	}
+
+	reqCtx := NewRequestContext()
+	c.Set(ginContextKey, reqCtx)
+	return reqCtx
+}
+
 // SetClientInfo define informações do cliente
 func (rc *RequestContext) SetClientInfo(clientID, nomeAplicacao string) {
 	rc.mu.Lock()
@@ -38,6 +58,15 @@ func (rc *RequestContext) SetClientInfo(clientID, nomeAplicacao string) {
 	rc.NomeAplicacao = nomeAplicacao
 }
 
+// SetTokenInfo define as informações extraídas de um token Bearer validado
+func (rc *RequestContext) SetTokenInfo(clientID, nomeAplicacao, scope string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ClientID = clientID
+	rc.NomeAplicacao = nomeAplicacao
+	rc.Scope = scope
+}
+
 // AddLogDetail adiciona detalhes ao log
 func (rc *RequestContext) AddLogDetail(detail string) {
 	if !rc.WSDetalheLogAPI {
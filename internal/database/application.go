@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// ClientSecretData representa o client_secret armazenado de uma aplicação
+type ClientSecretData struct {
+	ClientID string
+	Secret   string
+}
+
+// ListClientSecrets retorna o client_secret armazenado de todas as aplicações
+func (d *Database) ListClientSecrets() ([]ClientSecretData, error) {
+	query := `SELECT WSAPLCLIENTID, WSAPLCLIENTSECRET
+		FROM WSAPLICACOES
+		ORDER BY WSAPLCLIENTID`
+
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []ClientSecretData
+	for rows.Next() {
+		var data ClientSecretData
+		if err := rows.Scan(&data.ClientID, &data.Secret); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, data)
+	}
+
+	return secrets, rows.Err()
+}
+
+// ReplaceClientSecret substitui o client_secret armazenado pelo hash informado
+// O UPDATE só é aplicado se o valor atual ainda for oldSecret (evita sobrescrever uma troca concorrente)
+func (d *Database) ReplaceClientSecret(clientID, oldSecret, newSecret string) (bool, error) {
+	query := `UPDATE WSAPLICACOES
+		SET WSAPLCLIENTSECRET = :1
+		WHERE WSAPLCLIENTID = :2
+		AND WSAPLCLIENTSECRET = :3`
+
+	result, err := d.Exec(query, newSecret, clientID, oldSecret)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// ApplicationData representa um registro de WSAPLICACOES (sem o client_secret)
+type ApplicationData struct {
+	ClientID         string
+	Nome             string
+	Scopo            int64
+	JWTExpiracao     int
+	RefreshExpiracao int
+	Status           int
+	IPPermitidos     string // IPs/faixas CIDR permitidos (vazio = sem restrição)
+	Certificado      string // Certificado de cliente mTLS: "sha256:<SPKI>" ou subject (vazio = sem mapeamento)
+}
+
+// applicationColumns colunas lidas por ListApplications e GetApplication
+const applicationColumns = `WSAPLCLIENTID, WSAPLNOME, WSAPLSCOPO, WSAPLIJWTEXPIRACAO,
+	NVL(WSAPLREFRESHEXPIRACAO, 0), WSAPLSTATUS, WSAPLIPPERMITIDOS, WSAPLCERTIFICADO`
+
+// scanApplication lê uma linha com applicationColumns
+func scanApplication(scan func(dest ...interface{}) error) (ApplicationData, error) {
+	var app ApplicationData
+	var ipPermitidos, certificado sql.NullString
+	err := scan(&app.ClientID, &app.Nome, &app.Scopo, &app.JWTExpiracao, &app.RefreshExpiracao, &app.Status, &ipPermitidos, &certificado)
+	app.IPPermitidos = ipPermitidos.String
+	app.Certificado = certificado.String
+	return app, err
+}
+
+// ListApplications retorna todas as aplicações cadastradas
+func (d *Database) ListApplications() ([]ApplicationData, error) {
+	query := `SELECT ` + applicationColumns + `
+		FROM WSAPLICACOES
+		ORDER BY WSAPLCLIENTID`
+
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apps := []ApplicationData{}
+	for rows.Next() {
+		app, err := scanApplication(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, rows.Err()
+}
+
+// GetApplication busca uma aplicação pelo client_id (independente do status)
+// Retorna nil, nil se a aplicação não existir
+func (d *Database) GetApplication(clientID string) (*ApplicationData, error) {
+	query := `SELECT ` + applicationColumns + `
+		FROM WSAPLICACOES
+		WHERE WSAPLCLIENTID = :1`
+
+	app, err := scanApplication(d.QueryRow(query, clientID).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+// InsertApplication cadastra uma nova aplicação com o hash do client_secret
+func (d *Database) InsertApplication(app ApplicationData, secretHash string) error {
+	query := `INSERT INTO WSAPLICACOES(WSAPLCLIENTID, WSAPLCLIENTSECRET, WSAPLNOME, WSAPLSCOPO,
+		WSAPLIJWTEXPIRACAO, WSAPLREFRESHEXPIRACAO, WSAPLSTATUS, WSAPLIPPERMITIDOS, WSAPLCERTIFICADO)
+		VALUES(:1, :2, :3, :4, :5, :6, :7, :8, :9)`
+
+	_, err := d.Exec(query, app.ClientID, secretHash, app.Nome, app.Scopo,
+		app.JWTExpiracao, app.RefreshExpiracao, app.Status, app.IPPermitidos, app.Certificado)
+	return err
+}
+
+// UpdateApplication atualiza nome, escopo, expirações, status, IPs permitidos e certificado de uma aplicação
+func (d *Database) UpdateApplication(app ApplicationData) (bool, error) {
+	query := `UPDATE WSAPLICACOES
+		SET WSAPLNOME = :1, WSAPLSCOPO = :2, WSAPLIJWTEXPIRACAO = :3,
+		    WSAPLREFRESHEXPIRACAO = :4, WSAPLSTATUS = :5, WSAPLIPPERMITIDOS = :6,
+		    WSAPLCERTIFICADO = :7
+		WHERE WSAPLCLIENTID = :8`
+
+	result, err := d.Exec(query, app.Nome, app.Scopo, app.JWTExpiracao,
+		app.RefreshExpiracao, app.Status, app.IPPermitidos, app.Certificado, app.ClientID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// GetApplicationByCertificate busca a aplicação habilitada mapeada a um certificado de cliente (mTLS)
+// WSAPLCERTIFICADO pode conter a impressão digital SPKI ("sha256:<hex>") ou o subject do certificado
+// Retorna nil, nil se nenhuma aplicação habilitada estiver mapeada
+func (d *Database) GetApplicationByCertificate(fingerprint, subject string) (*ApplicationData, error) {
+	query := `SELECT ` + applicationColumns + `
+		FROM WSAPLICACOES
+		WHERE WSAPLCERTIFICADO IN (:1, :2)
+		AND WSAPLSTATUS = 1
+		ORDER BY CASE WHEN WSAPLCERTIFICADO = :3 THEN 0 ELSE 1 END`
+
+	// A impressão digital tem precedência sobre o subject
+	app, err := scanApplication(d.QueryRow(query, fingerprint, subject, fingerprint).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+// SetClientSecret grava o hash de um novo client_secret
+func (d *Database) SetClientSecret(clientID, secretHash string) (bool, error) {
+	query := `UPDATE WSAPLICACOES
+		SET WSAPLCLIENTSECRET = :1
+		WHERE WSAPLCLIENTID = :2`
+
+	result, err := d.Exec(query, secretHash, clientID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// ListIPAllowlists retorna os IPs permitidos (WSAPLIPPERMITIDOS) das aplicações que possuem restrição
+func (d *Database) ListIPAllowlists() (map[string]string, error) {
+	query := `SELECT WSAPLCLIENTID, WSAPLIPPERMITIDOS
+		FROM WSAPLICACOES
+		WHERE WSAPLIPPERMITIDOS IS NOT NULL`
+
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allowlists := make(map[string]string)
+	for rows.Next() {
+		var clientID, value string
+		if err := rows.Scan(&clientID, &value); err != nil {
+			return nil, err
+		}
+		allowlists[clientID] = value
+	}
+
+	return allowlists, rows.Err()
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"time"
+)
+
+// AuditEntry representa uma ação administrativa registrada em WSADMAUDITORIA
+type AuditEntry struct {
+	UUID      string // UUID da requisição (relaciona com WSREQUISICOES)
+	Operador  string // client_id do token que executou a ação
+	Acao      string // Ex: APLICACAO_CRIAR, APLICACAO_DESABILITAR
+	Alvo      string // Objeto afetado (client_id, IP, ...)
+	Detalhes  string // Dados alterados (JSON)
+	IP        string // IP de origem da requisição
+	Resultado int    // Código HTTP da resposta
+}
+
+// InsertAuditoria grava uma ação administrativa em WSADMAUDITORIA
+func (d *Database) InsertAuditoria(entry AuditEntry) error {
+	query := `INSERT INTO WSADMAUDITORIA(WSAUDNUMERO, WSAUDDATA, WSAUDUUID, WSAUDOPERADOR, WSAUDACAO,
+		WSAUDALVO, WSAUDDETALHES, WSAUDIP, WSAUDRESULTADO)
+		VALUES(SEQ_WSAUDNUMERO.NEXTVAL, :1, :2, :3, :4, :5, :6, :7, :8)`
+
+	_, err := d.Exec(query, time.Now(), entry.UUID, entry.Operador, entry.Acao,
+		entry.Alvo, entry.Detalhes, entry.IP, entry.Resultado)
+	if err != nil {
+		d.Logger.Errorw("Falha ao gravar auditoria", "error", err, "acao", entry.Acao, "alvo", entry.Alvo)
+	}
+	return err
+}
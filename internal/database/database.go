@@ -1,19 +1,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"wsicrmrest/internal/config"
 
-	_ "github.com/godror/godror"
+	"github.com/godror/godror"
 	"go.uber.org/zap"
 )
 
 // Database encapsula a conexão com o banco de dados
+// Dentro de WithTx a cópia recebida pela unidade de trabalho executa Exec, Query e QueryRow
+// na transação (tx) com o contexto informado.
 type Database struct {
 	DB     *sql.DB
 	Config *config.Config
 	Logger *zap.SugaredLogger
+
+	tx   *sql.Tx
+	ctx  context.Context
+	keys map[string]KeyAllocator // Estratégias de geração de chave (InitKeyAllocation)
+}
+
+// executor operações comuns a *sql.DB e *sql.Tx
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // NewDatabase cria uma nova conexão com o banco de dados Oracle usando TNSNAMES
@@ -56,20 +70,78 @@ func (d *Database) Close() error {
 	return nil
 }
 
+// WithTx executa fn como uma unidade de trabalho: todos os comandos executados pelo *Database
+// recebido (Exec, Query e QueryRow) participam da mesma transação, confirmada se fn retornar nil
+// e desfeita (rollback) em caso de erro ou panic. Chamadas aninhadas reutilizam a transação externa.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *Database) error) (err error) {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				d.Logger.Errorw("Falha ao desfazer transação", "error", rbErr)
+			}
+		}
+	}()
+
+	unit := *d
+	unit.tx = tx
+	unit.ctx = ctx
+
+	if err = fn(&unit); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação: %w", err)
+	}
+	return nil
+}
+
+// executor retorna a transação corrente ou o pool de conexões, com o contexto correspondente
+func (d *Database) executor() (executor, context.Context) {
+	if d.tx != nil {
+		return d.tx, d.ctx
+	}
+	return d.DB, context.Background()
+}
+
 // Query executa uma query e retorna os resultados
 func (d *Database) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	d.Logger.Debugw("Executando query", "query", query, "args", args)
-	return d.DB.Query(query, args...)
+	exec, ctx := d.executor()
+	return exec.QueryContext(ctx, query, args...)
 }
 
 // Exec executa um comando SQL
 func (d *Database) Exec(query string, args ...interface{}) (sql.Result, error) {
 	d.Logger.Debugw("Executando comando", "query", query, "args", args)
-	return d.DB.Exec(query, args...)
+	exec, ctx := d.executor()
+	return exec.ExecContext(ctx, query, args...)
 }
 
 // QueryRow executa uma query que retorna uma única linha
 func (d *Database) QueryRow(query string, args ...interface{}) *sql.Row {
 	d.Logger.Debugw("Executando query row", "query", query, "args", args)
-	return d.DB.QueryRow(query, args...)
+	exec, ctx := d.executor()
+	return exec.QueryRowContext(ctx, query, args...)
+}
+
+// IsUniqueViolation indica se o erro é uma violação de chave única do Oracle (ORA-00001)
+func IsUniqueViolation(err error) bool {
+	if oraErr, ok := godror.AsOraErr(err); ok {
+		return oraErr.Code() == 1
+	}
+	return false
 }
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyStrategy estratégia de geração de chave primária
+type KeyStrategy string
+
+// Estratégias de geração de chave (chaves <tabela>_key da seção [database])
+const (
+	KeyAuto     KeyStrategy = "auto"     // Sequence se existir; senão lock (quando suportado) ou retry
+	KeySequence KeyStrategy = "sequence" // NEXTVAL da sequence Oracle
+	KeyLock     KeyStrategy = "lock"     // SELECT ... FOR UPDATE na linha de controle + MAX+1
+	KeyRetry    KeyStrategy = "retry"    // MAX+1 com nova tentativa em violação de chave única
+)
+
+// Tabelas com geração de chave configurável
+const (
+	TableOcorrencia       = "Ocorrencia"
+	TableLogsApiHistorico = "LogsApiHistorico"
+)
+
+// keyMaxAttempts tentativas de gerar uma nova chave antes de retornar a violação de chave única
+const keyMaxAttempts = 5
+
+// sequenceNamePattern nome de sequence aceito (opcionalmente com o owner), concatenado no SQL
+var sequenceNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*(\.[A-Za-z][A-Za-z0-9_$#]*)?$`)
+
+// KeyAllocator estratégia de geração de chave em uso para uma tabela
+type KeyAllocator struct {
+	Tabela   string
+	Strategy KeyStrategy
+	Sequence string // Sequence usada (apenas KeySequence)
+	Motivo   string // Motivo da estratégia quando difere da configurada
+}
+
+// keyFallback estratégia sem sequence: lock quando a tabela tem linha de controle, senão retry
+// LogsApiHistorico usa a linha da mensagem em logsapi; Ocorrencia não tem linha de controle
+func keyFallback(tabela string) KeyStrategy {
+	if tabela == TableLogsApiHistorico {
+		return KeyLock
+	}
+	return KeyRetry
+}
+
+// defaultKeyAllocators estratégias usadas antes de InitKeyAllocation (sem consulta ao banco)
+func defaultKeyAllocators() map[string]KeyAllocator {
+	return map[string]KeyAllocator{
+		TableOcorrencia:       {Tabela: TableOcorrencia, Strategy: keyFallback(TableOcorrencia)},
+		TableLogsApiHistorico: {Tabela: TableLogsApiHistorico, Strategy: keyFallback(TableLogsApiHistorico)},
+	}
+}
+
+// InitKeyAllocation verifica as sequences configuradas e define a estratégia de geração de chave
+// de cada tabela ([database] ocorrencia_key e logsapihistorico_key). Chamado na inicialização;
+// a estratégia em uso é registrada no log e retornada.
+func (d *Database) InitKeyAllocation() []KeyAllocator {
+	configured := []struct {
+		tabela   string
+		strategy string
+		sequence string
+	}{
+		{TableOcorrencia, d.Config.Database.OcorrenciaKey, d.Config.Database.OcorrenciaSequence},
+		{TableLogsApiHistorico, d.Config.Database.LogsApiHistoricoKey, d.Config.Database.LogsApiHistoricoSequence},
+	}
+
+	allocators := make(map[string]KeyAllocator, len(configured))
+	report := make([]KeyAllocator, 0, len(configured))
+	for _, c := range configured {
+		allocator := d.resolveKeyAllocator(c.tabela, KeyStrategy(c.strategy), c.sequence)
+		allocators[c.tabela] = allocator
+		report = append(report, allocator)
+
+		d.Logger.Infow("Estratégia de geração de chave",
+			"tabela", allocator.Tabela,
+			"estrategia", allocator.Strategy,
+			"sequence", allocator.Sequence,
+			"configurada", c.strategy,
+			"motivo", allocator.Motivo)
+	}
+
+	d.keys = allocators
+	return report
+}
+
+// resolveKeyAllocator define a estratégia de uma tabela a partir da configuração e das sequences existentes
+func (d *Database) resolveKeyAllocator(tabela string, strategy KeyStrategy, sequence string) KeyAllocator {
+	allocator := KeyAllocator{Tabela: tabela, Strategy: keyFallback(tabela)}
+
+	switch strategy {
+	case KeyAuto, KeySequence:
+		if !sequenceNamePattern.MatchString(sequence) {
+			allocator.Motivo = fmt.Sprintf("nome de sequence inválido: %q", sequence)
+			break
+		}
+		exists, err := d.sequenceExists(sequence)
+		if err != nil {
+			allocator.Motivo = fmt.Sprintf("falha ao consultar a sequence %s: %v", sequence, err)
+			break
+		}
+		if !exists {
+			allocator.Motivo = fmt.Sprintf("sequence %s não encontrada", sequence)
+			break
+		}
+		allocator.Strategy = KeySequence
+		allocator.Sequence = strings.ToUpper(sequence)
+	case KeyLock:
+		if allocator.Strategy != KeyLock {
+			allocator.Motivo = "tabela sem linha de controle para lock"
+		}
+	case KeyRetry:
+		allocator.Strategy = KeyRetry
+	default:
+		allocator.Motivo = fmt.Sprintf("estratégia desconhecida: %q", strategy)
+	}
+
+	if allocator.Motivo != "" && strategy != KeyAuto {
+		d.Logger.Warnw("Estratégia de geração de chave configurada não disponível",
+			"tabela", tabela,
+			"configurada", strategy,
+			"em_uso", allocator.Strategy,
+			"motivo", allocator.Motivo)
+	}
+
+	return allocator
+}
+
+// sequenceExists indica se a sequence existe e é acessível pelo usuário da conexão
+func (d *Database) sequenceExists(sequence string) (bool, error) {
+	owner, name := "", strings.ToUpper(sequence)
+	if i := strings.Index(name, "."); i >= 0 {
+		owner, name = name[:i], name[i+1:]
+	}
+
+	var count int
+	err := d.QueryRow(`SELECT COUNT(*) FROM ALL_SEQUENCES
+		WHERE SEQUENCE_NAME = :1
+		AND (:2 IS NULL OR SEQUENCE_OWNER = :3)`, name, nullIfEmpty(owner), nullIfEmpty(owner)).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// keyAllocator retorna a estratégia em uso para a tabela
+func (d *Database) keyAllocator(tabela string) KeyAllocator {
+	if allocator, ok := d.keys[tabela]; ok {
+		return allocator
+	}
+	return defaultKeyAllocators()[tabela]
+}
+
+// nextSequenceValue retorna o próximo valor da sequence
+func (d *Database) nextSequenceValue(sequence string) (int, error) {
+	var value int
+	err := d.QueryRow(`SELECT ` + sequence + `.NEXTVAL FROM DUAL`).Scan(&value)
+	return value, err
+}
+
+// withKeyRetry executa insert com a chave gerada por next; uma violação de chave única (outra
+// requisição ou outro sistema usou a mesma chave) gera uma nova chave, até keyMaxAttempts tentativas
+// Na estratégia retry é o único controle; nas demais protege contra gravadores que ainda usam MAX+1.
+// No Oracle a falha de um comando não invalida a transação, então a nova tentativa pode ocorrer em WithTx.
+func (d *Database) withKeyRetry(allocator KeyAllocator, next func() (int, error), insert func(key int) error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		key, err := next()
+		if err != nil {
+			return 0, err
+		}
+
+		err = insert(key)
+		if err == nil {
+			return key, nil
+		}
+		if !IsUniqueViolation(err) || attempt >= keyMaxAttempts {
+			return 0, err
+		}
+
+		d.Logger.Warnw("Chave já utilizada por outra requisição - gerando nova chave",
+			"tabela", allocator.Tabela,
+			"chave", key,
+			"tentativa", attempt)
+	}
+}
+
+// nullIfEmpty retorna nil para string vazia (bind NULL)
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -125,6 +125,7 @@ func removeAuthorizationHeader(header string) string {
 		if !strings.HasPrefix(upperLine, "AUTHORIZATION:") &&
 			!strings.HasPrefix(upperLine, "X-API-KEY:") &&
 			!strings.HasPrefix(upperLine, "X-AUTH-TOKEN:") &&
+			!strings.HasPrefix(upperLine, "REFRESH_TOKEN:") &&
 			!strings.HasPrefix(upperLine, "COOKIE:") {
 			filtered = append(filtered, line)
 		}
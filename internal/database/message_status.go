@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MessageStatusData estado atual do ciclo de vida de uma mensagem
+type MessageStatusData struct {
+	Status     int       // logsapistatus atual (0 se não informado)
+	DataEvento time.Time // Timestamp do provedor do último evento aplicado (zero se desconhecido)
+}
+
+// GetMessageStatus retorna o status atual da mensagem (logsApi) e o timestamp do último
+// evento aplicado (WSMSGSTATUS)
+func (d *Database) GetMessageStatus(logsApiId int) (*MessageStatusData, error) {
+	query := `SELECT l.logsapistatus, s.WSMSTDTAEVENTO
+		FROM logsapi l
+		LEFT JOIN WSMSGSTATUS s ON s.WSMSTLOGSAPIID = l.logsapiid
+		WHERE l.logsapiid = :1`
+
+	var status sql.NullInt64
+	var dataEvento sql.NullTime
+	if err := d.QueryRow(query, logsApiId).Scan(&status, &dataEvento); err != nil {
+		return nil, err
+	}
+
+	return &MessageStatusData{
+		Status:     int(status.Int64),
+		DataEvento: dataEvento.Time,
+	}, nil
+}
+
+// LockMessageStatus bloqueia a linha da mensagem em logsapi (SELECT ... FOR UPDATE) e retorna o
+// status atual, serializando eventos simultâneos da mesma mensagem até o fim da transação
+// Deve ser executado dentro de WithTx; a transição é avaliada sobre o status retornado
+func (d *Database) LockMessageStatus(logsApiId int) (*MessageStatusData, error) {
+	var status sql.NullInt64
+	if err := d.QueryRow(`SELECT logsapistatus FROM logsapi WHERE logsapiid = :1 FOR UPDATE`, logsApiId).Scan(&status); err != nil {
+		return nil, err
+	}
+
+	return d.GetMessageStatus(logsApiId)
+}
+
+// SaveMessageStatus registra em WSMSGSTATUS o status aplicado e o timestamp do evento do provedor
+func (d *Database) SaveMessageStatus(logsApiId int, status int, dataEvento time.Time) error {
+	evento := sql.NullTime{Time: dataEvento, Valid: !dataEvento.IsZero()}
+	now := time.Now()
+
+	result, err := d.Exec(`UPDATE WSMSGSTATUS
+		SET WSMSTSTATUS = :1, WSMSTDTAEVENTO = :2, WSMSTDATA = :3
+		WHERE WSMSTLOGSAPIID = :4`, status, evento, now, logsApiId)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+
+	_, err = d.Exec(`INSERT INTO WSMSGSTATUS(WSMSTLOGSAPIID, WSMSTSTATUS, WSMSTDTAEVENTO, WSMSTDATA)
+		VALUES(:1, :2, :3, :4)`, logsApiId, status, evento, now)
+	if IsUniqueViolation(err) {
+		// Inserido por outra requisição entre o UPDATE e o INSERT
+		_, err = d.Exec(`UPDATE WSMSGSTATUS
+			SET WSMSTSTATUS = :1, WSMSTDTAEVENTO = :2, WSMSTDATA = :3
+			WHERE WSMSTLOGSAPIID = :4`, status, evento, now, logsApiId)
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshTokenData representa um refresh token armazenado em WSAPLREFRESHTOKEN
+type RefreshTokenData struct {
+	Numero    int64
+	ClientID  string
+	Familia   string
+	Expiracao time.Time
+	Utilizado bool
+}
+
+// InsertRefreshToken grava o hash de um novo refresh token
+// familia agrupa os tokens gerados por rotação a partir do mesmo login por client_credentials
+func (d *Database) InsertRefreshToken(hash, clientID, familia string, expiracao time.Time) error {
+	query := `INSERT INTO WSAPLREFRESHTOKEN(WSRTKNUMERO, WSRTKHASH, WSAPLCLIENTID, WSRTKFAMILIA, WSRTKDATA, WSRTKEXPIRACAO, WSRTKUTILIZADO)
+	          VALUES(SEQ_WSRTKNUMERO.NEXTVAL, :1, :2, :3, :4, :5, 0)`
+
+	_, err := d.Exec(query, hash, clientID, familia, time.Now(), expiracao)
+	return err
+}
+
+// GetRefreshToken busca um refresh token pelo hash
+// Retorna nil, nil se o token não existir
+func (d *Database) GetRefreshToken(hash string) (*RefreshTokenData, error) {
+	query := `SELECT WSRTKNUMERO, WSAPLCLIENTID, WSRTKFAMILIA, WSRTKEXPIRACAO, NVL(WSRTKUTILIZADO, 0)
+	          FROM WSAPLREFRESHTOKEN
+	          WHERE WSRTKHASH = :1`
+
+	var data RefreshTokenData
+	var utilizado int
+	err := d.QueryRow(query, hash).Scan(
+		&data.Numero,
+		&data.ClientID,
+		&data.Familia,
+		&data.Expiracao,
+		&utilizado,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data.Utilizado = utilizado == 1
+	return &data, nil
+}
+
+// ConsumeRefreshToken marca o refresh token como utilizado
+// Retorna false se outro processo já o consumiu (uso único garantido pelo UPDATE condicional)
+func (d *Database) ConsumeRefreshToken(numero int64) (bool, error) {
+	query := `UPDATE WSAPLREFRESHTOKEN
+		SET WSRTKUTILIZADO = 1, WSRTKDTAUTILIZACAO = :1
+		WHERE WSRTKNUMERO = :2
+		AND NVL(WSRTKUTILIZADO, 0) = 0`
+
+	result, err := d.Exec(query, time.Now(), numero)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// RevokeRefreshTokenFamily invalida todos os refresh tokens ainda não utilizados de uma família
+// Usado quando um refresh token já consumido é reapresentado (possível vazamento)
+func (d *Database) RevokeRefreshTokenFamily(familia string) (int64, error) {
+	query := `UPDATE WSAPLREFRESHTOKEN
+		SET WSRTKUTILIZADO = 1, WSRTKDTAUTILIZACAO = :1
+		WHERE WSRTKFAMILIA = :2
+		AND NVL(WSRTKUTILIZADO, 0) = 0`
+
+	result, err := d.Exec(query, time.Now(), familia)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeClientRefreshTokens invalida todos os refresh tokens ainda não utilizados de um client_id
+func (d *Database) RevokeClientRefreshTokens(clientID string) (int64, error) {
+	query := `UPDATE WSAPLREFRESHTOKEN
+		SET WSRTKUTILIZADO = 1, WSRTKDTAUTILIZACAO = :1
+		WHERE WSAPLCLIENTID = :2
+		AND NVL(WSRTKUTILIZADO, 0) = 0`
+
+	result, err := d.Exec(query, time.Now(), clientID)
+	if err != nil {
+		d.Logger.Errorw("Falha ao revogar refresh tokens do cliente", "error", err, "client_id", clientID)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TokenLogData representa um token emitido registrado em WSAPLLOGTOKEN
+type TokenLogData struct {
+	Numero    int64
+	ClientID  string
+	Data      time.Time
+	Expiracao time.Time
+	Revogado  bool
+}
+
+// GetTokenLog busca o registro de emissão de um token
+// Retorna nil, nil se o token não foi emitido por esta API
+func (d *Database) GetTokenLog(token string) (*TokenLogData, error) {
+	query := `SELECT WSLTKNUMERO, WSAPLCLIENTID, WSLTKDATA, WSLTKEXPIRACAO, NVL(WSLTKREVOGADO, 0)
+	          FROM WSAPLLOGTOKEN
+	          WHERE WSAPLTOKEN = :1`
+
+	var data TokenLogData
+	var revogado int
+	err := d.QueryRow(query, token).Scan(
+		&data.Numero,
+		&data.ClientID,
+		&data.Data,
+		&data.Expiracao,
+		&revogado,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data.Revogado = revogado == 1
+	return &data, nil
+}
+
+// RevokeToken marca um token emitido como revogado em WSAPLLOGTOKEN
+// Retorna a quantidade de registros afetados (0 se o token não existir ou já estiver revogado)
+func (d *Database) RevokeToken(token string) (int64, error) {
+	query := `UPDATE WSAPLLOGTOKEN
+		SET WSLTKREVOGADO = 1, WSLTKDTAREVOGACAO = :1
+		WHERE WSAPLTOKEN = :2
+		AND NVL(WSLTKREVOGADO, 0) = 0`
+
+	result, err := d.Exec(query, time.Now(), token)
+	if err != nil {
+		d.Logger.Errorw("Falha ao revogar token", "error", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeTokenByNumero marca um token como revogado pelo número (WSLTKNUMERO)
+func (d *Database) RevokeTokenByNumero(numero int64) (int64, error) {
+	query := `UPDATE WSAPLLOGTOKEN
+		SET WSLTKREVOGADO = 1, WSLTKDTAREVOGACAO = :1
+		WHERE WSLTKNUMERO = :2
+		AND NVL(WSLTKREVOGADO, 0) = 0`
+
+	result, err := d.Exec(query, time.Now(), numero)
+	if err != nil {
+		d.Logger.Errorw("Falha ao revogar token", "error", err, "numero", numero)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeClientTokens revoga todos os tokens ainda válidos de um client_id
+func (d *Database) RevokeClientTokens(clientID string) (int64, error) {
+	now := time.Now()
+	query := `UPDATE WSAPLLOGTOKEN
+		SET WSLTKREVOGADO = 1, WSLTKDTAREVOGACAO = :1
+		WHERE WSAPLCLIENTID = :2
+		AND WSLTKEXPIRACAO > :3
+		AND NVL(WSLTKREVOGADO, 0) = 0`
+
+	result, err := d.Exec(query, now, clientID, now)
+	if err != nil {
+		d.Logger.Errorw("Falha ao revogar tokens do cliente", "error", err, "client_id", clientID)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ListRevokedTokens retorna os tokens que ainda não expiraram e estão revogados ou pertencem
+// a aplicações desabilitadas em WSAPLICACOES (ex: desabilitadas direto no banco, sem a API de administração)
+// Consulta somente leitura: a revogação em WSAPLLOGTOKEN é feita no momento da desabilitação
+func (d *Database) ListRevokedTokens() ([]string, error) {
+	query := `SELECT WSAPLTOKEN
+		FROM WSAPLLOGTOKEN
+		WHERE WSLTKEXPIRACAO > :1
+		AND (WSLTKREVOGADO = 1
+			OR WSAPLCLIENTID IN (SELECT WSAPLCLIENTID FROM WSAPLICACOES WHERE WSAPLSTATUS <> 1))`
+
+	rows, err := d.DB.Query(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -59,8 +60,36 @@ func (d *Database) GetEmailByAPIMessageID(apiMessageID string) (*EmailData, erro
 	return &emailData, nil
 }
 
+// GetEmailByCodigo busca dados do email pelo código da mensagem (emsgcodigo)
+// Usado quando o ID da mensagem na API não corresponde e o provedor informa o externalId do envio
+func (d *Database) GetEmailByCodigo(emsgcodigo int) (*EmailData, error) {
+	query := `SELECT e.emsgcodigo, e.clicodigo, l.logsapiid
+	          FROM emailmensagem e
+	          INNER JOIN logsapi l ON e.emsgcodigo = l.emsgcodigo
+	          WHERE e.emsgcodigo = :1
+	          AND l.logsapitipmensagem = 1`
+
+	var emailData EmailData
+	err := d.DB.QueryRow(query, emsgcodigo).Scan(
+		&emailData.EmailNumero,
+		&emailData.CliCodigo,
+		&emailData.LogsApiId,
+	)
+	if err != nil {
+		d.Logger.Warnw("Email não encontrado pelo código da mensagem",
+			"error", err,
+			"emsgcodigo", emsgcodigo)
+		return nil, err
+	}
+
+	return &emailData, nil
+}
+
 // InsereLogsAPI insere registro na tabela logsApi e logsApiHistorico para Email
 // Equivalente a pgInsereLogsAPI do WinDev
+// O evento é sempre gravado em logsApiHistorico; o status atual (logsApi, EmailMensagem e
+// WSMSGSTATUS) só é atualizado quando atualizaStatus é true (transição de status válida).
+// Executar dentro de WithTx para que as gravações sejam confirmadas ou desfeitas em conjunto.
 func (d *Database) InsereLogsAPI(
 	emailNumero int,
 	logsApiTipId int,
@@ -73,7 +102,22 @@ func (d *Database) InsereLogsAPI(
 	enderecoEmail string,
 	logsApiId int,
 	logsApiTag string,
+	atualizaStatus bool,
+	dataEvento time.Time,
 ) error {
+	// Inserir em logsApiHistorico
+	if err := d.InsertLogsApiHistorico(logsApiId, logsApiHisDescricao, logsApiStatus, logsApiTag); err != nil {
+		return err
+	}
+
+	if !atualizaStatus {
+		d.Logger.Infow("Evento gravado apenas no histórico - status atual mantido",
+			"emailNumero", emailNumero,
+			"logsApiId", logsApiId,
+			"status", logsApiStatus)
+		return nil
+	}
+
 	// Atualizar tabela logsApi usando bind variables (proteção SQL injection)
 	queryUpdateLogsApi := `UPDATE logsapi
 		SET logsapistatus = :1
@@ -90,13 +134,14 @@ func (d *Database) InsereLogsAPI(
 		"logsApiId", logsApiId,
 		"status", logsApiStatus)
 
-	// Inserir em logsApiHistorico
-	if err := d.InsertLogsApiHistorico(logsApiId, logsApiHisDescricao, logsApiStatus, logsApiTag); err != nil {
+	// Atualizar status da mensagem na tabela EmailMensagem
+	if err := d.SetMsgStatus(logsApiStatus, logsApiHisDescricao, emailNumero); err != nil {
 		return err
 	}
 
-	// Atualizar status da mensagem na tabela EmailMensagem
-	if err := d.SetMsgStatus(logsApiStatus, logsApiHisDescricao, emailNumero); err != nil {
+	// Registrar o timestamp do evento aplicado (comparado com os próximos eventos)
+	if err := d.SaveMessageStatus(logsApiId, logsApiStatus, dataEvento); err != nil {
+		d.Logger.Errorw("Falha ao registrar status em WSMSGSTATUS", "error", err, "logsApiId", logsApiId)
 		return err
 	}
 
@@ -133,6 +178,7 @@ func (d *Database) SetMsgStatus(logsApiStatus int, logsApiHisDescricao string, e
 
 // InsereOcorrenciaEmailInconsistente insere ocorrência de email inconsistente
 // Equivalente a pgInsereOcorrenciaEmailInconsistente do WinDev
+// Executar dentro de WithTx para que a ocorrência e a limpeza do email sejam atômicas.
 func (d *Database) InsereOcorrenciaEmailInconsistente(
 	enderecoEmail string,
 	cliCodigo int,
@@ -146,7 +192,7 @@ func (d *Database) InsereOcorrenciaEmailInconsistente(
 		WHERE c.clicodigo = :1`
 
 	var cliNome, cliCpfCnpj, cliExtEmail2, cliExtEmail3 string
-	err := d.DB.QueryRow(queryCliente, cliCodigo).Scan(&cliNome, &cliCpfCnpj, &cliExtEmail2, &cliExtEmail3)
+	err := d.QueryRow(queryCliente, cliCodigo).Scan(&cliNome, &cliCpfCnpj, &cliExtEmail2, &cliExtEmail3)
 	if err != nil {
 		d.Logger.Errorw("Erro ao buscar dados do cliente", "error", err, "cliCodigo", cliCodigo)
 		return err
@@ -170,20 +216,55 @@ func (d *Database) InsereOcorrenciaEmailInconsistente(
 		return nil
 	}
 
-	// Obter próximo ID usando MAX+1
-	var maxOcoCod int
-	queryMax := `SELECT NVL(MAX(OcoCod), 0) + 1 FROM Ocorrencia`
-	err = d.DB.QueryRow(queryMax).Scan(&maxOcoCod)
+	ocoCod, err := d.insertOcorrencia(cliCodigo, cliCpfCnpj, ocorrenciaTipo, cliNome,
+		"Email inválido. Não foi possível o envio de mensagem para esse email, favor preencher o email corretamente.",
+		provedor)
 	if err != nil {
-		d.Logger.Errorw("Erro ao obter MAX(OcoCod)", "error", err)
+		d.Logger.Errorw("Falha ao inserir ocorrência de email inconsistente",
+			"error", err,
+			"cliCodigo", cliCodigo,
+			"email", enderecoEmail)
 		return err
 	}
 
-	d.Logger.Infow("Próximo OcoCod calculado",
-		"ococod", maxOcoCod)
+	d.Logger.Infow("Ocorrência de email inconsistente inserida com sucesso",
+		"ococod", ocoCod,
+		"cliCodigo", cliCodigo,
+		"email", enderecoEmail,
+		"tipo", ocorrenciaTipo,
+		"provedor", provedor,
+		"campo", campoLimpar)
+
+	// Limpar email inconsistente
+	// Dentro de WithTx o erro desfaz também a ocorrência inserida
+	if err := d.LimpaEmailInconsistente(campoLimpar, cliCodigo); err != nil {
+		d.Logger.Errorw("Erro ao limpar email inconsistente", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// insertOcorrencia insere a ocorrência de contato inconsistente e retorna o OcoCod gerado
+// OcoCod é gerado pela estratégia configurada ([database] ocorrencia_key): sequence ou MAX+1 com
+// nova tentativa em violação de chave única (retry)
+func (d *Database) insertOcorrencia(cliCodigo int, cliCpfCnpj string, ocorrenciaTipo int, cliNome, descricao, provedor string) (int, error) {
+	allocator := d.keyAllocator(TableOcorrencia)
+
+	next := func() (int, error) {
+		if allocator.Strategy == KeySequence {
+			return d.nextSequenceValue(allocator.Sequence)
+		}
+
+		var maxOcoCod int
+		if err := d.QueryRow(`SELECT NVL(MAX(OcoCod), 0) + 1 FROM Ocorrencia`).Scan(&maxOcoCod); err != nil {
+			d.Logger.Errorw("Erro ao obter MAX(OcoCod)", "error", err)
+			return 0, err
+		}
+		return maxOcoCod, nil
+	}
 
 	// Inserir ocorrência usando bind variables (proteção SQL injection)
-	now := time.Now()
 	queryInsert := `INSERT INTO Ocorrencia(
 		OcoCod,
 		EntCod,
@@ -199,40 +280,23 @@ func (d *Database) InsereOcorrenciaEmailInconsistente(
 		DatCad
 	) VALUES(:1, :2, :3, :4, :5, 2, :6, :7, :8, :9, :10, :11)`
 
-	if _, err := d.Exec(queryInsert,
-		maxOcoCod,
-		d.Config.Organization.Codigo,
-		cliCodigo,
-		cliCpfCnpj,
-		ocorrenciaTipo,
-		cliNome,
-		"Email inválido. Não foi possível o envio de mensagem para esse email, favor preencher o email corretamente.",
-		"WebHookSendGrid",
-		now,
-		"WebHookSendGrid",
-		now,
-	); err != nil {
-		d.Logger.Errorw("Falha ao inserir ocorrência de email inconsistente",
-			"error", err,
-			"cliCodigo", cliCodigo,
-			"email", enderecoEmail)
+	return d.withKeyRetry(allocator, next, func(ocoCod int) error {
+		now := time.Now()
+		_, err := d.Exec(queryInsert,
+			ocoCod,
+			d.Config.Organization.Codigo,
+			cliCodigo,
+			cliCpfCnpj,
+			ocorrenciaTipo,
+			cliNome,
+			descricao,
+			provedor,
+			now,
+			provedor,
+			now,
+		)
 		return err
-	}
-
-	d.Logger.Infow("Ocorrência de email inconsistente inserida com sucesso",
-		"cliCodigo", cliCodigo,
-		"email", enderecoEmail,
-		"tipo", ocorrenciaTipo,
-		"provedor", provedor,
-		"campo", campoLimpar)
-
-	// Limpar email inconsistente
-	if err := d.LimpaEmailInconsistente(campoLimpar, cliCodigo); err != nil {
-		d.Logger.Errorw("Erro ao limpar email inconsistente", "error", err)
-		// Não retorna erro pois a ocorrência já foi inserida
-	}
-
-	return nil
+	})
 }
 
 // LimpaEmailInconsistente limpa o campo de email inconsistente na tabela ClientesExtensao
@@ -301,8 +365,36 @@ func (d *Database) GetSMSByMessageID(smscodigo string) (*SMSData, error) {
 	return &smsData, nil
 }
 
+// GetSMSByCodigo busca dados do SMS pelo código da mensagem (smscodigo)
+// Usado quando o ID da mensagem na API não corresponde e o provedor informa o externalId do envio
+func (d *Database) GetSMSByCodigo(smscodigo int) (*SMSData, error) {
+	query := `SELECT s.smscodigo, s.clicodigo, l.logsapiid
+	          FROM smsmensagem s
+	          INNER JOIN logsapi l ON s.smscodigo = l.emsgcodigo
+	          WHERE s.smscodigo = :1
+	          AND l.logsapitipmensagem = 2`
+
+	var smsData SMSData
+	err := d.DB.QueryRow(query, smscodigo).Scan(
+		&smsData.SMSNumero,
+		&smsData.CliCodigo,
+		&smsData.LogsApiId,
+	)
+	if err != nil {
+		d.Logger.Warnw("SMS não encontrado pelo código da mensagem",
+			"error", err,
+			"smscodigo", smscodigo)
+		return nil, err
+	}
+
+	return &smsData, nil
+}
+
 // InsereLogsAPISMS insere/atualiza registro na tabela logsApi e logsApiHistorico para SMS
 // Equivalente a pgInsereLogsAPISms do WinDev
+// O evento é sempre gravado em logsApiHistorico; o status atual (logsApi, smsmensagem e
+// WSMSGSTATUS) só é atualizado quando atualizaStatus é true (transição de status válida).
+// Executar dentro de WithTx para que as gravações sejam confirmadas ou desfeitas em conjunto.
 func (d *Database) InsereLogsAPISMS(
 	smscodigo int,
 	logsApiTipId int,
@@ -315,7 +407,22 @@ func (d *Database) InsereLogsAPISMS(
 	numeroCelular string,
 	logsApiId int,
 	logsApiTag string,
+	atualizaStatus bool,
+	dataEvento time.Time,
 ) error {
+	// Inserir em logsApiHistorico
+	if err := d.InsertLogsApiHistorico(logsApiId, logsApiHisDescricao, logsApiStatus, logsApiTag); err != nil {
+		return err
+	}
+
+	if !atualizaStatus {
+		d.Logger.Infow("Evento gravado apenas no histórico - status atual mantido",
+			"smscodigo", smscodigo,
+			"logsApiId", logsApiId,
+			"status", logsApiStatus)
+		return nil
+	}
+
 	// Atualizar tabela logsApi usando bind variables (proteção SQL injection)
 	queryUpdateLogsApi := `UPDATE logsapi
 		SET logsapistatus = :1,
@@ -337,13 +444,14 @@ func (d *Database) InsereLogsAPISMS(
 		"logsApiId", logsApiId,
 		"status", logsApiStatus)
 
-	// Inserir em logsApiHistorico
-	if err := d.InsertLogsApiHistorico(logsApiId, logsApiHisDescricao, logsApiStatus, logsApiTag); err != nil {
+	// Atualizar status da mensagem na tabela smsmensagem
+	if err := d.SetMsgStatusSMS(logsApiStatus, logsApiHisDescricao, smscodigo); err != nil {
 		return err
 	}
 
-	// Atualizar status da mensagem na tabela smsmensagem
-	if err := d.SetMsgStatusSMS(logsApiStatus, logsApiHisDescricao, smscodigo); err != nil {
+	// Registrar o timestamp do evento aplicado (comparado com os próximos eventos)
+	if err := d.SaveMessageStatus(logsApiId, logsApiStatus, dataEvento); err != nil {
+		d.Logger.Errorw("Falha ao registrar status em WSMSGSTATUS", "error", err, "logsApiId", logsApiId)
 		return err
 	}
 
@@ -358,15 +466,36 @@ func (d *Database) InsereLogsAPISMS(
 
 // InsertLogsApiHistorico insere registro no histórico da API
 // Equivalente a pgInsertLogsApiHistorico do WinDev
+// LogsApiHisSequencial é gerado pela estratégia configurada ([database] logsapihistorico_key):
+// sequence, lock da linha da mensagem em logsapi (serializa os eventos da mensagem) ou retry.
 func (d *Database) InsertLogsApiHistorico(logsApiId int, logsApiHisDescricao string, logsApiStatus int, plataforma string) error {
-	// Obter próximo sequencial
-	querySeq := `SELECT NVL(MAX(LogsApiHisSequencial), 0) + 1 FROM LogsApiHistorico WHERE LogsApiId = :1`
+	allocator := d.keyAllocator(TableLogsApiHistorico)
+	if allocator.Strategy != KeyLock {
+		return d.insertLogsApiHistorico(allocator, logsApiId, logsApiHisDescricao, logsApiStatus, plataforma)
+	}
 
-	var sequencial int
-	err := d.DB.QueryRow(querySeq, logsApiId).Scan(&sequencial)
-	if err != nil {
-		d.Logger.Errorw("Erro ao obter sequencial", "error", err, "logsApiId", logsApiId)
-		return err
+	// O lock vale até o fim da transação: fora de WithTx é aberta uma transação própria
+	return d.WithTx(context.Background(), func(tx *Database) error {
+		var id int
+		if err := tx.QueryRow(`SELECT logsapiid FROM logsapi WHERE logsapiid = :1 FOR UPDATE`, logsApiId).Scan(&id); err != nil {
+			d.Logger.Errorw("Erro ao bloquear logsApi para gerar o sequencial", "error", err, "logsApiId", logsApiId)
+			return err
+		}
+		return tx.insertLogsApiHistorico(allocator, logsApiId, logsApiHisDescricao, logsApiStatus, plataforma)
+	})
+}
+
+// insertLogsApiHistorico gera o sequencial e insere o histórico
+func (d *Database) insertLogsApiHistorico(allocator KeyAllocator, logsApiId int, logsApiHisDescricao string, logsApiStatus int, plataforma string) error {
+	// Obter próximo sequencial
+	next := func() (int, error) {
+		if allocator.Strategy == KeySequence {
+			return d.nextSequenceValue(allocator.Sequence)
+		}
+
+		var sequencial int
+		err := d.QueryRow(`SELECT NVL(MAX(LogsApiHisSequencial), 0) + 1 FROM LogsApiHistorico WHERE LogsApiId = :1`, logsApiId).Scan(&sequencial)
+		return sequencial, err
 	}
 
 	// Inserir histórico usando bind variables (proteção SQL injection)
@@ -379,17 +508,22 @@ func (d *Database) InsertLogsApiHistorico(logsApiId int, logsApiHisDescricao str
 		logsapihisdata
 	) VALUES(:1, :2, :3, :4, :5, :6)`
 
-	if _, err := d.Exec(queryInsert,
-		logsApiId,
-		sequencial,
-		plataforma,
-		logsApiHisDescricao,
-		logsApiStatus,
-		time.Now(),
-	); err != nil {
+	sequencial, err := d.withKeyRetry(allocator, next, func(sequencial int) error {
+		_, err := d.Exec(queryInsert,
+			logsApiId,
+			sequencial,
+			plataforma,
+			logsApiHisDescricao,
+			logsApiStatus,
+			time.Now(),
+		)
+		return err
+	})
+	if err != nil {
 		d.Logger.Errorw("Falha ao inserir em logsApiHistorico",
 			"error", err,
-			"logsApiId", logsApiId)
+			"logsApiId", logsApiId,
+			"estrategia", allocator.Strategy)
 		return err
 	}
 
@@ -425,11 +559,40 @@ func (d *Database) SetMsgStatusSMS(logsApiStatus int, logsApiHisDescricao string
 
 // InsereOcorrenciaSmsInconsistente insere ocorrência de SMS inconsistente
 // Equivalente a pgInsereOcorrenciaSmsInconsistente do WinDev
+// Executar dentro de WithTx para que a ocorrência e a limpeza do celular sejam atômicas.
 func (d *Database) InsereOcorrenciaSmsInconsistente(
 	numeroCelular string,
 	cliCodigo int,
 	ocorrenciaTipo int,
 	provedor string,
+) error {
+	return d.insereOcorrenciaCelularInconsistente(numeroCelular, cliCodigo, ocorrenciaTipo, provedor,
+		"Celular inválido. Não foi possível o envio de mensagem para esse celular, favor preencher o celular corretamente.",
+		true)
+}
+
+// InsereOcorrenciaWhatsAppInconsistente insere ocorrência de WhatsApp inconsistente
+// O celular do cliente é mantido: a falha no WhatsApp não invalida o número para SMS e ligações.
+func (d *Database) InsereOcorrenciaWhatsAppInconsistente(
+	numeroCelular string,
+	cliCodigo int,
+	ocorrenciaTipo int,
+	provedor string,
+) error {
+	return d.insereOcorrenciaCelularInconsistente(numeroCelular, cliCodigo, ocorrenciaTipo, provedor,
+		"WhatsApp inválido. Não foi possível o envio de mensagem pelo WhatsApp para esse celular, favor conferir o celular.",
+		false)
+}
+
+// insereOcorrenciaCelularInconsistente insere a ocorrência de celular inconsistente do cliente
+// e, se limpar for verdadeiro, limpa o celular
+func (d *Database) insereOcorrenciaCelularInconsistente(
+	numeroCelular string,
+	cliCodigo int,
+	ocorrenciaTipo int,
+	provedor string,
+	descricao string,
+	limpar bool,
 ) error {
 	// Buscar dados do cliente
 	queryCliente := `SELECT c.clinome, c.clicpfcnpj, c.clicelular, c.clidddcelular
@@ -437,7 +600,7 @@ func (d *Database) InsereOcorrenciaSmsInconsistente(
 		WHERE c.clicodigo = :1`
 
 	var cliNome, cliCpfCnpj, cliCelular, clidddcelular string
-	err := d.DB.QueryRow(queryCliente, cliCodigo).Scan(&cliNome, &cliCpfCnpj, &cliCelular, &clidddcelular)
+	err := d.QueryRow(queryCliente, cliCodigo).Scan(&cliNome, &cliCpfCnpj, &cliCelular, &clidddcelular)
 	if err != nil {
 		d.Logger.Errorw("Erro ao buscar dados do cliente", "error", err, "cliCodigo", cliCodigo)
 		return err
@@ -468,65 +631,32 @@ func (d *Database) InsereOcorrenciaSmsInconsistente(
 		return nil
 	}
 
-	// Obter próximo ID usando MAX+1
-	var maxOcoCod int
-	queryMax := `SELECT NVL(MAX(OcoCod), 0) + 1 FROM Ocorrencia`
-	err = d.DB.QueryRow(queryMax).Scan(&maxOcoCod)
+	ocoCod, err := d.insertOcorrencia(cliCodigo, cliCpfCnpj, ocorrenciaTipo, cliNome, descricao, provedor)
 	if err != nil {
-		d.Logger.Errorw("Erro ao obter MAX(OcoCod)", "error", err)
-		return err
-	}
-
-	d.Logger.Infow("Próximo OcoCod calculado",
-		"ococod", maxOcoCod)
-
-	// Inserir ocorrência usando bind variables (proteção SQL injection)
-	now := time.Now()
-	queryInsert := `INSERT INTO Ocorrencia(
-		OcoCod,
-		EntCod,
-		CliCod,
-		CodPltCod,
-		TocNum,
-		OcoTip,
-		OcoCliNon,
-		OcoDsc,
-		OcoUsrSol,
-		OcoSolDta,
-		UsrAlt,
-		DatCad
-	) VALUES(:1, :2, :3, :4, :5, 2, :6, :7, :8, :9, :10, :11)`
-
-	if _, err := d.Exec(queryInsert,
-		maxOcoCod,
-		d.Config.Organization.Codigo,
-		cliCodigo,
-		cliCpfCnpj,
-		ocorrenciaTipo,
-		cliNome,
-		"Celular inválido. Não foi possível o envio de mensagem para esse celular, favor preencher o celular corretamente.",
-		provedor,
-		now,
-		provedor,
-		now,
-	); err != nil {
-		d.Logger.Errorw("Falha ao inserir ocorrência de SMS inconsistente",
+		d.Logger.Errorw("Falha ao inserir ocorrência de celular inconsistente",
 			"error", err,
 			"cliCodigo", cliCodigo,
 			"celular", numeroCelular)
 		return err
 	}
 
-	d.Logger.Infow("Ocorrência de SMS inconsistente inserida com sucesso",
+	d.Logger.Infow("Ocorrência de celular inconsistente inserida com sucesso",
+		"ococod", ocoCod,
 		"cliCodigo", cliCodigo,
 		"celular", numeroCelular,
 		"tipo", ocorrenciaTipo,
-		"provedor", provedor)
+		"provedor", provedor,
+		"limpar_celular", limpar)
+
+	if !limpar {
+		return nil
+	}
 
 	// Limpar celular inconsistente
+	// Dentro de WithTx o erro desfaz também a ocorrência inserida
 	if err := d.LimpaCelularInconsistente(cliCodigo); err != nil {
 		d.Logger.Errorw("Erro ao limpar celular inconsistente", "error", err)
-		// Não retorna erro pois a ocorrência já foi inserida
+		return err
 	}
 
 	return nil
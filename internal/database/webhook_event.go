@@ -0,0 +1,54 @@
+package database
+
+import (
+	"time"
+)
+
+// WebhookEventData identifica um evento de webhook recebido (registro de WSWEBHOOKEVENTOS)
+type WebhookEventData struct {
+	Chave      string // Hash da chave de deduplicação (provedor, message id, status e data do evento)
+	Provedor   string // Ex: zenvia-email, zenvia-sms
+	MessageID  string
+	Status     string
+	DataEvento string // Timestamp do evento informado pelo provedor
+	UUID       string // UUID da requisição que processou o evento (relaciona com WSREQUISICOES)
+}
+
+// RegisterWebhookEvent registra um evento em WSWEBHOOKEVENTOS
+// Retorna false se o evento já estava registrado (duplicado). A chave primária garante
+// que apenas uma requisição registre o evento, mesmo com várias instâncias.
+// Executar dentro de WithTx junto com a gravação do evento.
+func (d *Database) RegisterWebhookEvent(event WebhookEventData) (bool, error) {
+	query := `INSERT INTO WSWEBHOOKEVENTOS(WSWEVCHAVE, WSWEVPROVEDOR, WSWEVMSGID, WSWEVSTATUS,
+		WSWEVDTAEVENTO, WSWEVUUID, WSWEVDATA)
+		VALUES(:1, :2, :3, :4, :5, :6, :7)`
+
+	_, err := d.Exec(query, event.Chave, event.Provedor, event.MessageID, event.Status,
+		event.DataEvento, event.UUID, time.Now())
+	if IsUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// WebhookEventExists indica se o evento já está registrado em WSWEBHOOKEVENTOS (consulta sem registrar)
+func (d *Database) WebhookEventExists(chave string) (bool, error) {
+	var count int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM WSWEBHOOKEVENTOS WHERE WSWEVCHAVE = :1`, chave).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// PurgeWebhookEvents remove os eventos registrados antes da data informada
+func (d *Database) PurgeWebhookEvents(before time.Time) (int64, error) {
+	result, err := d.Exec(`DELETE FROM WSWEBHOOKEVENTOS WHERE WSWEVDATA < :1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Situação dos eventos em WSWEBHOOKPENDENTES
+const (
+	PendenteAguardando = 0 // Aguardando reprocessamento
+	PendenteProcessado = 1 // Mensagem encontrada e evento aplicado
+	PendenteExpirado   = 2 // Idade máxima atingida sem encontrar a mensagem
+)
+
+// WebhookPendingData evento de webhook sem mensagem correspondente (registro de WSWEBHOOKPENDENTES)
+type WebhookPendingData struct {
+	Numero           int64
+	Provedor         string // Ex: zenvia-email, zenvia-sms
+	MessageID        string
+	ExternalID       string
+	Status           string // Código do evento no provedor
+	Evento           string // Evento normalizado (JSON de webhook.Event)
+	UUID             string // Requisição que recebeu o evento (relaciona com WSREQUISICOES)
+	Data             time.Time
+	Tentativas       int
+	ProximaTentativa *time.Time
+	Situacao         int // PendenteAguardando, PendenteProcessado ou PendenteExpirado
+	Erro             string
+	DataFim          *time.Time
+}
+
+// webhookPendingColumns colunas lidas por scanWebhookPending
+const webhookPendingColumns = `WSWPENUMERO, WSWPEPROVEDOR, WSWPEMSGID, WSWPEEXTERNALID, WSWPESTATUS, WSWPEEVENTO,
+	WSWPEUUID, WSWPEDATA, NVL(WSWPETENTATIVAS, 0), WSWPEPROXIMA, NVL(WSWPESITUACAO, 0), WSWPEERRO, WSWPEDTAFIM`
+
+// InsertWebhookPending grava um evento sem mensagem correspondente para reprocessamento
+func (d *Database) InsertWebhookPending(data WebhookPendingData, proximaTentativa time.Time) (int64, error) {
+	var numero int64
+	if err := d.QueryRow(`SELECT SEQ_WSWPENUMERO.NEXTVAL FROM DUAL`).Scan(&numero); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO WSWEBHOOKPENDENTES(WSWPENUMERO, WSWPEPROVEDOR, WSWPEMSGID, WSWPEEXTERNALID, WSWPESTATUS,
+		WSWPEEVENTO, WSWPEUUID, WSWPEDATA, WSWPETENTATIVAS, WSWPEPROXIMA, WSWPESITUACAO)
+		VALUES(:1, :2, :3, :4, :5, :6, :7, :8, 0, :9, 0)`
+
+	_, err := d.Exec(query, numero, data.Provedor, data.MessageID, data.ExternalID, data.Status,
+		data.Evento, data.UUID, time.Now(), proximaTentativa)
+	if err != nil {
+		return 0, err
+	}
+
+	return numero, nil
+}
+
+// GetWebhookPending busca um evento pendente pelo número
+// Retorna nil, nil se o evento não existir
+func (d *Database) GetWebhookPending(numero int64) (*WebhookPendingData, error) {
+	rows, err := d.DB.Query(`SELECT `+webhookPendingColumns+` FROM WSWEBHOOKPENDENTES WHERE WSWPENUMERO = :1`, numero)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending, err := scanWebhookPendings(rows)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	return &pending[0], nil
+}
+
+// ListDueWebhookPendings retorna os eventos aguardando reprocessamento com tentativa vencida
+func (d *Database) ListDueWebhookPendings(now time.Time, limit int) ([]WebhookPendingData, error) {
+	query := `SELECT * FROM (
+		SELECT ` + webhookPendingColumns + `
+		FROM WSWEBHOOKPENDENTES
+		WHERE WSWPESITUACAO = 0
+		AND WSWPEPROXIMA <= :1
+		ORDER BY WSWPEPROXIMA
+	) WHERE ROWNUM <= :2`
+
+	rows, err := d.DB.Query(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookPendings(rows)
+}
+
+// ListWebhookPendings lista os eventos de uma situação, opcionalmente de um provedor, do mais recente ao mais antigo
+func (d *Database) ListWebhookPendings(situacao int, provedor string, limit int) ([]WebhookPendingData, error) {
+	query := `SELECT * FROM (
+		SELECT ` + webhookPendingColumns + `
+		FROM WSWEBHOOKPENDENTES
+		WHERE WSWPESITUACAO = :1
+		AND (:2 IS NULL OR WSWPEPROVEDOR = :3)
+		ORDER BY WSWPEDATA DESC
+	) WHERE ROWNUM <= :4`
+
+	var filtroProvedor interface{}
+	if provedor != "" {
+		filtroProvedor = provedor
+	}
+
+	rows, err := d.DB.Query(query, situacao, filtroProvedor, filtroProvedor, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookPendings(rows)
+}
+
+// UpdateWebhookPending registra o resultado de um reprocessamento
+func (d *Database) UpdateWebhookPending(numero int64, tentativas int, proximaTentativa *time.Time, situacao int, erro string) error {
+	var dataFim interface{}
+	if situacao != PendenteAguardando {
+		dataFim = time.Now()
+	}
+	if len(erro) > 1000 {
+		erro = erro[:1000]
+	}
+
+	query := `UPDATE WSWEBHOOKPENDENTES
+		SET WSWPETENTATIVAS = :1, WSWPEPROXIMA = :2, WSWPESITUACAO = :3, WSWPEERRO = :4, WSWPEDTAFIM = :5
+		WHERE WSWPENUMERO = :6`
+
+	_, err := d.Exec(query, tentativas, proximaTentativa, situacao, erro, dataFim, numero)
+	return err
+}
+
+// scanWebhookPendings lê as linhas de WSWEBHOOKPENDENTES (colunas de webhookPendingColumns)
+func scanWebhookPendings(rows *sql.Rows) ([]WebhookPendingData, error) {
+	var pending []WebhookPendingData
+	for rows.Next() {
+		var data WebhookPendingData
+		var msgID, externalID, status, uuid, erro sql.NullString
+		var proxima, dataFim sql.NullTime
+		if err := rows.Scan(&data.Numero, &data.Provedor, &msgID, &externalID, &status, &data.Evento,
+			&uuid, &data.Data, &data.Tentativas, &proxima, &data.Situacao, &erro, &dataFim); err != nil {
+			return nil, err
+		}
+		data.MessageID = msgID.String
+		data.ExternalID = externalID.String
+		data.Status = status.String
+		data.UUID = uuid.String
+		data.Erro = erro.String
+		if proxima.Valid {
+			data.ProximaTentativa = &proxima.Time
+		}
+		if dataFim.Valid {
+			data.DataFim = &dataFim.Time
+		}
+		pending = append(pending, data)
+	}
+
+	return pending, rows.Err()
+}
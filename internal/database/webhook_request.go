@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// WebhookRequestFilter filtro das requisições de webhook gravadas em WSREQUISICOES
+// Campos vazios (ou zero) não filtram
+type WebhookRequestFilter struct {
+	UUID        string
+	De          *time.Time // WSREQDTARECEBE >= De
+	Ate         *time.Time // WSREQDTARECEBE <= Ate
+	Endpoint    string     // Ex: /webhook/zenvia/email
+	CodResposta int        // WSREQCODRESPOSTA
+	Limite      int
+}
+
+// WebhookRequestData requisição de webhook gravada em WSREQUISICOES (payload em WSREQPARAMETROS)
+type WebhookRequestData struct {
+	UUID        string
+	DataRecebe  time.Time
+	Endpoint    string
+	CodResposta int
+	Procedure   string
+	Parametros  string
+}
+
+// ListWebhookRequests lista as requisições recebidas em /webhook que atendem ao filtro,
+// em ordem de recebimento (a ordem original dos eventos de cada mensagem é preservada)
+func (d *Database) ListWebhookRequests(filter WebhookRequestFilter) ([]WebhookRequestData, error) {
+	query := `SELECT * FROM (
+		SELECT WSREQUUID, WSREQDTARECEBE, WSREQENDPOINT, WSREQCODRESPOSTA, WSREQPROCEDURE, WSREQPARAMETROS
+		FROM WSREQUISICOES
+		WHERE WSREQENDPOINT LIKE '/WEBHOOK/%'
+		AND (:1 IS NULL OR WSREQUUID = :2)
+		AND (:3 IS NULL OR WSREQDTARECEBE >= :4)
+		AND (:5 IS NULL OR WSREQDTARECEBE <= :6)
+		AND (:7 IS NULL OR WSREQENDPOINT = :8)
+		AND (:9 IS NULL OR WSREQCODRESPOSTA = :10)
+		ORDER BY WSREQDTARECEBE
+	) WHERE ROWNUM <= :11`
+
+	var filtroUUID, filtroDe, filtroAte, filtroEndpoint, filtroCodigo interface{}
+	if filter.UUID != "" {
+		filtroUUID = filter.UUID
+	}
+	if filter.De != nil {
+		filtroDe = *filter.De
+	}
+	if filter.Ate != nil {
+		filtroAte = *filter.Ate
+	}
+	if filter.Endpoint != "" {
+		// GravaLogDB grava o endpoint em maiúsculas
+		filtroEndpoint = strings.ToUpper(filter.Endpoint)
+	}
+	if filter.CodResposta != 0 {
+		filtroCodigo = filter.CodResposta
+	}
+
+	rows, err := d.DB.Query(query,
+		filtroUUID, filtroUUID,
+		filtroDe, filtroDe,
+		filtroAte, filtroAte,
+		filtroEndpoint, filtroEndpoint,
+		filtroCodigo, filtroCodigo,
+		filter.Limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []WebhookRequestData
+	for rows.Next() {
+		var data WebhookRequestData
+		var procedure, parametros sql.NullString
+		if err := rows.Scan(&data.UUID, &data.DataRecebe, &data.Endpoint, &data.CodResposta,
+			&procedure, &parametros); err != nil {
+			return nil, err
+		}
+		data.Procedure = procedure.String
+		data.Parametros = parametros.String
+		requests = append(requests, data)
+	}
+
+	return requests, rows.Err()
+}
@@ -0,0 +1,44 @@
+package database
+
+import "database/sql"
+
+// WebhookStatusRuleData regra de mapeamento de evento de webhook para status do CRM (registro de WSWEBHOOKSTATUS)
+type WebhookStatusRuleData struct {
+	Provedor   string // Provedor e canal, ex: zenvia-email, zenvia-sms
+	Evento     string // Código do evento no provedor (minúsculo) ou chave derivada (ex: hard_bounce)
+	Status     int    // Status do CRM (0 = apenas histórico)
+	Descricao  string // Modelo da descrição gravada em logsApiHistorico ({descricao}, {detalhes})
+	Tag        string // Tag gravada em logsApiHistorico
+	Ocorrencia bool   // Gera ocorrência de contato inconsistente
+	Ativo      bool   // Regras inativas ignoram o evento
+}
+
+// ListWebhookStatusRules retorna as regras de mapeamento de status cadastradas em WSWEBHOOKSTATUS
+func (d *Database) ListWebhookStatusRules() ([]WebhookStatusRuleData, error) {
+	query := `SELECT WSWSTPROVEDOR, WSWSTEVENTO, WSWSTSTATUS, WSWSTDESCRICAO, WSWSTTAG,
+		NVL(WSWSTOCORRENCIA, 0), NVL(WSWSTATIVO, 1)
+		FROM WSWEBHOOKSTATUS`
+
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []WebhookStatusRuleData
+	for rows.Next() {
+		var rule WebhookStatusRuleData
+		var descricao, tag sql.NullString
+		var ocorrencia, ativo int
+		if err := rows.Scan(&rule.Provedor, &rule.Evento, &rule.Status, &descricao, &tag, &ocorrencia, &ativo); err != nil {
+			return nil, err
+		}
+		rule.Descricao = descricao.String
+		rule.Tag = tag.String
+		rule.Ocorrencia = ocorrencia == 1
+		rule.Ativo = ativo == 1
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
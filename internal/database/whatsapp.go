@@ -0,0 +1,157 @@
+package database
+
+import "time"
+
+// WhatsAppData representa os dados da mensagem WhatsApp recuperados do banco
+type WhatsAppData struct {
+	WhatsAppNumero int
+	CliCodigo      int
+	LogsApiId      int
+	NumeroCelular  string
+}
+
+// GetWhatsAppByMessageID busca dados da mensagem WhatsApp pelo ID da mensagem na Zenvia
+// Mensagens WhatsApp são registradas em logsapi com logsapitipmensagem = 3
+func (d *Database) GetWhatsAppByMessageID(apiMessageID string) (*WhatsAppData, error) {
+	query := `SELECT w.wppcodigo, w.clicodigo, l.logsapiid
+	          FROM whatsappmensagem w
+	          INNER JOIN logsapi l ON w.wppcodigo = l.emsgcodigo
+	          WHERE w.WPPAPIID = :1
+	          AND l.logsapitipmensagem = 3`
+
+	d.Logger.Infow("Executando query para buscar WhatsApp",
+		"query", query,
+		"wppapiid_buscado", apiMessageID,
+		"wppapiid_length", len(apiMessageID),
+		"wppapiid_is_empty", apiMessageID == "")
+
+	var whatsAppData WhatsAppData
+	err := d.DB.QueryRow(query, apiMessageID).Scan(
+		&whatsAppData.WhatsAppNumero,
+		&whatsAppData.CliCodigo,
+		&whatsAppData.LogsApiId,
+	)
+
+	if err != nil {
+		d.Logger.Warnw("Query não retornou resultados",
+			"error", err,
+			"wppapiid_buscado", apiMessageID)
+		return nil, err
+	}
+
+	d.Logger.Infow("WhatsApp encontrado no banco",
+		"wppcodigo", whatsAppData.WhatsAppNumero,
+		"clicodigo", whatsAppData.CliCodigo,
+		"logsapiid", whatsAppData.LogsApiId)
+
+	return &whatsAppData, nil
+}
+
+// GetWhatsAppByCodigo busca dados da mensagem WhatsApp pelo código da mensagem (wppcodigo)
+// Usado quando o ID da mensagem na Zenvia não corresponde e o provedor informa o externalId do envio
+func (d *Database) GetWhatsAppByCodigo(wppcodigo int) (*WhatsAppData, error) {
+	query := `SELECT w.wppcodigo, w.clicodigo, l.logsapiid
+	          FROM whatsappmensagem w
+	          INNER JOIN logsapi l ON w.wppcodigo = l.emsgcodigo
+	          WHERE w.wppcodigo = :1
+	          AND l.logsapitipmensagem = 3`
+
+	var whatsAppData WhatsAppData
+	err := d.DB.QueryRow(query, wppcodigo).Scan(
+		&whatsAppData.WhatsAppNumero,
+		&whatsAppData.CliCodigo,
+		&whatsAppData.LogsApiId,
+	)
+	if err != nil {
+		d.Logger.Warnw("WhatsApp não encontrado pelo código da mensagem",
+			"error", err,
+			"wppcodigo", wppcodigo)
+		return nil, err
+	}
+
+	return &whatsAppData, nil
+}
+
+// InsereLogsAPIWhatsApp insere/atualiza registro na tabela logsApi e logsApiHistorico para WhatsApp
+// O evento é sempre gravado em logsApiHistorico; o status atual (logsApi, whatsappmensagem e
+// WSMSGSTATUS) só é atualizado quando atualizaStatus é true (transição de status válida).
+// Executar dentro de WithTx para que as gravações sejam confirmadas ou desfeitas em conjunto.
+func (d *Database) InsereLogsAPIWhatsApp(
+	wppcodigo int,
+	logsApiStatus int,
+	logsApiRetorno string,
+	logsApiHisDescricao string,
+	logsApiId int,
+	logsApiTag string,
+	atualizaStatus bool,
+	dataEvento time.Time,
+) error {
+	// Inserir em logsApiHistorico
+	if err := d.InsertLogsApiHistorico(logsApiId, logsApiHisDescricao, logsApiStatus, logsApiTag); err != nil {
+		return err
+	}
+
+	if !atualizaStatus {
+		d.Logger.Infow("Evento gravado apenas no histórico - status atual mantido",
+			"wppcodigo", wppcodigo,
+			"logsApiId", logsApiId,
+			"status", logsApiStatus)
+		return nil
+	}
+
+	// Atualizar tabela logsApi usando bind variables (proteção SQL injection)
+	queryUpdateLogsApi := `UPDATE logsapi
+		SET logsapistatus = :1,
+		    logsapiretorno = :2
+		WHERE logsapiid = :3`
+
+	if _, err := d.Exec(queryUpdateLogsApi,
+		logsApiStatus,
+		logsApiRetorno,
+		logsApiId,
+	); err != nil {
+		d.Logger.Errorw("Falha ao atualizar logsApi",
+			"error", err,
+			"logsApiId", logsApiId)
+		return err
+	}
+
+	// Atualizar status da mensagem na tabela whatsappmensagem
+	if err := d.SetMsgStatusWhatsApp(logsApiStatus, wppcodigo); err != nil {
+		return err
+	}
+
+	// Registrar o timestamp do evento aplicado (comparado com os próximos eventos)
+	if err := d.SaveMessageStatus(logsApiId, logsApiStatus, dataEvento); err != nil {
+		d.Logger.Errorw("Falha ao registrar status em WSMSGSTATUS", "error", err, "logsApiId", logsApiId)
+		return err
+	}
+
+	d.Logger.Infow("Logs API WhatsApp atualizados com sucesso",
+		"wppcodigo", wppcodigo,
+		"logsApiId", logsApiId,
+		"status", logsApiStatus,
+		"tag", logsApiTag)
+
+	return nil
+}
+
+// SetMsgStatusWhatsApp atualiza o status da mensagem na tabela whatsappmensagem
+func (d *Database) SetMsgStatusWhatsApp(logsApiStatus int, wppcodigo int) error {
+	query := `UPDATE whatsappmensagem
+		SET wppstsenvio = :1
+		WHERE wppcodigo = :2`
+
+	if _, err := d.Exec(query, logsApiStatus, wppcodigo); err != nil {
+		d.Logger.Errorw("Falha ao atualizar status WhatsApp",
+			"error", err,
+			"wppcodigo", wppcodigo)
+		return err
+	}
+
+	d.Logger.Infow("Status WhatsApp atualizado com sucesso",
+		"wppcodigo", wppcodigo,
+		"status", logsApiStatus)
+
+	return nil
+}
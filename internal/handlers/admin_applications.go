@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListApplications godoc
+// @Summary List applications
+// @Description Lists every application registered in WSAPLICACOES (client_secret is never returned)
+// @Tags Administration
+// @Produce json
+// @Success 200 {object} models.ApplicationListResponse "Applications"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 500 {object} models.ApplicationListResponse "Database error"
+// @Router /connect/v1/admin/applications [get]
+// @Security BearerAuth
+func ListApplications(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "ListApplications"
+
+		apps, err := db.ListApplications()
+		if err != nil {
+			logger.Errorw("Erro ao listar aplicações", "error", err)
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusInternalServerError, models.ApplicationListResponse{
+				Code:         "003",
+				Message:      "Falha ao consultar aplicações.",
+				Applications: []models.ApplicationInfo{},
+			})
+			return
+		}
+
+		response := models.ApplicationListResponse{Code: "000", Applications: []models.ApplicationInfo{}}
+		for _, app := range apps {
+			response.Applications = append(response.Applications, applicationInfo(app))
+		}
+
+		respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusOK, response)
+	}
+}
+
+// GetApplication godoc
+// @Summary Get an application
+// @Description Returns a single application from WSAPLICACOES
+// @Tags Administration
+// @Produce json
+// @Param client_id path string true "Application client_id"
+// @Success 200 {object} models.ApplicationResponse "Application"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.ApplicationResponse "Application not found"
+// @Failure 500 {object} models.ApplicationResponse "Database error"
+// @Router /connect/v1/admin/applications/{client_id} [get]
+// @Security BearerAuth
+func GetApplication(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "GetApplication"
+		clientID := c.Param("client_id")
+		reqParametros := "Client_Id: " + clientID + "\n"
+
+		app, ok := loadApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, clientID)
+		if !ok {
+			return
+		}
+
+		info := applicationInfo(*app)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.ApplicationResponse{
+			Code:        "000",
+			Application: &info,
+		})
+	}
+}
+
+// CreateApplication godoc
+// @Summary Create an application
+// @Description Registers a new application in WSAPLICACOES. A random client_secret is generated, stored as an
+// @Description argon2id hash and returned in the response exactly once.
+// @Tags Administration
+// @Accept json
+// @Produce json
+// @Param body body models.ApplicationRequest true "client_id, nome and scopo are required"
+// @Success 201 {object} models.ApplicationResponse "Application created (includes client_secret)"
+// @Failure 400 {object} models.ApplicationResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 409 {object} models.ApplicationResponse "client_id already exists"
+// @Failure 500 {object} models.ApplicationResponse "Database error"
+// @Router /connect/v1/admin/applications [post]
+// @Security BearerAuth
+func CreateApplication(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "CreateApplication"
+
+		req, bodyOK := readApplicationRequest(c)
+		req.ClientID = strings.TrimSpace(req.ClientID)
+		reqParametros := "Client_Id: " + req.ClientID + "\n"
+
+		// Valores padrão para novas aplicações
+		app := database.ApplicationData{
+			ClientID:     req.ClientID,
+			JWTExpiracao: 86400,
+			Status:       1,
+		}
+		applyApplicationRequest(&app, req)
+
+		message := ""
+		switch {
+		case !bodyOK:
+			message = "Corpo da requisição inválido."
+		case req.ClientID == "" || len(req.ClientID) > 100 || strings.ContainsAny(req.ClientID, ": "):
+			message = "client_id deve ser informado (até 100 caracteres, sem ':' ou espaços)."
+		case req.Scopo == nil:
+			message = "scopo deve ser informado."
+		default:
+			message = validateApplication(app)
+		}
+		if message != "" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.ApplicationResponse{
+				Code:    "001",
+				Message: message,
+			})
+			return
+		}
+
+		existing, err := db.GetApplication(app.ClientID)
+		if err != nil {
+			logger.Errorw("Erro ao buscar aplicação", "error", err, "client_id", app.ClientID)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.ApplicationResponse{
+				Code:    "003",
+				Message: "Falha ao consultar aplicação.",
+			})
+			return
+		}
+		if existing != nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusConflict, models.ApplicationResponse{
+				Code:    "004",
+				Message: "client_id já cadastrado.",
+			})
+			return
+		}
+
+		secret, secretHash, err := newHashedSecret()
+		if err == nil {
+			err = db.InsertApplication(app, secretHash)
+		}
+		if err != nil {
+			logger.Errorw("Erro ao cadastrar aplicação", "error", err, "client_id", app.ClientID)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.ApplicationResponse{
+				Code:    "003",
+				Message: "Falha ao cadastrar aplicação.",
+			})
+			return
+		}
+
+		if cache := auth.GetIPAllowlistCache(); cache != nil && app.IPPermitidos != "" {
+			cache.Refresh()
+		}
+
+		info := applicationInfo(app)
+		auditAdmin(c, db, reqCtx, "APLICACAO_CRIAR", app.ClientID, info, http.StatusCreated)
+
+		logger.Infow("Aplicação cadastrada",
+			"client_id", app.ClientID,
+			"admin_client_id", reqCtx.ClientID,
+			"admin_ip", c.ClientIP())
+
+		// O client_secret é retornado uma única vez e não é gravado no log de requisições
+		response := models.ApplicationResponse{
+			Code:        "000",
+			Message:     "Aplicação cadastrada. Guarde o client_secret: ele não será exibido novamente.",
+			Application: &info,
+		}
+		logged := response
+		response.ClientSecret = secret
+		respondWithLogAs(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusCreated, logged, response)
+	}
+}
+
+// UpdateApplication godoc
+// @Summary Update an application
+// @Description Updates nome, scopo, jwt_expiracao, refresh_expiracao and/or status. Omitted fields keep their
+// @Description current value. Setting status to 0 disables the application and revokes its live tokens.
+// @Tags Administration
+// @Accept json
+// @Produce json
+// @Param client_id path string true "Application client_id"
+// @Param body body models.ApplicationRequest true "Fields to update"
+// @Success 200 {object} models.ApplicationResponse "Application updated"
+// @Failure 400 {object} models.ApplicationResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.ApplicationResponse "Application not found"
+// @Failure 500 {object} models.ApplicationResponse "Database error"
+// @Router /connect/v1/admin/applications/{client_id} [put]
+// @Security BearerAuth
+func UpdateApplication(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "UpdateApplication"
+		clientID := c.Param("client_id")
+		reqParametros := "Client_Id: " + clientID + "\n"
+
+		req, bodyOK := readApplicationRequest(c)
+		if !bodyOK {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.ApplicationResponse{
+				Code:    "001",
+				Message: "Corpo da requisição inválido.",
+			})
+			return
+		}
+
+		app, ok := loadApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, clientID)
+		if !ok {
+			return
+		}
+
+		updated := *app
+		applyApplicationRequest(&updated, req)
+		saveApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, "APLICACAO_ALTERAR", *app, updated)
+	}
+}
+
+// SetApplicationStatus godoc
+// @Summary Enable or disable an application
+// @Description POST .../enable sets WSAPLSTATUS = 1. POST .../disable sets WSAPLSTATUS = 0 and revokes every live
+// @Description access and refresh token of the application.
+// @Tags Administration
+// @Produce json
+// @Param client_id path string true "Application client_id"
+// @Success 200 {object} models.ApplicationResponse "Status updated"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.ApplicationResponse "Application not found"
+// @Failure 500 {object} models.ApplicationResponse "Database error"
+// @Router /connect/v1/admin/applications/{client_id}/enable [post]
+// @Router /connect/v1/admin/applications/{client_id}/disable [post]
+// @Security BearerAuth
+func SetApplicationStatus(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "SetApplicationStatus"
+		clientID := c.Param("client_id")
+		reqParametros := "Client_Id: " + clientID + "\n"
+
+		app, ok := loadApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, clientID)
+		if !ok {
+			return
+		}
+
+		acao := "APLICACAO_HABILITAR"
+		if status != 1 {
+			acao = "APLICACAO_DESABILITAR"
+		}
+
+		updated := *app
+		updated.Status = status
+		saveApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, acao, *app, updated)
+	}
+}
+
+// RotateApplicationSecret godoc
+// @Summary Rotate an application secret
+// @Description Generates a new client_secret, stores its argon2id hash and returns it exactly once.
+// @Description The previous secret stops working immediately; tokens already issued remain valid.
+// @Tags Administration
+// @Produce json
+// @Param client_id path string true "Application client_id"
+// @Success 200 {object} models.ApplicationResponse "New client_secret"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.ApplicationResponse "Application not found"
+// @Failure 500 {object} models.ApplicationResponse "Database error"
+// @Router /connect/v1/admin/applications/{client_id}/rotate-secret [post]
+// @Security BearerAuth
+func RotateApplicationSecret(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "RotateApplicationSecret"
+		clientID := c.Param("client_id")
+		reqParametros := "Client_Id: " + clientID + "\n"
+
+		app, ok := loadApplication(c, cfg, db, logger, reqCtx, reqParametros, nomeProcedure, clientID)
+		if !ok {
+			return
+		}
+
+		secret, secretHash, err := newHashedSecret()
+		if err == nil {
+			_, err = db.SetClientSecret(app.ClientID, secretHash)
+		}
+		if err != nil {
+			logger.Errorw("Erro ao trocar client_secret", "error", err, "client_id", app.ClientID)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.ApplicationResponse{
+				Code:    "003",
+				Message: "Falha ao trocar client_secret.",
+			})
+			return
+		}
+
+		auditAdmin(c, db, reqCtx, "APLICACAO_TROCAR_SECRET", app.ClientID, nil, http.StatusOK)
+
+		logger.Infow("Client_secret trocado",
+			"client_id", app.ClientID,
+			"admin_client_id", reqCtx.ClientID,
+			"admin_ip", c.ClientIP())
+
+		info := applicationInfo(*app)
+		response := models.ApplicationResponse{
+			Code:        "000",
+			Message:     "Client_secret trocado. Guarde o novo valor: ele não será exibido novamente.",
+			Application: &info,
+		}
+		logged := response
+		response.ClientSecret = secret
+		respondWithLogAs(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, logged, response)
+	}
+}
+
+// loadApplication busca a aplicação do path e responde 404/500 quando não for possível
+func loadApplication(c *gin.Context, cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, reqParametros, nomeProcedure, clientID string) (*database.ApplicationData, bool) {
+	app, err := db.GetApplication(clientID)
+	if err != nil {
+		logger.Errorw("Erro ao buscar aplicação", "error", err, "client_id", clientID)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.ApplicationResponse{
+			Code:    "003",
+			Message: "Falha ao consultar aplicação.",
+		})
+		return nil, false
+	}
+	if app == nil {
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusNotFound, models.ApplicationResponse{
+			Code:    "002",
+			Message: "Aplicação não encontrada.",
+		})
+		return nil, false
+	}
+	return app, true
+}
+
+// saveApplication valida e grava as alterações, audita e revoga os tokens quando a aplicação é desabilitada
+func saveApplication(c *gin.Context, cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, reqParametros, nomeProcedure, acao string, before, after database.ApplicationData) {
+	if message := validateApplication(after); message != "" {
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.ApplicationResponse{
+			Code:    "001",
+			Message: message,
+		})
+		return
+	}
+
+	if _, err := db.UpdateApplication(after); err != nil {
+		logger.Errorw("Erro ao alterar aplicação", "error", err, "client_id", after.ClientID)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.ApplicationResponse{
+			Code:    "003",
+			Message: "Falha ao alterar aplicação.",
+		})
+		return
+	}
+
+	var revoked int64
+	if before.Status == 1 && after.Status != 1 {
+		revoked = revokeApplicationTokens(db, logger, after.ClientID)
+	}
+	if cache := auth.GetIPAllowlistCache(); cache != nil && before.IPPermitidos != after.IPPermitidos {
+		cache.Refresh()
+	}
+
+	info := applicationInfo(after)
+	auditAdmin(c, db, reqCtx, acao, after.ClientID, map[string]interface{}{
+		"antes":   applicationInfo(before),
+		"depois":  info,
+		"revoked": revoked,
+	}, http.StatusOK)
+
+	logger.Infow("Aplicação alterada",
+		"client_id", after.ClientID,
+		"acao", acao,
+		"revoked", revoked,
+		"admin_client_id", reqCtx.ClientID,
+		"admin_ip", c.ClientIP())
+
+	respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.ApplicationResponse{
+		Code:        "000",
+		Message:     "Aplicação alterada com sucesso.",
+		Application: &info,
+		Revoked:     revoked,
+	})
+}
+
+// revokeApplicationTokens revoga os tokens e refresh tokens de uma aplicação desabilitada
+func revokeApplicationTokens(db *database.Database, logger *zap.SugaredLogger, clientID string) int64 {
+	revoked, err := db.RevokeClientTokens(clientID)
+	if err != nil {
+		// O cache de revogação também rejeita os tokens de aplicações desabilitadas no próximo refresh
+		logger.Errorw("Erro ao revogar tokens da aplicação desabilitada", "error", err, "client_id", clientID)
+	}
+	if cache := auth.GetRevocationCache(); cache != nil {
+		cache.Refresh()
+	}
+
+	if _, err := db.RevokeClientRefreshTokens(clientID); err != nil {
+		logger.Errorw("Erro ao revogar refresh tokens", "error", err, "client_id", clientID)
+	}
+
+	return revoked
+}
+
+// readApplicationRequest lê o corpo JSON da requisição
+func readApplicationRequest(c *gin.Context) (models.ApplicationRequest, bool) {
+	var req models.ApplicationRequest
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		err = json.Unmarshal(bodyBytes, &req)
+	}
+	return req, err == nil
+}
+
+// applyApplicationRequest aplica os campos informados sobre a aplicação
+func applyApplicationRequest(app *database.ApplicationData, req models.ApplicationRequest) {
+	if nome := strings.TrimSpace(req.Nome); nome != "" {
+		app.Nome = nome
+	}
+	if req.Scopo != nil {
+		app.Scopo = *req.Scopo
+	}
+	if req.JWTExpiracao != nil {
+		app.JWTExpiracao = *req.JWTExpiracao
+	}
+	if req.RefreshExpiracao != nil {
+		app.RefreshExpiracao = *req.RefreshExpiracao
+	}
+	if req.Status != nil {
+		app.Status = *req.Status
+	}
+	if req.IPPermitidos != nil {
+		app.IPPermitidos = strings.TrimSpace(*req.IPPermitidos)
+	}
+	if req.Certificado != nil {
+		app.Certificado = strings.TrimSpace(*req.Certificado)
+		if normalized, err := auth.NormalizeCertificateMapping(app.Certificado); err == nil {
+			app.Certificado = normalized
+		}
+	}
+}
+
+// validateApplication retorna a mensagem de erro de validação ("" se válida)
+func validateApplication(app database.ApplicationData) string {
+	switch {
+	case app.Nome == "":
+		return "nome deve ser informado."
+	case app.Scopo <= 0 || utils.EscopoBits(utils.Escopo(app.Scopo)) != app.Scopo:
+		return "scopo deve conter apenas bits de escopo conhecidos."
+	case app.JWTExpiracao <= 0:
+		return "jwt_expiracao deve ser maior que zero."
+	case app.RefreshExpiracao < 0:
+		return "refresh_expiracao não pode ser negativo."
+	case app.Status != 0 && app.Status != 1:
+		return "status deve ser 0 (desabilitada) ou 1 (habilitada)."
+	case len(app.IPPermitidos) > 2000:
+		return "ip_permitidos deve ter até 2000 caracteres."
+	case len(app.Certificado) > 500:
+		return "certificado deve ter até 500 caracteres."
+	}
+	if _, err := auth.ParseIPAllowlist(app.IPPermitidos); err != nil {
+		return "ip_permitidos inválido: " + err.Error() + "."
+	}
+	if _, err := auth.NormalizeCertificateMapping(app.Certificado); err != nil {
+		return "certificado inválido: " + err.Error() + "."
+	}
+	return ""
+}
+
+// newHashedSecret gera um client_secret e seu hash argon2id
+func newHashedSecret() (string, string, error) {
+	secret, err := auth.NewClientSecret()
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := auth.HashSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hash, nil
+}
+
+// applicationInfo converte o registro de WSAPLICACOES para a resposta da API
+func applicationInfo(app database.ApplicationData) models.ApplicationInfo {
+	return models.ApplicationInfo{
+		ClientID:         app.ClientID,
+		Nome:             app.Nome,
+		Scopo:            app.Scopo,
+		Escopo:           utils.Escopo(app.Scopo),
+		JWTExpiracao:     app.JWTExpiracao,
+		RefreshExpiracao: app.RefreshExpiracao,
+		Status:           app.Status,
+		IPPermitidos:     app.IPPermitidos,
+		Certificado:      app.Certificado,
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/middleware"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// clientCredentials obtém client_id e client_secret do header Authorization (Basic)
+// ou, na ausência dele, dos campos client_id/client_secret do formulário
+func clientCredentials(c *gin.Context, logger *zap.SugaredLogger) (string, string, models.TokenResponse, int) {
+	authorization := utils.EliminaCaracterNulo(c.GetHeader("Authorization"))
+	if strings.HasPrefix(authorization, "Basic ") {
+		return decodeBasicAuth(logger, authorization)
+	}
+
+	clientID := utils.EliminaCaracterNulo(c.PostForm("client_id"))
+	clientSecret := utils.EliminaCaracterNulo(c.PostForm("client_secret"))
+	if clientID == "" || clientSecret == "" {
+		return "", "", models.TokenResponse{
+			Code:    "002",
+			Message: "Client_id e Client_secret devem ser informados.",
+		}, 401
+	}
+
+	return clientID, clientSecret, models.TokenResponse{}, 0
+}
+
+// decodeBasicAuth extrai client_id e client_secret do header Authorization (Basic)
+// Retorna codError 0 em caso de sucesso; caso contrário, a resposta de erro (código 002)
+func decodeBasicAuth(logger *zap.SugaredLogger, authorization string) (string, string, models.TokenResponse, int) {
+	authDecoded, err := base64.StdEncoding.DecodeString(authorization[6:])
+	if err != nil {
+		logger.Errorw("Erro ao decodificar Authorization", "error", err)
+		return "", "", models.TokenResponse{
+			Code:    "002",
+			Message: "Erro ao decodificar credenciais.",
+		}, 401
+	}
+
+	authStr := utils.EliminaCaracterNulo(string(authDecoded))
+	parts := strings.SplitN(authStr, ":", 2)
+
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", models.TokenResponse{
+			Code:    "002",
+			Message: "Conteúdo do nome Client_id ou Client_secret inválido.",
+		}, 401
+	}
+
+	return parts[0], parts[1], models.TokenResponse{}, 0
+}
+
+// lookupApplication consulta a aplicação ativa em WSAPLICACOES (substituível nos testes)
+var lookupApplication = getApplicationByClientID
+
+// authenticateClient valida as credenciais do client em WSAPLICACOES e o IP de origem
+// Retorna a aplicação ou a resposta de erro (códigos 003 a 006 e 018) com o status HTTP correspondente
+func authenticateClient(db *database.Database, logger *zap.SugaredLogger, clientID, clientSecret, clientIP string) (*models.Application, models.TokenResponse, int) {
+	// Buscar aplicação no banco de dados (client_id desconhecido ou desabilitado não retorna linha: 409/004;
+	// até a versão 3.0.0.6 o client_id desconhecido retornava 403/003, reservado agora para falhas do banco)
+	app, err := lookupApplication(db, clientID)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Errorw("Erro ao buscar aplicação", "error", err)
+		return nil, models.TokenResponse{
+			Code:    "003",
+			Message: "Falha ao verificar aplicação.",
+		}, 403
+	}
+	if app == nil {
+		return nil, models.TokenResponse{
+			Code:    "004",
+			Message: "Falha na Validação da Aplicação. Client_Id Inválido ou Desabilitado.",
+		}, 409
+	}
+	if app.Status != 1 {
+		return nil, models.TokenResponse{
+			Code:    "006",
+			Message: "Aplicação Desabilitada.",
+		}, 409
+	}
+	// Hash argon2id/bcrypt ou texto plano legado (comparação em tempo constante)
+	ok, legacy := auth.VerifySecret(app.ClientSecret, clientSecret)
+	if !ok {
+		return nil, models.TokenResponse{
+			Code:    "005",
+			Message: "Client_secret Inválido.",
+		}, 409
+	}
+	if legacy {
+		// Acompanhamento da migração: execute "wsicrmrest rehash-secrets" para converter
+		logger.Warnw("Client_secret armazenado em texto plano utilizado", "client_id", clientID)
+	}
+
+	if errResponse, errCode := checkIPAllowlist(logger, app, clientIP); errCode != 0 {
+		return nil, errResponse, errCode
+	}
+
+	return app, models.TokenResponse{}, 0
+}
+
+// certificateClient retorna a aplicação mapeada ao certificado de cliente (mTLS) quando a
+// requisição não traz credenciais (Basic ou client_secret); caso contrário, nil
+func certificateClient(c *gin.Context) *database.ApplicationData {
+	if c.GetHeader("Authorization") != "" || c.PostForm("client_secret") != "" {
+		return nil
+	}
+	return middleware.CertApplication(c)
+}
+
+// authenticateCertificate autentica a aplicação mapeada ao certificado de cliente (mTLS) sem client_secret
+// Retorna a aplicação ou a resposta de erro (códigos 003, 004 e 018) com o status HTTP correspondente
+func authenticateCertificate(db *database.Database, logger *zap.SugaredLogger, certApp *database.ApplicationData, clientIP string) (*models.Application, models.TokenResponse, int) {
+	app, err := lookupApplication(db, certApp.ClientID)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Errorw("Erro ao buscar aplicação", "error", err)
+		return nil, models.TokenResponse{
+			Code:    "003",
+			Message: "Falha ao verificar aplicação.",
+		}, 403
+	}
+	if app == nil {
+		return nil, models.TokenResponse{
+			Code:    "004",
+			Message: "Falha na Validação da Aplicação. Client_Id Inválido ou Desabilitado.",
+		}, 409
+	}
+
+	if errResponse, errCode := checkIPAllowlist(logger, app, clientIP); errCode != 0 {
+		return nil, errResponse, errCode
+	}
+
+	return app, models.TokenResponse{}, 0
+}
+
+// checkCertificateBinding impede que credenciais de outra aplicação sejam usadas
+// junto com um certificado de cliente mapeado (código 021, HTTP 403)
+func checkCertificateBinding(c *gin.Context, logger *zap.SugaredLogger, clientID string) (models.TokenResponse, int) {
+	certApp := middleware.CertApplication(c)
+	if certApp == nil || certApp.ClientID == clientID {
+		return models.TokenResponse{}, 0
+	}
+
+	logger.Warnw("Credenciais não correspondem ao certificado de cliente",
+		"client_id", clientID,
+		"cert_client_id", certApp.ClientID)
+
+	return models.TokenResponse{
+		Code:    "021",
+		Message: "Certificado de cliente não corresponde à aplicação informada.",
+	}, 403
+}
+
+// checkIPAllowlist verifica o IP de origem contra WSAPLIPPERMITIDOS da aplicação
+// Retorna a resposta de erro (código 018, HTTP 403) quando o IP não é permitido
+func checkIPAllowlist(logger *zap.SugaredLogger, app *models.Application, clientIP string) (models.TokenResponse, int) {
+	allowed, err := auth.CheckIPAllowlist(app.IPPermitidos, clientIP)
+	if err != nil {
+		logger.Errorw("WSAPLIPPERMITIDOS inválido - acesso bloqueado", "client_id", app.ClientID, "error", err)
+	}
+	if allowed {
+		return models.TokenResponse{}, 0
+	}
+
+	logger.Warnw("IP de origem não permitido para a aplicação",
+		"client_id", app.ClientID,
+		"client_ip", clientIP)
+
+	return models.TokenResponse{
+		Code:    "018",
+		Message: "IP de origem não permitido para a aplicação.",
+	}, 403
+}
+
+// recordIPViolation alimenta o Fail2Ban quando a resposta é uma violação da lista de IPs permitidos
+func recordIPViolation(c *gin.Context, response models.TokenResponse, codError int) {
+	if response.Code == "018" {
+		middleware.RecordFail2BanAttempt(c, codError)
+	}
+}
@@ -6,7 +6,14 @@
 package handlers
 
 import (
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
 	"wsicrmrest/internal/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +32,22 @@ type Fail2BanUnbanRequest struct {
 	IP string `json:"ip" binding:"required"`
 }
 
+// Fail2BanBanRequest requisição para banir IP manualmente
+type Fail2BanBanRequest struct {
+	IP             string `json:"ip" binding:"required"`
+	Motivo         string `json:"motivo" binding:"required"` // Motivo do banimento (registrado na auditoria)
+	DuracaoMinutos int    `json:"duracao_minutos"`           // Duração em minutos (0 = duração padrão do Fail2Ban)
+}
+
+// fail2banMaxBanMinutes duração máxima de um banimento manual (30 dias)
+const fail2banMaxBanMinutes = 30 * 24 * 60
+
+// fail2banUnavailable resposta quando o Fail2Ban não está inicializado
+var fail2banUnavailable = gin.H{
+	"code":    "503",
+	"message": "Fail2Ban não está inicializado",
+}
+
 // Fail2BanGetStatus godoc
 // @Summary Lista IPs banidos pelo Fail2Ban
 // @Description Retorna lista de IPs atualmente banidos e estatísticas detalhadas
@@ -33,17 +56,19 @@ type Fail2BanUnbanRequest struct {
 // @Produce json
 // @Param ip query string false "IP específico para consultar estatísticas"
 // @Success 200 {object} Fail2BanStatusResponse
-// @Failure 500 {object} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse "Token Bearer ausente ou inválido"
+// @Failure 403 {object} models.ErrorResponse "Token sem escopo 'sistema'"
+// @Failure 503 {object} map[string]interface{}
 // @Router /connect/v1/fail2ban/status [get]
-func Fail2BanGetStatus(logger *zap.SugaredLogger) gin.HandlerFunc {
+// @Security BearerAuth
+func Fail2BanGetStatus(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "Fail2BanGetStatus"
 		f2b := middleware.GetFail2Ban()
 
 		if f2b == nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"code":    "503",
-				"message": "Fail2Ban não está inicializado",
-			})
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusServiceUnavailable, fail2banUnavailable)
 			return
 		}
 
@@ -62,70 +87,180 @@ func Fail2BanGetStatus(logger *zap.SugaredLogger) gin.HandlerFunc {
 
 		logger.Infow("Consulta de status Fail2Ban",
 			"admin_ip", c.ClientIP(),
+			"operador", reqCtx.ClientID,
 			"banned_count", len(bannedIPs),
 			"query_ip", queryIP,
 		)
 
-		c.JSON(http.StatusOK, response)
+		respondWithLog(c, cfg, db, reqCtx, "IP: "+queryIP+"\n", nomeProcedure, http.StatusOK, response)
 	}
 }
 
 // Fail2BanUnbanIP godoc
 // @Summary Remove ban de um IP
-// @Description Remove manualmente o banimento de um IP específico
+// @Description Remove manualmente o banimento de um IP específico (registrado em WSADMAUDITORIA)
 // @Tags Fail2Ban
 // @Accept json
 // @Produce json
 // @Param request body Fail2BanUnbanRequest true "IP a ser desbanido"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse "Token Bearer ausente ou inválido"
+// @Failure 403 {object} models.ErrorResponse "Token sem escopo 'sistema'"
 // @Failure 404 {object} map[string]interface{}
 // @Failure 503 {object} map[string]interface{}
 // @Router /connect/v1/fail2ban/unban [post]
-func Fail2BanUnbanIP(logger *zap.SugaredLogger) gin.HandlerFunc {
+// @Security BearerAuth
+func Fail2BanUnbanIP(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "Fail2BanUnbanIP"
 		var req Fail2BanUnbanRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusBadRequest, gin.H{
 				"code":    "400",
 				"message": "Parâmetros inválidos",
 				"error":   err.Error(),
 			})
 			return
 		}
+		reqParametros := "IP: " + req.IP + "\n"
 
 		f2b := middleware.GetFail2Ban()
 
 		if f2b == nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"code":    "503",
-				"message": "Fail2Ban não está inicializado",
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusServiceUnavailable, fail2banUnavailable)
+			return
+		}
+
+		if !f2b.UnbanIP(req.IP) {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusNotFound, gin.H{
+				"code":    "404",
+				"message": "IP não está banido ou não encontrado",
+				"ip":      req.IP,
 			})
 			return
 		}
 
-		if f2b.UnbanIP(req.IP) {
-			logger.Infow("IP desbanido manualmente",
-				"ip", req.IP,
-				"admin_ip", c.ClientIP(),
-			)
+		logger.Infow("IP desbanido manualmente",
+			"ip", req.IP,
+			"admin_ip", c.ClientIP(),
+			"operador", reqCtx.ClientID,
+		)
+
+		auditAdmin(c, db, reqCtx, "FAIL2BAN_DESBANIR", req.IP, nil, http.StatusOK)
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, gin.H{
+			"code":    "200",
+			"message": "IP desbanido com sucesso",
+			"ip":      req.IP,
+		})
+	}
+}
 
-			c.JSON(http.StatusOK, gin.H{
-				"code":    "200",
-				"message": "IP desbanido com sucesso",
+// Fail2BanBanIP godoc
+// @Summary Bane um IP manualmente
+// @Description Bane manualmente um IP pelo período informado, com motivo registrado em WSADMAUDITORIA.
+// @Description duracao_minutos = 0 usa a duração padrão do Fail2Ban (máximo 43200 = 30 dias).
+// @Description IPs da whitelist e o próprio IP do operador não podem ser banidos.
+// @Tags Fail2Ban
+// @Accept json
+// @Produce json
+// @Param request body Fail2BanBanRequest true "IP, motivo e duração do banimento"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse "Token Bearer ausente ou inválido"
+// @Failure 403 {object} models.ErrorResponse "Token sem escopo 'sistema'"
+// @Failure 409 {object} map[string]interface{} "IP na whitelist"
+// @Failure 503 {object} map[string]interface{}
+// @Router /connect/v1/fail2ban/ban [post]
+// @Security BearerAuth
+func Fail2BanBanIP(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "Fail2BanBanIP"
+		var req Fail2BanBanRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusBadRequest, gin.H{
+				"code":    "400",
+				"message": "Parâmetros inválidos",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		req.IP = strings.TrimSpace(req.IP)
+		req.Motivo = strings.TrimSpace(req.Motivo)
+		reqParametros := "IP: " + req.IP + "\nMotivo: " + req.Motivo + "\nDuracao_minutos: " + strconv.Itoa(req.DuracaoMinutos) + "\n"
+
+		if message := validateBanRequest(c, req); message != "" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, gin.H{
+				"code":    "400",
+				"message": message,
 				"ip":      req.IP,
 			})
-		} else {
-			c.JSON(http.StatusNotFound, gin.H{
-				"code":    "404",
-				"message": "IP não está banido ou não encontrado",
+			return
+		}
+
+		f2b := middleware.GetFail2Ban()
+
+		if f2b == nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusServiceUnavailable, fail2banUnavailable)
+			return
+		}
+
+		expiry, ok := f2b.BanIP(req.IP, time.Duration(req.DuracaoMinutos)*time.Minute, req.Motivo)
+		if !ok {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusConflict, gin.H{
+				"code":    "409",
+				"message": "IP está na whitelist do Fail2Ban e não pode ser banido",
 				"ip":      req.IP,
 			})
+			return
 		}
+
+		logger.Warnw("IP banido manualmente",
+			"ip", req.IP,
+			"motivo", req.Motivo,
+			"ban_expiry", expiry,
+			"admin_ip", c.ClientIP(),
+			"operador", reqCtx.ClientID,
+		)
+
+		auditAdmin(c, db, reqCtx, "FAIL2BAN_BANIR", req.IP, map[string]interface{}{
+			"motivo":          req.Motivo,
+			"duracao_minutos": req.DuracaoMinutos,
+			"ban_expiry":      expiry,
+		}, http.StatusOK)
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, gin.H{
+			"code":       "200",
+			"message":    "IP banido com sucesso",
+			"ip":         req.IP,
+			"ban_expiry": expiry,
+		})
 	}
 }
 
+// validateBanRequest retorna a mensagem de erro de validação do banimento manual ("" se válido)
+func validateBanRequest(c *gin.Context, req Fail2BanBanRequest) string {
+	switch {
+	case net.ParseIP(req.IP) == nil:
+		return "IP inválido"
+	case req.IP == c.ClientIP():
+		return "Não é permitido banir o próprio IP"
+	case req.Motivo == "":
+		return "Motivo deve ser informado"
+	case len(req.Motivo) > 500:
+		return "Motivo deve ter até 500 caracteres"
+	case req.DuracaoMinutos < 0 || req.DuracaoMinutos > fail2banMaxBanMinutes:
+		return "duracao_minutos deve estar entre 0 e " + strconv.Itoa(fail2banMaxBanMinutes)
+	}
+	return ""
+}
+
 // Fail2BanGetIPStats godoc
 // @Summary Estatísticas de um IP específico
 // @Description Retorna estatísticas detalhadas de tentativas e banimentos de um IP
@@ -135,14 +270,21 @@ func Fail2BanUnbanIP(logger *zap.SugaredLogger) gin.HandlerFunc {
 // @Param ip path string true "Endereço IP"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse "Token Bearer ausente ou inválido"
+// @Failure 403 {object} models.ErrorResponse "Token sem escopo 'sistema'"
 // @Failure 404 {object} map[string]interface{}
 // @Failure 503 {object} map[string]interface{}
 // @Router /connect/v1/fail2ban/ip/{ip} [get]
-func Fail2BanGetIPStats(logger *zap.SugaredLogger) gin.HandlerFunc {
+// @Security BearerAuth
+func Fail2BanGetIPStats(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "Fail2BanGetIPStats"
 		ip := c.Param("ip")
+		reqParametros := "IP: " + ip + "\n"
+
 		if ip == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, gin.H{
 				"code":    "400",
 				"message": "IP não fornecido",
 			})
@@ -152,17 +294,14 @@ func Fail2BanGetIPStats(logger *zap.SugaredLogger) gin.HandlerFunc {
 		f2b := middleware.GetFail2Ban()
 
 		if f2b == nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"code":    "503",
-				"message": "Fail2Ban não está inicializado",
-			})
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusServiceUnavailable, fail2banUnavailable)
 			return
 		}
 
 		stats := f2b.GetIPStats(ip)
 
 		if tracked, ok := stats["tracked"].(bool); ok && !tracked {
-			c.JSON(http.StatusNotFound, gin.H{
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusNotFound, gin.H{
 				"code":    "404",
 				"message": "IP não está sendo rastreado",
 				"ip":      ip,
@@ -173,8 +312,9 @@ func Fail2BanGetIPStats(logger *zap.SugaredLogger) gin.HandlerFunc {
 		logger.Infow("Consulta de estatísticas de IP",
 			"ip", ip,
 			"admin_ip", c.ClientIP(),
+			"operador", reqCtx.ClientID,
 		)
 
-		c.JSON(http.StatusOK, stats)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, stats)
 	}
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithLog grava o log da requisição em WSREQUISICOES e envia a resposta JSON
+func respondWithLog(c *gin.Context, cfg *config.Config, db *database.Database, reqCtx *reqcontext.RequestContext, reqParametros string, nomeProcedure string, codError int, response interface{}) {
+	respondWithLogAs(c, cfg, db, reqCtx, reqParametros, nomeProcedure, codError, response, response)
+}
+
+// respondWithLogAs envia response, mas grava logged em WSREQUISICOES
+// Usado quando a resposta contém dados que não podem ser registrados (ex: client_secret)
+func respondWithLogAs(c *gin.Context, cfg *config.Config, db *database.Database, reqCtx *reqcontext.RequestContext, reqParametros string, nomeProcedure string, codError int, logged interface{}, response interface{}) {
+	respJSON, _ := json.Marshal(logged)
+
+	go db.GravaLogDB(
+		reqCtx.UUID,
+		c.Request.Method,
+		c.Request.URL.Path,
+		middleware.HeadersAsString(c),
+		reqParametros,
+		codError,
+		string(respJSON),
+		nomeProcedure,
+		reqCtx.ClientID,
+		reqCtx.NomeAplicacao,
+		reqCtx.StartTime,
+		c.ClientIP(),
+		cfg.Application.WSGravaLogDB,
+		cfg.Application.WSDetalheLogAPI,
+		reqCtx.DetalheLogAPI,
+		config.Version,
+	)
+
+	c.JSON(codError, response)
+}
+
+// maskToken retorna apenas o início do token para registro em log
+func maskToken(token string) string {
+	if len(token) <= 16 {
+		return "***"
+	}
+	return token[:16] + "..."
+}
+
+// auditAdmin registra uma ação administrativa em WSADMAUDITORIA
+// O operador é o client_id do token Bearer que executou a ação
+func auditAdmin(c *gin.Context, db *database.Database, reqCtx *reqcontext.RequestContext, acao, alvo string, detalhes interface{}, resultado int) {
+	detalhesJSON := ""
+	if detalhes != nil {
+		if b, err := json.Marshal(detalhes); err == nil {
+			detalhesJSON = string(b)
+		}
+	}
+
+	db.InsertAuditoria(database.AuditEntry{
+		UUID:      reqCtx.UUID,
+		Operador:  reqCtx.ClientID,
+		Acao:      acao,
+		Alvo:      alvo,
+		Detalhes:  detalhesJSON,
+		IP:        c.ClientIP(),
+		Resultado: resultado,
+	})
+}
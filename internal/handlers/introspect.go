@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IntrospectToken godoc
+// @Summary Token introspection (RFC 7662)
+// @Description Returns whether a token is active and, if so, its client_id, scope, exp, iat and aplicacao.
+// @Description The caller authenticates with its own client credentials (Basic Auth or client_id/client_secret form fields)
+// @Description or with a TLS client certificate mapped to its application (mTLS).
+// @Description A token is active when its signature and claims are valid, it was issued by this API (WSAPLLOGTOKEN)
+// @Description and it has not been revoked. Inactive tokens return only `{"active": false}`.
+// @Description
+// @Description **Example:**
+// @Description ```bash
+// @Description curl -X POST "https://api.example.com/connect/v1/introspect" \
+// @Description   -H "Authorization: Basic $(echo -n 'client_id:client_secret' | base64)" \
+// @Description   -d "token=eyJ0eXAiOiJKV1Qi..."
+// @Description ```
+// @Tags Authentication
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param Authorization header string false "Basic Auth (Base64 encoded client_id:client_secret)"
+// @Param token formData string true "Token to introspect"
+// @Param client_id formData string false "Caller client_id (when Basic Auth is not used)"
+// @Param client_secret formData string false "Caller client_secret (when Basic Auth is not used)"
+// @Success 200 {object} models.IntrospectionResponse "Introspection result"
+// @Failure 400 {object} models.TokenResponse "Token parameter missing"
+// @Failure 401 {object} models.TokenResponse "Invalid caller credentials"
+// @Failure 403 {object} models.TokenResponse "Database error"
+// @Router /connect/v1/introspect [post]
+// @Security BasicAuth
+func IntrospectToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "IntrospectToken"
+
+		// Autenticação do chamador com suas próprias credenciais ou certificado de cliente (mTLS)
+		certApp := certificateClient(c)
+		var clientID, clientSecret string
+		var errResponse models.TokenResponse
+		var errCode int
+		if certApp != nil {
+			clientID = certApp.ClientID
+		} else if clientID, clientSecret, errResponse, errCode = clientCredentials(c, logger); errCode != 0 {
+			c.Header("WWW-Authenticate", `Basic realm="wsicrmrest"`)
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, errCode, errResponse)
+			return
+		}
+
+		token := strings.TrimSpace(utils.EliminaCaracterNulo(c.PostForm("token")))
+		reqParametros := "Client_Id: " + clientID + "\nToken: " + maskToken(token) + "\n"
+
+		var app *models.Application
+		if certApp != nil {
+			app, errResponse, errCode = authenticateCertificate(db, logger, certApp, c.ClientIP())
+		} else if errResponse, errCode = checkCertificateBinding(c, logger, clientID); errCode == 0 {
+			app, errResponse, errCode = authenticateClient(db, logger, clientID, clientSecret, c.ClientIP())
+		}
+		if errCode != 0 {
+			recordIPViolation(c, errResponse, errCode)
+
+			// RFC 7662: falha na autenticação do chamador retorna 401
+			if errCode != http.StatusForbidden {
+				errCode = http.StatusUnauthorized
+				c.Header("WWW-Authenticate", `Basic realm="wsicrmrest"`)
+			}
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, errCode, errResponse)
+			return
+		}
+		reqCtx.SetClientInfo(clientID, app.Nome)
+
+		if token == "" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.TokenResponse{
+				Code:    "001",
+				Message: "O parâmetro token deve ser informado.",
+			})
+			return
+		}
+
+		response, err := introspect(cfg, db, token)
+		if err != nil {
+			logger.Errorw("Erro ao consultar token em WSAPLLOGTOKEN", "error", err)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusForbidden, models.TokenResponse{
+				Code:    "007",
+				Message: "Falha ao consultar token.",
+			})
+			return
+		}
+
+		logger.Infow("Introspecção de token",
+			"client_id", clientID,
+			"token_client_id", response.ClientID,
+			"active", response.Active)
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, response)
+	}
+}
+
+// introspect valida assinatura e claims, a emissão em WSAPLLOGTOKEN e a revogação do token
+func introspect(cfg *config.Config, db *database.Database, token string) (models.IntrospectionResponse, error) {
+	inactive := models.IntrospectionResponse{Active: false}
+
+	claims, err := auth.ParseToken(cfg, token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	if cache := auth.GetRevocationCache(); cache != nil && cache.IsRevoked(token) {
+		return inactive, nil
+	}
+
+	tokenLog, err := db.GetTokenLog(token)
+	if err != nil {
+		return inactive, err
+	}
+	if tokenLog == nil || tokenLog.Revogado || tokenLog.ClientID != claims.ClientID {
+		return inactive, nil
+	}
+
+	return models.IntrospectionResponse{
+		Active:    true,
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+		TokenType: "Bearer",
+		Exp:       claims.Exp,
+		Iat:       claims.Nbf,
+		Iss:       claims.Iss,
+		Aplicacao: claims.Aplicacao,
+	}, nil
+}
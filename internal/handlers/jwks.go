@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"wsicrmrest/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary Public signing keys (JWKS)
+// @Description Returns the public keys used to sign RS256/ES256 tokens, including keys being rotated out
+// @Description Consumers should select the key by the "kid" header of the token
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} auth.JWKS "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func JWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks := auth.JWKS{Keys: []auth.JWK{}}
+		if ks := auth.GetKeyStore(); ks != nil {
+			jwks = ks.JWKS()
+		}
+
+		// Chaves públicas podem ser armazenadas em cache pelos consumidores
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	}
+}
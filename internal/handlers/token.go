@@ -1,16 +1,15 @@
 package handlers
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
+	"database/sql"
 	"encoding/json"
 	"strings"
 	"time"
+	"wsicrmrest/internal/auth"
 	"wsicrmrest/internal/config"
 	reqcontext "wsicrmrest/internal/context"
 	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/middleware"
 	"wsicrmrest/internal/models"
 	"wsicrmrest/internal/utils"
 
@@ -34,6 +33,15 @@ import (
 // @Description - X-RateLimit-Remaining-Minute: Remaining requests this minute
 // @Description - X-RateLimit-Remaining-Hour: Remaining requests this hour
 // @Description
+// @Description **Refresh token:**
+// @Description Applications with WSAPLREFRESHEXPIRACAO > 0 also receive a single-use refresh_token.
+// @Description Send `Grant_type: refresh_token` and `Refresh_token: <token>` (no Basic credentials) to obtain a new
+// @Description access token and a rotated refresh token.
+// @Description
+// @Description **Client certificate (mTLS):**
+// @Description When the TLS client certificate is mapped to an application (WSAPLCERTIFICADO), send only
+// @Description `Grant_type: client_credentials` without Authorization; the certificate replaces the client_secret.
+// @Description
 // @Description **Example:**
 // @Description ```bash
 // @Description curl -X GET "https://api.example.com/connect/v1/token" \
@@ -43,24 +51,25 @@ import (
 // @Tags Authentication
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Basic Auth (Base64 encoded client_id:client_secret)"
-// @Param Grant_type header string true "Must be 'client_credentials'"
+// @Param Authorization header string false "Basic Auth (Base64 encoded client_id:client_secret) - required for client_credentials"
+// @Param Grant_type header string true "'client_credentials' or 'refresh_token'"
+// @Param Refresh_token header string false "Refresh token (required for Grant_type refresh_token)"
 // @Success 200 {object} models.TokenResponse "Token generated successfully"
 // @Failure 401 {object} models.TokenResponse "Invalid credentials or grant type"
-// @Failure 403 {object} models.TokenResponse "Database connection error"
-// @Failure 409 {object} models.TokenResponse "Invalid client_id or disabled application"
+// @Failure 403 {object} models.TokenResponse "Database error while checking the client (003), IP not allowed or client certificate mismatch"
+// @Failure 409 {object} models.TokenResponse "Unknown or disabled client_id (004; returned 403/003 up to 3.0.0.6), disabled application (006) or invalid client_secret (005)"
 // @Failure 429 {object} object "Rate limit exceeded"
-// @Failure 500 {object} models.TokenResponse "Error generating token"
+// @Failure 500 {object} models.TokenResponse "Error generating token or database error while checking the refresh token application (003)"
 // @Router /connect/v1/token [get]
 // @Security BasicAuth
 func GenerateToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Criar contexto da requisição
-		reqCtx := reqcontext.NewRequestContext()
+		reqCtx := reqcontext.FromGin(c)
 
 		reqMetodo := "GET"
 		reqEndPoint := "/connect/v1/token"
-		reqHeader := getHeadersAsString(c)
+		reqHeader := middleware.HeadersAsString(c)
 		reqParametros := ""
 		var reqResposta string
 		var codError int
@@ -69,6 +78,7 @@ func GenerateToken(cfg *config.Config, db *database.Database, logger *zap.Sugare
 		// Ler headers
 		authorization := c.GetHeader("Authorization")
 		grantType := c.GetHeader("Grant_type")
+		refreshToken := utils.EliminaCaracterNulo(c.GetHeader("Refresh_token"))
 		host := c.GetHeader("Host")
 
 		logger.Infow("Gerando Token",
@@ -81,9 +91,30 @@ func GenerateToken(cfg *config.Config, db *database.Database, logger *zap.Sugare
 		var response models.TokenResponse
 
 		// Validar Authorization e Grant_type
-		if !strings.HasPrefix(authorization, "Basic ") || grantType != "client_credentials" {
+		certApp := middleware.CertApplication(c)
+		if grantType == "refresh_token" {
+			reqParametros = "Grant_type: refresh_token\nRefresh_token: " + maskToken(refreshToken) + "\n"
+			response, codError = exchangeRefreshToken(cfg, db, logger, reqCtx, refreshToken, host, c.ClientIP())
+		} else if certApp != nil && authorization == "" && grantType == "client_credentials" {
+			// Certificado de cliente (mTLS) mapeado a uma aplicação: dispensa client_secret
+			reqParametros = "Client_Id: " + certApp.ClientID + "\nCertificado: " + certApp.Certificado + "\n"
+
+			logger.Infow("Gerando Token - Certificado de cliente", "client_id", certApp.ClientID)
+
+			if app, errResponse, errCode := authenticateCertificate(db, logger, certApp, c.ClientIP()); errCode != 0 {
+				response = errResponse
+				codError = errCode
+			} else {
+				response, codError = issueClientToken(cfg, db, logger, reqCtx, app, host)
+			}
+		} else if !strings.HasPrefix(authorization, "Basic ") || grantType != "client_credentials" {
+			// Apenas o esquema do Authorization é registrado: o restante pode conter o client_secret
+			scheme, _, _ := strings.Cut(authorization, " ")
+			if scheme == authorization {
+				scheme = ""
+			}
 			logger.Warnw("Credenciais inválidas",
-				"authorization", authorization,
+				"authorization_scheme", scheme,
 				"grant_type", grantType)
 
 			response = models.TokenResponse{
@@ -91,100 +122,28 @@ func GenerateToken(cfg *config.Config, db *database.Database, logger *zap.Sugare
 				Message: "As chaves Authorization e Grant_type devem ser informados corretamente.",
 			}
 			codError = 401
+		} else if clientID, clientSecret, errResponse, errCode := decodeBasicAuth(logger, authorization); errCode != 0 {
+			response = errResponse
+			codError = errCode
 		} else {
-			// Decodificar Basic Auth
-			authEncoded := authorization[6:]
-			authDecoded, err := base64.StdEncoding.DecodeString(authEncoded)
-			if err != nil {
-				logger.Errorw("Erro ao decodificar Authorization", "error", err)
-				response = models.TokenResponse{
-					Code:    "002",
-					Message: "Erro ao decodificar credenciais.",
-				}
-				codError = 401
+			reqParametros = "Client_Id: " + clientID + "\n"
+
+			logger.Infow("Gerando Token - Client_id", "client_id", clientID)
+
+			if errResponse, errCode := checkCertificateBinding(c, logger, clientID); errCode != 0 {
+				response = errResponse
+				codError = errCode
+			} else if app, errResponse, errCode := authenticateClient(db, logger, clientID, clientSecret, c.ClientIP()); errCode != 0 {
+				response = errResponse
+				codError = errCode
 			} else {
-				authStr := utils.EliminaCaracterNulo(string(authDecoded))
-				parts := strings.SplitN(authStr, ":", 2)
-
-				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-					response = models.TokenResponse{
-						Code:    "002",
-						Message: "Conteúdo do nome Client_id ou Client_secret inválido.",
-					}
-					codError = 401
-				} else {
-					clientID := parts[0]
-					clientSecret := parts[1]
-
-					reqParametros = "Client_Id: " + clientID + "\nClient_Secret: " + clientSecret + "\n"
-
-					logger.Infow("Gerando Token - Client_id", "client_id", clientID)
-
-					// Buscar aplicação no banco de dados
-					app, err := getApplicationByClientID(db, clientID)
-					if err != nil {
-						logger.Errorw("Erro ao buscar aplicação", "error", err)
-						response = models.TokenResponse{
-							Code:    "003",
-							Message: "Falha ao verificar aplicação.",
-						}
-						codError = 403
-					} else if app == nil {
-						response = models.TokenResponse{
-							Code:    "004",
-							Message: "Falha na Validação da Aplicação. Client_Id Inválido ou Desabilitado.",
-						}
-						codError = 409
-					} else if app.Status != 1 {
-						response = models.TokenResponse{
-							Code:    "006",
-							Message: "Aplicação Desabilitada.",
-						}
-						codError = 409
-					} else if !constantTimeCompare(app.ClientSecret, clientSecret) {
-						// Usar comparação de tempo constante para prevenir timing attacks
-						response = models.TokenResponse{
-							Code:    "005",
-							Message: "Client_secret Inválido.",
-						}
-						codError = 409
-					} else {
-						// Gerar token JWT
-						token, expiration, nbf, err := generateJWT(cfg, app)
-						if err != nil {
-							logger.Errorw("Erro ao gerar token", "error", err)
-							response = models.TokenResponse{
-								Code:    "008",
-								Message: "Erro ao gerar token JWT.",
-							}
-							codError = 500
-						} else {
-							scope := utils.Escopo(app.Scopo)
-
-							// Definir informações do cliente no contexto
-							reqCtx.SetClientInfo(clientID, app.Nome)
-
-							response = models.TokenResponse{
-								Code:        "000",
-								AccessToken: token,
-								TokenType:   "Bearer",
-								ExpiresIn:   expiration,
-								DateTime:    nbf,
-								Scope:       scope,
-								Modulos:     cfg.Organization.RegModulos,
-							}
-							codError = 200
-
-							// Gravar log do token no banco
-							if err := logTokenToDB(db, clientID, token, host, nbf, expiration); err != nil {
-								logger.Errorw("Erro ao gravar log do token", "error", err)
-							}
-						}
-					}
-				}
+				response, codError = issueClientToken(cfg, db, logger, reqCtx, app, host)
 			}
 		}
 
+		// Violação da lista de IPs permitidos alimenta o Fail2Ban
+		recordIPViolation(c, response, codError)
+
 		duration := reqCtx.GetDuration()
 		logger.Infow("Token - Finalizado",
 			"duration", duration,
@@ -219,19 +178,69 @@ func GenerateToken(cfg *config.Config, db *database.Database, logger *zap.Sugare
 	}
 }
 
+// issueClientToken gera o token JWT (e o refresh token opcional) de uma aplicação autenticada
+func issueClientToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, app *models.Application, host string) (models.TokenResponse, int) {
+	// Gerar token JWT
+	token, expiration, nbf, err := generateJWT(cfg, app)
+	if err != nil {
+		logger.Errorw("Erro ao gerar token", "error", err)
+		return models.TokenResponse{
+			Code:    "008",
+			Message: "Erro ao gerar token JWT.",
+		}, 500
+	}
+
+	scope := utils.Escopo(app.Scopo)
+
+	// Definir informações do cliente no contexto
+	reqCtx.SetClientInfo(app.ClientID, app.Nome)
+
+	response := models.TokenResponse{
+		Code:        "000",
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiration,
+		DateTime:    nbf,
+		Scope:       scope,
+		Modulos:     cfg.Organization.RegModulos,
+	}
+
+	// Gravar log do token no banco
+	if err := logTokenToDB(db, app.ClientID, token, host, nbf, expiration); err != nil {
+		logger.Errorw("Erro ao gravar log do token", "error", err)
+	}
+
+	// Refresh token opcional (configurado por aplicação)
+	if app.RefreshExpiracao > 0 {
+		refresh, refreshExp, err := issueRefreshToken(cfg, db, app, "")
+		if err != nil {
+			logger.Errorw("Erro ao gerar refresh token", "error", err)
+		} else {
+			response.RefreshToken = refresh
+			response.RefreshExpiresIn = refreshExp
+		}
+	}
+
+	return response, 200
+}
+
 // getApplicationByClientID busca uma aplicação pelo client_id
 func getApplicationByClientID(db *database.Database, clientID string) (*models.Application, error) {
-	query := `SELECT WSAPLCLIENTSECRET, WSAPLIJWTEXPIRACAO, WSAPLSCOPO, WSAPLSTATUS, WSAPLNOME
+	query := `SELECT WSAPLCLIENTSECRET, WSAPLIJWTEXPIRACAO, WSAPLSCOPO, WSAPLSTATUS, WSAPLNOME,
+	                 NVL(WSAPLREFRESHEXPIRACAO, 0), WSAPLIPPERMITIDOS
 	          FROM WSAPLICACOES
 	          WHERE WSAPLSTATUS = 1 AND WSAPLCLIENTID = :1`
 
 	var app models.Application
+	var ipPermitidos sql.NullString
 	err := db.QueryRow(query, clientID).Scan(
 		&app.ClientSecret,
 		&app.JWTExpiracao,
 		&app.Scopo,
 		&app.Status,
 		&app.Nome,
+		&app.RefreshExpiracao,
+		&ipPermitidos,
 	)
 
 	if err != nil {
@@ -239,21 +248,12 @@ func getApplicationByClientID(db *database.Database, clientID string) (*models.A
 	}
 
 	app.ClientID = clientID
+	app.IPPermitidos = ipPermitidos.String
 	return &app, nil
 }
 
 // generateJWT gera um token JWT
 func generateJWT(cfg *config.Config, app *models.Application) (string, int64, int64, error) {
-	// Header
-	header := map[string]string{
-		"typ": "JWT",
-		"alg": "HS256",
-	}
-	headerJSON, _ := json.Marshal(header)
-	headerB64 := base64.StdEncoding.EncodeToString(headerJSON)
-	headerB64 = utils.StringChange(headerB64, "\n", "")
-	headerB64 = utils.StringChange(headerB64, "\r", "")
-
 	// Calcular nbf (not before) e exp (expiration)
 	now := time.Now()
 	nbf := utils.CalcTimeStampUnix(now, cfg.JWT.Timezone)
@@ -275,25 +275,12 @@ func generateJWT(cfg *config.Config, app *models.Application) (string, int64, in
 		"scope":     scope,
 		"aplicacao": app.Nome,
 	}
-	payloadJSON, _ := json.Marshal(payload)
-	payloadB64 := base64.StdEncoding.EncodeToString(payloadJSON)
-	payloadB64 = utils.StringChange(payloadB64, "\n", "")
-	payloadB64 = utils.StringChange(payloadB64, "\r", "")
-	payloadB64 = utils.StringChange(payloadB64, "=", "")
-
-	// Criar token sem assinatura
-	tokenUnsigned := headerB64 + "." + payloadB64
-
-	// Gerar assinatura HMAC-SHA256
-	h := hmac.New(sha256.New, []byte(cfg.JWT.SecretKey))
-	h.Write([]byte(tokenUnsigned))
-	signature := h.Sum(nil)
-	signatureB64 := base64.StdEncoding.EncodeToString(signature)
-	signatureB64 = utils.StringChange(signatureB64, "+", "-")
-	signatureB64 = utils.StringChange(signatureB64, "=", "")
-
-	// Token completo
-	token := headerB64 + "." + payloadB64 + "." + signatureB64
+
+	// Assinatura conforme [jwt] signing_algorithm (HS256 legado, RS256 ou ES256)
+	token, err := auth.SignToken(cfg, payload)
+	if err != nil {
+		return "", 0, 0, err
+	}
 
 	return token, exp, nbf, nil
 }
@@ -310,19 +297,3 @@ func logTokenToDB(db *database.Database, clientID, token, host string, nbf, exp
 	_, err := db.Exec(query, dataGeracao, dataExpiracao, clientID, token, host)
 	return err
 }
-
-// constantTimeCompare compara duas strings em tempo constante para prevenir timing attacks
-func constantTimeCompare(a, b string) bool {
-	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
-}
-
-// getHeadersAsString retorna todos os headers como string
-func getHeadersAsString(c *gin.Context) string {
-	var headers strings.Builder
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			headers.WriteString(key + ": " + value + "\n")
-		}
-	}
-	return headers.String()
-}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OAuthToken godoc
+// @Summary Generate JWT token (OAuth2 client_credentials)
+// @Description Standards-compliant token endpoint (RFC 6749, section 4.4) for off-the-shelf OAuth2 libraries.
+// @Description Accepts `application/x-www-form-urlencoded` with `grant_type=client_credentials` and the client
+// @Description credentials via Basic Auth or the `client_id`/`client_secret` form fields.
+// @Description With mTLS, a client certificate mapped to the application (WSAPLCERTIFICADO) replaces the client_secret
+// @Description (RFC 8705 tls_client_auth); `client_id` is then optional.
+// @Description The optional `scope` parameter (space separated) narrows the granted scopes to a subset of WSAPLSCOPO.
+// @Description Errors follow RFC 6749 section 5.2 (`invalid_request`, `invalid_client`, `invalid_scope`, `unsupported_grant_type`, `server_error`).
+// @Description
+// @Description **Example:**
+// @Description ```bash
+// @Description curl -X POST "https://api.example.com/connect/v1/token" \
+// @Description   -u "client_id:client_secret" \
+// @Description   -d "grant_type=client_credentials&scope=clientes produtos"
+// @Description ```
+// @Tags Authentication
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param Authorization header string false "Basic Auth (Base64 encoded client_id:client_secret)"
+// @Param grant_type formData string true "Must be 'client_credentials'"
+// @Param client_id formData string false "client_id (when Basic Auth is not used)"
+// @Param client_secret formData string false "client_secret (when Basic Auth is not used)"
+// @Param scope formData string false "Space separated subset of the application scopes"
+// @Success 200 {object} models.OAuthTokenResponse "Token generated successfully"
+// @Failure 400 {object} models.OAuthErrorResponse "invalid_request, invalid_scope or unsupported_grant_type"
+// @Failure 401 {object} models.OAuthErrorResponse "invalid_client"
+// @Failure 500 {object} models.OAuthErrorResponse "server_error (token generation or database failure)"
+// @Router /connect/v1/token [post]
+// @Security BasicAuth
+func OAuthToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "OAuthToken"
+		host := c.GetHeader("Host")
+
+		// RFC 6749 5.1: respostas de token não devem ser armazenadas em cache
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		grantType := utils.EliminaCaracterNulo(c.PostForm("grant_type"))
+		requestedScope := strings.TrimSpace(utils.EliminaCaracterNulo(c.PostForm("scope")))
+		reqParametros := "grant_type: " + grantType + "\nscope: " + requestedScope + "\n"
+
+		logger.Infow("Gerando Token (OAuth2)",
+			"client_ip", c.ClientIP(),
+			"host", host,
+			"grant_type", grantType)
+
+		if !strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded") {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.OAuthErrorResponse{
+				Error:            "invalid_request",
+				ErrorDescription: "Content-Type deve ser application/x-www-form-urlencoded.",
+			})
+			return
+		}
+		if grantType == "" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.OAuthErrorResponse{
+				Error:            "invalid_request",
+				ErrorDescription: "O parâmetro grant_type deve ser informado.",
+			})
+			return
+		}
+		if grantType != "client_credentials" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.OAuthErrorResponse{
+				Error:            "unsupported_grant_type",
+				ErrorDescription: "Apenas grant_type=client_credentials é suportado.",
+			})
+			return
+		}
+
+		var app *models.Application
+		var clientID string
+		var errResponse models.TokenResponse
+		var errCode int
+
+		if certApp := certificateClient(c); certApp != nil {
+			// RFC 8705 (tls_client_auth): client autenticado pelo certificado; client_id, se informado, deve corresponder
+			clientID = certApp.ClientID
+			reqParametros += "client_id: " + clientID + "\ncertificado: " + certApp.Certificado + "\n"
+			if formClientID := utils.EliminaCaracterNulo(c.PostForm("client_id")); formClientID != "" && formClientID != clientID {
+				errResponse, errCode = checkCertificateBinding(c, logger, formClientID)
+			} else {
+				app, errResponse, errCode = authenticateCertificate(db, logger, certApp, c.ClientIP())
+			}
+		} else {
+			var clientSecret string
+			if clientID, clientSecret, errResponse, errCode = clientCredentials(c, logger); errCode == 0 {
+				reqParametros += "client_id: " + clientID + "\n"
+				if errResponse, errCode = checkCertificateBinding(c, logger, clientID); errCode == 0 {
+					app, errResponse, errCode = authenticateClient(db, logger, clientID, clientSecret, c.ClientIP())
+				}
+			}
+		}
+
+		if errCode == 0 {
+			issueOAuthToken(c, cfg, db, logger, reqCtx, reqParametros, app, requestedScope, host)
+			return
+		}
+
+		// IP fora da lista permitida da aplicação: credenciais válidas, mas client não autorizado
+		if errResponse.Code == "018" {
+			recordIPViolation(c, errResponse, errCode)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.OAuthErrorResponse{
+				Error:            "unauthorized_client",
+				ErrorDescription: errResponse.Message,
+			})
+			return
+		}
+
+		// Falha no banco ao verificar a aplicação: erro do servidor, não credencial inválida
+		if errResponse.Code == "003" {
+			logger.Errorw("Falha ao verificar aplicação (OAuth2)", "client_id", clientID, "message", errResponse.Message)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.OAuthErrorResponse{
+				Error:            "server_error",
+				ErrorDescription: errResponse.Message,
+			})
+			return
+		}
+
+		// RFC 6749 5.2: falhas de autenticação do client retornam invalid_client (401)
+		logger.Warnw("Falha na autenticação do client (OAuth2)",
+			"client_id", clientID,
+			"code", errResponse.Code,
+			"message", errResponse.Message)
+		c.Header("WWW-Authenticate", `Basic realm="wsicrmrest"`)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusUnauthorized, models.OAuthErrorResponse{
+			Error:            "invalid_client",
+			ErrorDescription: errResponse.Message,
+		})
+	}
+}
+
+// issueOAuthToken gera o token com o escopo solicitado e grava os logs
+func issueOAuthToken(c *gin.Context, cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, reqParametros string, app *models.Application, requestedScope, host string) {
+	nomeProcedure := "OAuthToken"
+	reqCtx.SetClientInfo(app.ClientID, app.Nome)
+
+	scope, ok := narrowScope(app.Scopo, requestedScope)
+	if !ok {
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.OAuthErrorResponse{
+			Error:            "invalid_scope",
+			ErrorDescription: "Escopo solicitado inválido ou não permitido para a aplicação.",
+		})
+		return
+	}
+
+	// Token emitido com o escopo concedido (subconjunto de WSAPLSCOPO)
+	granted := *app
+	granted.Scopo = scope
+
+	token, expiration, nbf, err := generateJWT(cfg, &granted)
+	if err != nil {
+		logger.Errorw("Erro ao gerar token", "error", err)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.OAuthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: "Erro ao gerar token JWT.",
+		})
+		return
+	}
+
+	// Gravar log do token no banco
+	if err := logTokenToDB(db, app.ClientID, token, host, nbf, expiration); err != nil {
+		logger.Errorw("Erro ao gravar log do token", "error", err)
+	}
+
+	respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiration - nbf,
+		Scope:       utils.Escopo(scope),
+	})
+}
+
+// narrowScope converte o parâmetro scope (nomes separados por espaço) em bits de escopo
+// Sem scope, concede todos os escopos da aplicação. Retorna false se algum nome for
+// desconhecido ou não estiver contido em WSAPLSCOPO.
+func narrowScope(appScope int64, requested string) (int64, bool) {
+	if requested == "" {
+		return appScope, true
+	}
+
+	var scope int64
+	for _, nome := range strings.Fields(requested) {
+		bit := utils.EscopoBits(nome)
+		if bit == 0 || appScope&bit == 0 {
+			return 0, false
+		}
+		scope |= bit
+	}
+
+	return scope, true
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// exchangeRefreshToken troca um refresh token por um novo access token (Grant_type: refresh_token)
+// O refresh token é de uso único: é consumido e substituído por um novo da mesma família.
+// A reapresentação de um token já utilizado invalida toda a família.
+func exchangeRefreshToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, refreshToken, host, clientIP string) (models.TokenResponse, int) {
+	invalid := models.TokenResponse{
+		Code:    "009",
+		Message: "Refresh_token inválido, expirado ou já utilizado.",
+	}
+
+	if refreshToken == "" {
+		return invalid, 401
+	}
+
+	stored, err := db.GetRefreshToken(auth.HashRefreshToken(refreshToken))
+	if err != nil {
+		logger.Errorw("Erro ao buscar refresh token", "error", err)
+		return models.TokenResponse{
+			Code:    "003",
+			Message: "Falha ao verificar aplicação.",
+		}, 403
+	}
+	if stored == nil {
+		return invalid, 401
+	}
+
+	if stored.Utilizado {
+		// Reuso de refresh token: possível vazamento, invalida a família inteira
+		revoked, _ := db.RevokeRefreshTokenFamily(stored.Familia)
+		logger.Warnw("Refresh token reutilizado - família invalidada",
+			"client_id", stored.ClientID,
+			"familia", stored.Familia,
+			"revoked", revoked)
+		return invalid, 401
+	}
+
+	if time.Now().After(stored.Expiracao) {
+		return invalid, 401
+	}
+
+	// Aplicação e IP verificados antes de consumir: uma troca rejeitada não inutiliza o refresh token
+	app, err := getApplicationByClientID(db, stored.ClientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Errorw("Erro ao verificar aplicação do refresh token", "client_id", stored.ClientID, "error", err)
+		return models.TokenResponse{
+			Code:    "003",
+			Message: "Falha ao verificar aplicação.",
+		}, 500
+	}
+	if err != nil || app.Status != 1 {
+		return models.TokenResponse{
+			Code:    "006",
+			Message: "Aplicação Desabilitada.",
+		}, 409
+	}
+
+	if errResponse, errCode := checkIPAllowlist(logger, app, clientIP); errCode != 0 {
+		return errResponse, errCode
+	}
+
+	consumed, err := db.ConsumeRefreshToken(stored.Numero)
+	if err != nil {
+		logger.Errorw("Erro ao consumir refresh token", "error", err)
+		return models.TokenResponse{
+			Code:    "003",
+			Message: "Falha ao verificar aplicação.",
+		}, 403
+	}
+	if !consumed {
+		// Outro processo consumiu o mesmo token simultaneamente
+		db.RevokeRefreshTokenFamily(stored.Familia)
+		return invalid, 401
+	}
+
+	logger.Infow("Gerando Token por refresh_token", "client_id", app.ClientID)
+
+	token, expiration, nbf, err := generateJWT(cfg, app)
+	if err != nil {
+		logger.Errorw("Erro ao gerar token", "error", err)
+		return models.TokenResponse{
+			Code:    "008",
+			Message: "Erro ao gerar token JWT.",
+		}, 500
+	}
+
+	reqCtx.SetClientInfo(app.ClientID, app.Nome)
+
+	response := models.TokenResponse{
+		Code:        "000",
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiration,
+		DateTime:    nbf,
+		Scope:       utils.Escopo(app.Scopo),
+		Modulos:     cfg.Organization.RegModulos,
+	}
+
+	if err := logTokenToDB(db, app.ClientID, token, host, nbf, expiration); err != nil {
+		logger.Errorw("Erro ao gravar log do token", "error", err)
+	}
+
+	// Rotação: novo refresh token na mesma família
+	if app.RefreshExpiracao > 0 {
+		newRefresh, refreshExp, err := issueRefreshToken(cfg, db, app, stored.Familia)
+		if err != nil {
+			logger.Errorw("Erro ao gerar refresh token", "error", err)
+		} else {
+			response.RefreshToken = newRefresh
+			response.RefreshExpiresIn = refreshExp
+		}
+	}
+
+	return response, 200
+}
+
+// issueRefreshToken gera e grava um refresh token com a validade configurada para a aplicação
+// familia vazia inicia uma nova família (login por client_credentials)
+func issueRefreshToken(cfg *config.Config, db *database.Database, app *models.Application, familia string) (string, int64, error) {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if familia == "" {
+		familia = uuid.New().String()
+	}
+
+	now := time.Now()
+	lifetime := time.Duration(app.RefreshExpiracao) * time.Second
+
+	if err := db.InsertRefreshToken(hash, app.ClientID, familia, now.Add(lifetime)); err != nil {
+		return "", 0, err
+	}
+
+	exp := utils.CalcTimeStampUnix(now, cfg.JWT.Timezone) + int64(app.RefreshExpiracao)
+	return token, exp, nil
+}
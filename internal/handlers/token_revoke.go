@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RevokeToken godoc
+// @Summary Revoke a token
+// @Description Revokes a single issued token, identified by the token itself or by its WSLTKNUMERO.
+// @Description The revocation is stored in WSAPLLOGTOKEN and propagated to every instance through the revocation cache.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body models.RevokeTokenRequest true "Token or WSLTKNUMERO to revoke"
+// @Success 200 {object} models.RevokeResponse "Token revoked"
+// @Failure 400 {object} models.RevokeResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.RevokeResponse "Token not found or already revoked"
+// @Failure 500 {object} models.RevokeResponse "Database error"
+// @Router /connect/v1/admin/tokens/revoke [post]
+// @Security BearerAuth
+func RevokeToken(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "RevokeToken"
+
+		var req models.RevokeTokenRequest
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			err = json.Unmarshal(bodyBytes, &req)
+		}
+		req.Token = strings.TrimSpace(req.Token)
+
+		if err != nil || (req.Token == "" && req.Numero <= 0) {
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusBadRequest, models.RevokeResponse{
+				Code:    "001",
+				Message: "Informe o token ou o número (WSLTKNUMERO) a ser revogado.",
+			})
+			return
+		}
+
+		var revoked int64
+		var reqParametros, alvo string
+		if req.Token != "" {
+			alvo = maskToken(req.Token)
+			reqParametros = "Token: " + alvo + "\n"
+			revoked, err = db.RevokeToken(req.Token)
+		} else {
+			alvo = strconv.FormatInt(req.Numero, 10)
+			reqParametros = fmt.Sprintf("Numero: %d\n", req.Numero)
+			revoked, err = db.RevokeTokenByNumero(req.Numero)
+		}
+
+		if err != nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.RevokeResponse{
+				Code:    "003",
+				Message: "Falha ao revogar token.",
+			})
+			return
+		}
+
+		if revoked == 0 {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusNotFound, models.RevokeResponse{
+				Code:    "002",
+				Message: "Token não encontrado ou já revogado.",
+			})
+			return
+		}
+
+		if cache := auth.GetRevocationCache(); cache != nil {
+			if req.Token != "" {
+				cache.MarkRevoked(req.Token)
+			} else {
+				cache.Refresh()
+			}
+		}
+
+		auditAdmin(c, db, reqCtx, "TOKEN_REVOGAR", alvo, nil, http.StatusOK)
+
+		logger.Infow("Token revogado",
+			"admin_client_id", reqCtx.ClientID,
+			"admin_ip", c.ClientIP(),
+			"numero", req.Numero)
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.RevokeResponse{
+			Code:    "000",
+			Message: "Token revogado com sucesso.",
+			Revoked: revoked,
+		})
+	}
+}
+
+// RevokeClientTokens godoc
+// @Summary Revoke all live tokens of a client
+// @Description Revokes every non-expired token issued to the given client_id.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body models.RevokeClientTokensRequest true "client_id whose tokens will be revoked"
+// @Success 200 {object} models.RevokeResponse "Tokens revoked (revoked may be 0)"
+// @Failure 400 {object} models.RevokeResponse "Invalid request"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 500 {object} models.RevokeResponse "Database error"
+// @Router /connect/v1/admin/tokens/revoke-client [post]
+// @Security BearerAuth
+func RevokeClientTokens(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "RevokeClientTokens"
+
+		var req models.RevokeClientTokensRequest
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			err = json.Unmarshal(bodyBytes, &req)
+		}
+		req.ClientID = strings.TrimSpace(req.ClientID)
+
+		if err != nil || req.ClientID == "" {
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusBadRequest, models.RevokeResponse{
+				Code:    "001",
+				Message: "Informe o client_id cujos tokens devem ser revogados.",
+			})
+			return
+		}
+
+		reqParametros := "Client_Id: " + req.ClientID + "\n"
+
+		revoked, err := db.RevokeClientTokens(req.ClientID)
+		if err != nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.RevokeResponse{
+				Code:    "003",
+				Message: "Falha ao revogar tokens.",
+			})
+			return
+		}
+
+		if cache := auth.GetRevocationCache(); cache != nil && revoked > 0 {
+			cache.Refresh()
+		}
+
+		// Refresh tokens do cliente também deixam de valer
+		if _, err := db.RevokeClientRefreshTokens(req.ClientID); err != nil {
+			logger.Errorw("Erro ao revogar refresh tokens", "error", err, "client_id", req.ClientID)
+		}
+
+		auditAdmin(c, db, reqCtx, "TOKEN_REVOGAR_CLIENTE", req.ClientID, map[string]interface{}{
+			"revoked": revoked,
+		}, http.StatusOK)
+
+		logger.Infow("Tokens do cliente revogados",
+			"client_id", req.ClientID,
+			"revoked", revoked,
+			"admin_client_id", reqCtx.ClientID,
+			"admin_ip", c.ClientIP())
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.RevokeResponse{
+			Code:    "000",
+			Message: "Tokens revogados com sucesso.",
+			Revoked: revoked,
+		})
+	}
+}
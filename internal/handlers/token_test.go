@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/testhelpers"
+	"wsicrmrest/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// TestGenerateJWT_ParseToken garante que os tokens emitidos por generateJWT
+// (base64 padrão misturado com substituições url-safe) continuam válidos no middleware
+func TestGenerateJWT_ParseToken(t *testing.T) {
+	cfg := testhelpers.SetupTestConfig()
+
+	// Vários client_id/nome para cobrir payloads que geram "+" e "/" no base64
+	for i := 0; i < 50; i++ {
+		app := &models.Application{
+			ClientID:     fmt.Sprintf("CLIENTE%03d?>>~", i),
+			JWTExpiracao: 3600,
+			Scopo:        int64(i*37) | 1,
+			Status:       1,
+			Nome:         fmt.Sprintf("Aplicação ÿ %d", i),
+		}
+
+		token, exp, _, err := generateJWT(cfg, app)
+		if err != nil {
+			t.Fatalf("generateJWT: %v", err)
+		}
+
+		claims, err := auth.ParseToken(cfg, token)
+		if err != nil {
+			t.Fatalf("ParseToken(%q): %v", token, err)
+		}
+		if claims.ClientID != app.ClientID || claims.Aplicacao != app.Nome || claims.Exp != exp {
+			t.Fatalf("claims inesperadas: %+v", claims)
+		}
+	}
+}
+
+// TestParseToken_Rejections verifica os principais motivos de rejeição
+func TestParseToken_Rejections(t *testing.T) {
+	cfg := testhelpers.SetupTestConfig()
+	app := &models.Application{ClientID: "CLIENTE1", JWTExpiracao: 3600, Scopo: 1, Status: 1, Nome: "Teste"}
+
+	token, _, _, err := generateJWT(cfg, app)
+	if err != nil {
+		t.Fatalf("generateJWT: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := auth.ParseToken(cfg, tampered); err == nil {
+		t.Error("token adulterado deveria ser rejeitado")
+	}
+
+	if _, err := auth.ParseToken(cfg, parts[0]+"."+parts[1]); !errors.Is(err, auth.ErrTokenMalformed) {
+		t.Errorf("esperado ErrTokenMalformed, obtido %v", err)
+	}
+
+	otherIssuer := *cfg
+	otherIssuer.JWT.Issuer = "OutroEmissor"
+	if _, err := auth.ParseToken(&otherIssuer, token); !errors.Is(err, auth.ErrTokenIssuer) {
+		t.Errorf("esperado ErrTokenIssuer, obtido %v", err)
+	}
+
+	expired := &models.Application{ClientID: "CLIENTE1", JWTExpiracao: 1, Scopo: 1, Status: 1, Nome: "Teste"}
+	future := *cfg
+	future.JWT.Timezone = 1 // Gera nbf/exp uma hora no passado
+	expiredToken, _, _, _ := generateJWT(&future, expired)
+	if _, err := auth.ParseToken(cfg, expiredToken); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("esperado ErrTokenExpired, obtido %v", err)
+	}
+
+	// Variações de encoding da assinatura geram outro texto para o mesmo token (revogação por texto)
+	variants := []string{token + "=", parts[0] + "." + parts[1] + "." + strings.NewReplacer("-", "+", "/", "_").Replace(parts[2])}
+	for _, variant := range variants {
+		if variant == token {
+			continue
+		}
+		if _, err := auth.ParseToken(cfg, variant); !errors.Is(err, auth.ErrTokenMalformed) {
+			t.Errorf("variação %q: esperado ErrTokenMalformed, obtido %v", variant, err)
+		}
+	}
+
+	otherKey := *cfg
+	otherKey.JWT.SecretKey = "OutraChave"
+	if _, err := auth.ParseToken(&otherKey, token); !errors.Is(err, auth.ErrTokenSignature) {
+		t.Errorf("esperado ErrTokenSignature, obtido %v", err)
+	}
+}
+
+// TestGenerateJWT_KeyRotation emite tokens RS256/ES256 e garante que tokens da chave
+// anterior continuam válidos após a rotação, enquanto HS256 respeita legacy_hs256
+func TestGenerateJWT_KeyRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaFile := writePKCS8Key(t, dir, "rsa.key", rsaKey)
+	ecFile := writePKCS8Key(t, dir, "ec.key", ecKey)
+
+	cfg := testhelpers.SetupTestConfig()
+	hsToken, _, _, err := generateJWT(cfg, &models.Application{ClientID: "CLIENTE1", JWTExpiracao: 3600, Scopo: 1, Nome: "Teste"})
+	if err != nil {
+		t.Fatalf("generateJWT HS256: %v", err)
+	}
+
+	cfg.JWT.KeyFiles = []config.JWTKeyFile{{KID: "rsa-1", File: rsaFile}, {KID: "ec-2", File: ecFile}}
+	cfg.JWT.SigningAlgorithm = "RS256"
+	cfg.JWT.SigningKeyID = "rsa-1"
+	cfg.JWT.LegacyHS256 = true
+	if _, err := auth.LoadKeyStore(cfg); err != nil {
+		t.Fatalf("LoadKeyStore: %v", err)
+	}
+
+	app := &models.Application{ClientID: "CLIENTE1", JWTExpiracao: 3600, Scopo: 1, Nome: "Teste"}
+	rsToken, _, _, err := generateJWT(cfg, app)
+	if err != nil {
+		t.Fatalf("generateJWT RS256: %v", err)
+	}
+
+	// Rotação: ES256 passa a assinar, rsa-1 permanece somente para validação
+	cfg.JWT.SigningAlgorithm = "ES256"
+	cfg.JWT.SigningKeyID = "ec-2"
+	ks, err := auth.LoadKeyStore(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeyStore: %v", err)
+	}
+	if len(ks.JWKS().Keys) != 2 {
+		t.Fatalf("JWKS deveria publicar 2 chaves, obtido %d", len(ks.JWKS().Keys))
+	}
+
+	esToken, _, _, err := generateJWT(cfg, app)
+	if err != nil {
+		t.Fatalf("generateJWT ES256: %v", err)
+	}
+
+	for _, token := range []string{rsToken, esToken, hsToken} {
+		if _, err := auth.ParseToken(cfg, token); err != nil {
+			t.Errorf("ParseToken(%q): %v", token, err)
+		}
+	}
+
+	cfg.JWT.LegacyHS256 = false
+	if _, err := auth.ParseToken(cfg, hsToken); !errors.Is(err, auth.ErrTokenAlgorithm) {
+		t.Errorf("esperado ErrTokenAlgorithm para HS256 sem legacy_hs256, obtido %v", err)
+	}
+
+	// Chave removida de key_files: tokens emitidos com ela deixam de ser aceitos
+	cfg.JWT.KeyFiles = cfg.JWT.KeyFiles[1:]
+	if _, err := auth.LoadKeyStore(cfg); err != nil {
+		t.Fatalf("LoadKeyStore: %v", err)
+	}
+	if _, err := auth.ParseToken(cfg, rsToken); !errors.Is(err, auth.ErrTokenKeyID) {
+		t.Errorf("esperado ErrTokenKeyID, obtido %v", err)
+	}
+}
+
+func writePKCS8Key(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestNarrowScope verifica a redução de escopo do parâmetro scope do POST /connect/v1/token
+func TestNarrowScope(t *testing.T) {
+	appScope := utils.EscopoClientes | utils.EscopoProdutos | utils.EscopoSistema
+
+	tests := []struct {
+		requested string
+		want      int64
+		ok        bool
+	}{
+		{"", appScope, true},
+		{"clientes", utils.EscopoClientes, true},
+		{"produtos  sistema", utils.EscopoProdutos | utils.EscopoSistema, true},
+		{"lojas", 0, false},
+		{"clientes inexistente", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := narrowScope(appScope, tt.requested)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("narrowScope(%q) = %d, %v; esperado %d, %v", tt.requested, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestAuthenticateClient garante os códigos de erro da validação do client: client_id desconhecido
+// retorna 409/004 (até a versão 3.0.0.6 retornava 403/003) e 003 fica reservado para falhas do banco
+func TestAuthenticateClient(t *testing.T) {
+	original := lookupApplication
+	t.Cleanup(func() { lookupApplication = original })
+
+	apps := map[string]*models.Application{
+		"ATIVA":      {ClientID: "ATIVA", ClientSecret: "secret", Status: 1},
+		"DESATIVADA": {ClientID: "DESATIVADA", ClientSecret: "secret", Status: 0},
+	}
+	lookupApplication = func(db *database.Database, clientID string) (*models.Application, error) {
+		if clientID == "FALHA" {
+			return nil, errors.New("ORA-03113: end-of-file on communication channel")
+		}
+		if app, ok := apps[clientID]; ok {
+			return app, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	tests := []struct {
+		clientID string
+		secret   string
+		code     string
+		status   int
+	}{
+		{"ATIVA", "secret", "", 0},
+		{"DESCONHECIDA", "secret", "004", 409},
+		{"DESATIVADA", "secret", "006", 409},
+		{"ATIVA", "errado", "005", 409},
+		{"FALHA", "secret", "003", 403},
+	}
+
+	logger := zap.NewNop().Sugar()
+	for _, tt := range tests {
+		_, response, status := authenticateClient(nil, logger, tt.clientID, tt.secret, "10.0.0.1")
+		if response.Code != tt.code || status != tt.status {
+			t.Errorf("%s: código %q status %d, esperado %q %d", tt.clientID, response.Code, status, tt.code, tt.status)
+		}
+	}
+}
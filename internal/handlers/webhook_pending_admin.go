@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// webhookPendingMaxLimit limite de eventos retornados pela listagem
+const webhookPendingMaxLimit = 500
+
+// ListWebhookPending godoc
+// @Summary List unmatched webhook events
+// @Description Lists webhook events whose message was not found (WSWEBHOOKPENDENTES), most recent first. Pending
+// @Description events are retried automatically with exponential backoff ([webhook] dead_letter_retry_seconds) until
+// @Description they are applied or reach [webhook] dead_letter_max_age_hours.
+// @Tags Administration
+// @Produce json
+// @Param situacao query int false "0 = pending (default), 1 = applied, 2 = expired"
+// @Param provider query string false "Provider filter, e.g. zenvia-email"
+// @Param limit query int false "Maximum number of events (default 100, max 500)"
+// @Success 200 {object} models.WebhookPendingListResponse "Events"
+// @Failure 400 {object} models.WebhookPendingListResponse "Invalid filter"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 500 {object} models.WebhookPendingListResponse "Database error"
+// @Router /connect/v1/admin/webhooks/pending [get]
+// @Security BearerAuth
+func ListWebhookPending(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "ListWebhookPending"
+		reqParametros := c.Request.URL.RawQuery
+
+		situacao, errSituacao := strconv.Atoi(c.DefaultQuery("situacao", "0"))
+		limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if errSituacao != nil || situacao < database.PendenteAguardando || situacao > database.PendenteExpirado ||
+			errLimit != nil || limit <= 0 || limit > webhookPendingMaxLimit {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.WebhookPendingListResponse{
+				Code:    "001",
+				Message: fmt.Sprintf("situacao deve ser 0, 1 ou 2 e limit entre 1 e %d.", webhookPendingMaxLimit),
+				Pending: []models.WebhookPendingInfo{},
+			})
+			return
+		}
+
+		rows, err := db.ListWebhookPendings(situacao, c.Query("provider"), limit)
+		if err != nil {
+			logger.Errorw("Erro ao listar WSWEBHOOKPENDENTES", "error", err)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.WebhookPendingListResponse{
+				Code:    "003",
+				Message: "Falha ao consultar eventos pendentes.",
+				Pending: []models.WebhookPendingInfo{},
+			})
+			return
+		}
+
+		pending := make([]models.WebhookPendingInfo, 0, len(rows))
+		for i := range rows {
+			pending = append(pending, webhookPendingInfo(&rows[i]))
+		}
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.WebhookPendingListResponse{
+			Code:    "000",
+			Pending: pending,
+		})
+	}
+}
+
+// ReprocessWebhookPending godoc
+// @Summary Reprocess an unmatched webhook event
+// @Description Immediately runs a pending or expired event from WSWEBHOOKPENDENTES through the webhook pipeline.
+// @Description If the message is still not found, a pending event keeps its retry schedule and an expired event
+// @Description stays expired; the attempt is recorded in both cases. Applied events are returned unchanged.
+// @Tags Administration
+// @Produce json
+// @Param numero path int true "Event number (WSWPENUMERO)"
+// @Success 200 {object} models.WebhookPendingResponse "Reprocessing result (see pending.situacao)"
+// @Failure 400 {object} models.WebhookPendingResponse "Invalid event number"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 404 {object} models.WebhookPendingResponse "Event not found"
+// @Failure 500 {object} models.WebhookPendingResponse "Database error or dead-letter store disabled"
+// @Router /connect/v1/admin/webhooks/pending/{numero}/reprocess [post]
+// @Security BearerAuth
+func ReprocessWebhookPending(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "ReprocessWebhookPending"
+		reqParametros := "Numero: " + c.Param("numero") + "\n"
+
+		numero, err := strconv.ParseInt(c.Param("numero"), 10, 64)
+		if err != nil || numero <= 0 {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.WebhookPendingResponse{
+				Code:    "001",
+				Message: "Número do evento inválido.",
+			})
+			return
+		}
+
+		deadLetter := webhook.GetDeadLetter()
+		if deadLetter == nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.WebhookPendingResponse{
+				Code:    "003",
+				Message: "Reprocessamento de eventos pendentes desabilitado ([webhook] dead_letter).",
+			})
+			return
+		}
+
+		pending, err := deadLetter.Reprocess(numero)
+		if err != nil {
+			logger.Errorw("Erro ao reprocessar evento de webhook pendente", "numero", numero, "error", err)
+			auditAdmin(c, db, reqCtx, "WEBHOOK_PENDENTE_REPROCESSAR", strconv.FormatInt(numero, 10), nil, http.StatusInternalServerError)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.WebhookPendingResponse{
+				Code:    "003",
+				Message: "Falha ao reprocessar o evento.",
+			})
+			return
+		}
+		if pending == nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusNotFound, models.WebhookPendingResponse{
+				Code:    "002",
+				Message: "Evento não encontrado.",
+			})
+			return
+		}
+
+		auditAdmin(c, db, reqCtx, "WEBHOOK_PENDENTE_REPROCESSAR", strconv.FormatInt(numero, 10), map[string]interface{}{
+			"situacao":   pending.Situacao,
+			"tentativas": pending.Tentativas,
+		}, http.StatusOK)
+
+		message := "Evento aplicado."
+		if pending.Situacao != database.PendenteProcessado {
+			message = "Mensagem ainda não encontrada."
+		}
+
+		info := webhookPendingInfo(pending)
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.WebhookPendingResponse{
+			Code:    "000",
+			Message: message,
+			Pending: &info,
+		})
+	}
+}
+
+// webhookPendingInfo converte o registro de WSWEBHOOKPENDENTES para a resposta da API
+func webhookPendingInfo(data *database.WebhookPendingData) models.WebhookPendingInfo {
+	return models.WebhookPendingInfo{
+		Numero:           data.Numero,
+		Provedor:         data.Provedor,
+		MessageID:        data.MessageID,
+		ExternalID:       data.ExternalID,
+		Status:           data.Status,
+		UUID:             data.UUID,
+		Data:             data.Data,
+		Tentativas:       data.Tentativas,
+		ProximaTentativa: data.ProximaTentativa,
+		Situacao:         data.Situacao,
+		Erro:             data.Erro,
+		DataFim:          data.DataFim,
+	}
+}
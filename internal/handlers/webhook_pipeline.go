@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// webhookLogSeparator separador dos blocos de log de cada webhook recebido
+const webhookLogSeparator = "===================================================================================================="
+
+// webhookFailureResponse resposta de evento não aplicado por falha do banco (transação desfeita)
+var webhookFailureResponse = models.WebhookResponse{
+	Success: false,
+	Message: "Falha ao gravar evento no banco de dados",
+}
+
+// errDuplicateEvent evento já registrado em WSWEBHOOKEVENTOS: a transação é desfeita sem gravar nada
+var errDuplicateEvent = errors.New("evento de webhook duplicado")
+
+// WebhookHandler recebe os eventos de status de um provedor de webhook
+// Fluxo compartilhado por todos os provedores: leitura e parse do body, validação do tipo de
+// callback e do status, gravação na fila assíncrona (quando habilitada) ou aplicação imediata
+// do evento, resposta ao provedor e log da requisição em WSREQUISICOES.
+// Eventos válidos recebem 200 (inclusive mensagem não encontrada) para evitar reenvios; falha do
+// banco ao aplicar um evento retorna 500 para que o provedor reenvie (a deduplicação descarta os
+// eventos do lote que já foram aplicados).
+func WebhookHandler(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider) gin.HandlerFunc {
+	info := provider.Info()
+
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		var reqParametros string
+
+		respond := func(codError int, response models.WebhookResponse) {
+			logger.Infow("Finalizando tratamento de mensagem recebida pelo "+info.Label,
+				"success", response.Success,
+				"message", response.Message)
+			logger.Infow(webhookLogSeparator)
+
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, info.Procedure, codError, response)
+		}
+
+		// Log inicial
+		logger.Infow(webhookLogSeparator)
+		logger.Infow("Iniciando tratamento de mensagem recebida pelo " + info.Label)
+		logger.Infow(webhookLogSeparator)
+
+		// Ler o body
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logger.Errorw("Erro ao ler body da requisição", "error", err)
+			respond(http.StatusBadRequest, models.WebhookResponse{
+				Success: false,
+				Message: "Erro ao ler body da requisição",
+			})
+			return
+		}
+
+		reqParametros = string(bodyBytes)
+
+		// Log do payload recebido
+		logger.Infow("Payload recebido do "+info.Label, "payload", reqParametros)
+
+		events, err := provider.Parse(bodyBytes)
+		if err != nil {
+			logger.Errorw("Erro ao fazer parse do JSON", "error", err, "body", reqParametros)
+			respond(http.StatusBadRequest, models.WebhookResponse{
+				Success: false,
+				Message: "JSON inválido",
+			})
+			return
+		}
+
+		responses := make([]models.WebhookResponse, 0, len(events))
+		failed := 0
+		for _, event := range events {
+			response, err := receiveWebhookEvent(c.Request.Context(), db, logger, provider, event, reqCtx.UUID)
+			if err != nil {
+				failed++
+			}
+			responses = append(responses, response)
+		}
+
+		if failed > 0 {
+			respond(http.StatusInternalServerError, models.WebhookResponse{
+				Success: false,
+				Message: fmt.Sprintf("Falha ao gravar %d de %d eventos - reenvie a requisição", failed, len(events)),
+			})
+			return
+		}
+
+		respond(http.StatusOK, summarizeWebhookResponses(responses))
+	}
+}
+
+// WebhookProcessor aplica os eventos do provedor gravados na fila de ingestão assíncrona
+func WebhookProcessor(db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider) webhook.Processor {
+	return func(queued *webhook.QueuedEvent) error {
+		var event webhook.Event
+		if err := json.Unmarshal([]byte(queued.Payload), &event); err != nil {
+			// O evento foi validado antes de entrar na fila: não há o que reprocessar
+			logger.Errorw("Payload inválido na fila de webhooks", "id", queued.ID, "uuid", queued.UUID, "error", err)
+			return nil
+		}
+
+		_, err := applyWebhookEvent(context.Background(), db, logger, provider, &event, queued.UUID)
+		return deadLetterUnmatched(logger, &event, queued.UUID, err)
+	}
+}
+
+// WebhookDeadLetterHandler reaplica os eventos do provedor gravados em WSWEBHOOKPENDENTES
+func WebhookDeadLetterHandler(db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider) webhook.DeadLetterHandler {
+	return func(event *webhook.Event, uuid string) error {
+		_, err := applyWebhookEvent(context.Background(), db, logger, provider, event, uuid)
+		return err
+	}
+}
+
+// deadLetterUnmatched grava em WSWEBHOOKPENDENTES o evento cuja mensagem não foi encontrada
+// para reprocessamento posterior; demais erros (e a falha ao gravar o pendente) são retornados
+// para que o evento seja reenviado pelo provedor ou reprocessado pela fila
+func deadLetterUnmatched(logger *zap.SugaredLogger, event *webhook.Event, uuid string, err error) error {
+	if !errors.Is(err, webhook.ErrMessageNotFound) {
+		return err
+	}
+
+	deadLetter := webhook.GetDeadLetter()
+	if deadLetter == nil {
+		return nil
+	}
+
+	numero, addErr := deadLetter.Add(event, uuid)
+	if addErr != nil {
+		logger.Errorw("Erro ao gravar evento em WSWEBHOOKPENDENTES",
+			"messageId", event.MessageID,
+			"error", addErr)
+		return addErr
+	}
+
+	logger.Infow("Evento gravado em WSWEBHOOKPENDENTES para reprocessamento",
+		"numero", numero,
+		"messageId", event.MessageID,
+		"externalId", event.ExternalID,
+		"evento", event.Code)
+	return nil
+}
+
+// summarizeWebhookResponses resposta ao provedor: a do único evento recebido ou o resumo do lote
+func summarizeWebhookResponses(responses []models.WebhookResponse) models.WebhookResponse {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	queued := len(responses) > 0
+	for _, response := range responses {
+		if !response.Queued {
+			queued = false
+		}
+	}
+
+	return models.WebhookResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d eventos processados", len(responses)),
+		Queued:  queued,
+	}
+}
+
+// receiveWebhookEvent valida um evento recebido e o grava na fila assíncrona ou o aplica imediatamente
+// O erro indica que o evento não foi aplicado nem guardado para reprocessamento (falha do banco)
+func receiveWebhookEvent(ctx context.Context, db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider, event *webhook.Event, uuid string) (models.WebhookResponse, error) {
+	info := provider.Info()
+
+	// Validar tipo de callback
+	if tipoCallback := strings.ToLower(event.Type); info.EventType != "" && tipoCallback != info.EventType {
+		logger.Warnw("Tipo de mensagem inválida",
+			"tipo", tipoCallback,
+			"esperado", info.EventType)
+
+		return models.WebhookResponse{
+			Success: true,
+			Message: fmt.Sprintf("Tipo de mensagem não processado: %s", tipoCallback),
+		}, nil
+	}
+
+	// Determinar status e tag baseado no evento
+	if _, ok := provider.Status(event); !ok {
+		logger.Warnw("Status fora do escopo de tratamento",
+			"evento", event.Code,
+			"descricao", event.Description)
+
+		return models.WebhookResponse{
+			Success: true,
+			Message: fmt.Sprintf("Status não processado: %s", event.Code),
+		}, nil
+	}
+
+	// Ingestão assíncrona: o evento é gravado na fila local e processado pelos workers
+	// Se a gravação na fila falhar o evento é processado de forma síncrona
+	if queue := webhook.GetQueue(); queue != nil {
+		payload, _ := json.Marshal(event)
+		if id, err := queue.Enqueue(info.Name, event.MessageID, uuid, payload); err != nil {
+			logger.Errorw("Erro ao gravar evento na fila de webhooks - processando de forma síncrona",
+				"messageId", event.MessageID,
+				"error", err)
+		} else {
+			logger.Infow("Evento gravado na fila de webhooks",
+				"id", id,
+				"messageId", event.MessageID,
+				"evento", event.Code)
+
+			return models.WebhookResponse{
+				Success: true,
+				Message: "Evento recebido - processamento assíncrono",
+				Queued:  true,
+			}, nil
+		}
+	}
+
+	response, err := applyWebhookEvent(ctx, db, logger, provider, event, uuid)
+	return response, deadLetterUnmatched(logger, event, uuid, err)
+}
+
+// applyWebhookEvent aplica um evento de status já validado: deduplicação, busca da mensagem,
+// máquina de estados e gravação em logsApi/logsApiHistorico, na tabela de mensagens do canal e da
+// ocorrência de contato inconsistente em uma única transação (Database.WithTx)
+// Usado no processamento síncrono, pelos workers da fila de ingestão e pelo reprocessamento de
+// WSWEBHOOKPENDENTES. O erro indica mensagem não encontrada (webhook.ErrMessageNotFound) ou falha
+// transitória do banco (o evento pode ser reprocessado); a resposta é a enviada ao provedor.
+func applyWebhookEvent(ctx context.Context, db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider, event *webhook.Event, uuid string) (models.WebhookResponse, error) {
+	info := provider.Info()
+
+	status, ok := provider.Status(event)
+	if !ok {
+		return models.WebhookResponse{
+			Success: true,
+			Message: fmt.Sprintf("Status não processado: %s", event.Code),
+		}, nil
+	}
+
+	logger.Infow("Processando ocorrência",
+		"provider", info.Name,
+		"from", event.Recipient,
+		"evento", event.Code,
+		"messageId", event.MessageID,
+		"externalId", event.ExternalID,
+		"description", event.Description)
+
+	// Deduplicação: reenvios do mesmo evento são confirmados sem novo processamento
+	evento := &database.WebhookEventData{
+		Provedor:   info.Name,
+		MessageID:  event.MessageID,
+		Status:     event.Code,
+		DataEvento: event.Timestamp,
+		UUID:       uuid,
+	}
+	// O cache em memória descarta reenvios recentes; o registro em WSWEBHOOKEVENTOS é feito na
+	// transação da gravação do evento (desfeito junto com ela)
+	dedup := webhook.GetDeduplicator()
+	if dedup != nil && dedup.Seen(evento) {
+		return duplicateWebhookEvent(logger, event, evento), nil
+	}
+
+	// Buscar dados da mensagem no banco
+	logger.Infow("Buscando mensagem no banco de dados",
+		"messageId", event.MessageID,
+		"externalId", event.ExternalID,
+		"destinatario", event.Recipient)
+
+	message, err := provider.FindMessage(db, event)
+	if err != nil {
+		logger.Warnw("O ID da mensagem NÃO foi encontrado na tabela "+info.Table,
+			"messageId", event.MessageID,
+			"externalId", event.ExternalID,
+			"destinatario", event.Recipient,
+			"evento", event.Code,
+			"error", err)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookResponse{
+				Success: true,
+				Message: "Mensagem não encontrada no banco de dados",
+			}, webhook.ErrMessageNotFound
+		}
+		return webhookFailureResponse, err
+	}
+
+	logger.Infow("ID da mensagem encontrado na tabela "+info.Table,
+		"messageId", event.MessageID,
+		"numero", message.Numero,
+		"clicodigo", message.CliCodigo,
+		"logsapiid", message.LogsApiId)
+
+	// Preparar dados para inserção no log
+	sLogsApiEnvio := "{}"
+	sLogsApiRetorno := strings.ReplaceAll(event.Raw, "'", "`")
+	sLogsApiDtaCadastro := time.Now().Format("20060102150405")
+	sLogsApiHisDescricao := strings.ReplaceAll(status.Descricao, "'", "")
+
+	response := models.WebhookResponse{
+		Success: true,
+		Message: "Webhook processado com sucesso",
+	}
+
+	// Gravação atômica: logsApi/logsApiHistorico, tabela de mensagens do canal, WSMSGSTATUS e a
+	// ocorrência de contato inconsistente são confirmadas juntas ou desfeitas em caso de erro
+	dataEvento := webhook.ParseEventTime(event.Timestamp)
+	var transicao webhook.Transition
+	err = db.WithTx(ctx, func(tx *database.Database) error {
+		if dedup != nil && dedup.Claim(tx, evento) {
+			return errDuplicateEvent
+		}
+
+		// Máquina de estados: o evento sempre entra no histórico, mas o status atual só avança
+		// (eventos tardios ou regressões não sobrescrevem um status mais recente)
+		// A linha da mensagem fica bloqueada até o commit: eventos simultâneos da mesma mensagem
+		// avaliam a transição sobre o status já gravado pelo anterior
+		estado, err := tx.LockMessageStatus(message.LogsApiId)
+		if err != nil {
+			logger.Errorw("Erro ao consultar status atual da mensagem", "logsApiId", message.LogsApiId, "error", err)
+			return err
+		}
+
+		// Eventos apenas de histórico mantêm o status atual (transição repetida, sem avanço)
+		if status.HistoryOnly {
+			status.Codigo = estado.Status
+		}
+
+		transicao = webhook.EvaluateTransition(estado.Status, estado.DataEvento, status.Codigo, dataEvento)
+		switch transicao {
+		case webhook.TransitionRegression:
+			logger.Warnw("Regressão de status detectada - evento gravado apenas no histórico",
+				"messageId", event.MessageID,
+				"logsApiId", message.LogsApiId,
+				"status_atual", estado.Status,
+				"status_evento", status.Codigo,
+				"data_evento", dataEvento,
+				"data_ultimo_evento", estado.DataEvento)
+		case webhook.TransitionOutOfOrder:
+			logger.Warnw("Evento fora de ordem - evento gravado apenas no histórico",
+				"messageId", event.MessageID,
+				"logsApiId", message.LogsApiId,
+				"status_atual", estado.Status,
+				"status_evento", status.Codigo,
+				"data_evento", dataEvento,
+				"data_ultimo_evento", estado.DataEvento)
+		}
+
+		if err := insertWebhookLogs(tx, info, message, event, status, transicao, dataEvento,
+			sLogsApiEnvio, sLogsApiRetorno, sLogsApiDtaCadastro, sLogsApiHisDescricao); err != nil {
+			logger.Errorw("Erro ao inserir logs API", "provider", info.Name, "error", err)
+			return err
+		}
+
+		// Inserir ocorrência se o contato for inconsistente (regra com ocorrência, ex: 125 - bounce)
+		if status.Ocorrencia && transicao.Advances() {
+			if err := insertWebhookOcorrencia(tx, info, message, event); err != nil {
+				logger.Errorw("Erro ao inserir ocorrência de contato inconsistente", "provider", info.Name, "error", err)
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errDuplicateEvent) {
+		dedup.Remember(evento)
+		return duplicateWebhookEvent(logger, event, evento), nil
+	}
+	if err != nil {
+		logger.Errorw("Gravação do evento desfeita", "provider", info.Name, "messageId", event.MessageID, "error", err)
+		return webhookFailureResponse, err
+	}
+	if dedup != nil {
+		dedup.Remember(evento)
+	}
+
+	logger.Infow("Processamento concluído com sucesso",
+		"from", event.Recipient,
+		"status", status.Codigo,
+		"tag", status.Tag,
+		"transicao", transicao.String())
+
+	return response, nil
+}
+
+// duplicateWebhookEvent resposta de evento já processado (reenvio do provedor)
+func duplicateWebhookEvent(logger *zap.SugaredLogger, event *webhook.Event, evento *database.WebhookEventData) models.WebhookResponse {
+	logger.Infow("Evento duplicado ignorado",
+		"messageId", event.MessageID,
+		"evento", event.Code,
+		"timestamp", evento.DataEvento)
+
+	return models.WebhookResponse{
+		Success:   true,
+		Message:   "Evento duplicado - já processado",
+		Duplicate: true,
+	}
+}
+
+// insertWebhookLogs grava o evento em logsApi/logsApiHistorico e na tabela de mensagens do canal
+func insertWebhookLogs(tx *database.Database, info webhook.ProviderInfo, message *webhook.Message, event *webhook.Event,
+	status webhook.Status, transicao webhook.Transition, dataEvento time.Time,
+	sLogsApiEnvio, sLogsApiRetorno, sLogsApiDtaCadastro, sLogsApiHisDescricao string) error {
+	switch info.Channel {
+	case webhook.ChannelWhatsApp:
+		return tx.InsereLogsAPIWhatsApp(
+			message.Numero,
+			status.Codigo,
+			sLogsApiRetorno,
+			sLogsApiHisDescricao,
+			message.LogsApiId,
+			status.Tag,
+			transicao.Advances(),
+			dataEvento,
+		)
+	case webhook.ChannelSMS:
+		return tx.InsereLogsAPISMS(
+			message.Numero,
+			0, // logsApiTipId
+			status.Codigo,
+			sLogsApiEnvio,
+			sLogsApiRetorno,
+			sLogsApiDtaCadastro,
+			sLogsApiHisDescricao,
+			1, // ocorrenciaNumero
+			event.Recipient,
+			message.LogsApiId,
+			status.Tag,
+			transicao.Advances(),
+			dataEvento,
+		)
+	}
+
+	return tx.InsereLogsAPI(
+		message.Numero,
+		0, // logsApiTipId
+		status.Codigo,
+		sLogsApiEnvio,
+		sLogsApiRetorno,
+		sLogsApiDtaCadastro,
+		sLogsApiHisDescricao,
+		1, // tipoMensagem (1 = email)
+		event.Recipient,
+		message.LogsApiId,
+		status.Tag,
+		transicao.Advances(),
+		dataEvento,
+	)
+}
+
+// insertWebhookOcorrencia grava a ocorrência de contato inconsistente e limpa o contato do cliente
+// WhatsApp apenas registra a ocorrência: o celular segue válido para SMS
+func insertWebhookOcorrencia(tx *database.Database, info webhook.ProviderInfo, message *webhook.Message, event *webhook.Event) error {
+	switch info.Channel {
+	case webhook.ChannelEmail:
+		return tx.InsereOcorrenciaEmailInconsistente(
+			event.Recipient,
+			message.CliCodigo,
+			721, // Tipo de ocorrência para email inconsistente
+			info.Source,
+		)
+	case webhook.ChannelWhatsApp:
+		return tx.InsereOcorrenciaWhatsAppInconsistente(
+			event.Recipient,
+			message.CliCodigo,
+			721, // Tipo de ocorrência para celular inconsistente
+			info.Source,
+		)
+	}
+
+	return tx.InsereOcorrenciaSmsInconsistente(
+		event.Recipient,
+		message.CliCodigo,
+		721, // Tipo de ocorrência para celular inconsistente
+		info.Source,
+	)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WebhookQueueResponse métricas da fila de ingestão assíncrona de webhooks
+type WebhookQueueResponse struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message,omitempty"`
+	Async   bool                `json:"async"`           // Ingestão assíncrona ativa ([webhook] async)
+	Queue   *webhook.QueueStats `json:"queue,omitempty"` // Métricas da fila (apenas com ingestão assíncrona)
+}
+
+// WebhookQueueStats godoc
+// @Summary Webhook ingestion queue metrics
+// @Description Returns the depth and counters of the asynchronous webhook ingestion queue ([webhook] async = true):
+// @Description pending events, events in flight, events waiting for a retry, age of the oldest pending event and
+// @Description the enqueued/processed/retried/failed counters since startup.
+// @Tags Administration
+// @Produce json
+// @Success 200 {object} WebhookQueueResponse "Queue metrics"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Router /connect/v1/admin/webhooks/queue [get]
+// @Security BearerAuth
+func WebhookQueueStats(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "WebhookQueueStats"
+
+		queue := webhook.GetQueue()
+		if queue == nil {
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusOK, WebhookQueueResponse{
+				Code:    "000",
+				Message: "Ingestão assíncrona de webhooks desabilitada.",
+				Async:   false,
+			})
+			return
+		}
+
+		stats := queue.Stats()
+		respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusOK, WebhookQueueResponse{
+			Code:  "000",
+			Async: true,
+			Queue: &stats,
+		})
+	}
+}
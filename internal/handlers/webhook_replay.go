@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Resultados do reprocessamento de eventos de webhook (models.WebhookReplayEventInfo.Resultado)
+const (
+	ReplayAplicado      = "aplicado"       // Evento aplicado
+	ReplaySimulado      = "simulado"       // Evento seria aplicado (dry-run)
+	ReplayDuplicado     = "duplicado"      // Evento já processado (WSWEBHOOKEVENTOS)
+	ReplayNaoEncontrado = "nao_encontrado" // Mensagem não encontrada
+	ReplayIgnorado      = "ignorado"       // Fora do escopo de tratamento
+	ReplayErro          = "erro"           // Payload inválido ou falha no banco
+)
+
+// Limites de requisições selecionadas por reprocessamento
+const (
+	WebhookReplayDefaultLimit = 100
+	WebhookReplayMaxLimit     = 1000
+)
+
+// ReplayWebhooks godoc
+// @Summary Replay webhook requests
+// @Description Selects webhook requests logged in WSREQUISICOES (by UUID, reception time range, endpoint and/or
+// @Description response code, at least one filter required) and runs the stored payloads (WSREQPARAMETROS) through
+// @Description the webhook pipeline again, in reception order. Events already applied are reported as `duplicado`
+// @Description and are not applied twice. Replayed events are applied synchronously: they bypass the ingestion queue
+// @Description and unmatched events are not stored in WSWEBHOOKPENDENTES.
+// @Description
+// @Description With `dry_run: true` nothing is written: each event is parsed, mapped and evaluated against the current
+// @Description message status, and the response reports the status, transition and occurrence it would produce.
+// @Description Requests are only logged when `[application] ws_grava_log_db` is enabled.
+// @Tags Administration
+// @Accept json
+// @Produce json
+// @Param body body models.WebhookReplayRequest true "Selection filter"
+// @Success 200 {object} models.WebhookReplayResponse "Replay result per request and event"
+// @Failure 400 {object} models.WebhookReplayResponse "Invalid filter"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 500 {object} models.WebhookReplayResponse "Database error"
+// @Router /connect/v1/admin/webhooks/replay [post]
+// @Security BearerAuth
+func ReplayWebhooks(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "ReplayWebhooks"
+
+		var req models.WebhookReplayRequest
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			err = json.Unmarshal(bodyBytes, &req)
+		}
+		reqParametros := string(bodyBytes)
+
+		if err != nil {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.WebhookReplayResponse{
+				Code:     "001",
+				Message:  "JSON inválido.",
+				Requests: []models.WebhookReplayRequestInfo{},
+			})
+			return
+		}
+
+		filter := database.WebhookRequestFilter{
+			UUID:        strings.TrimSpace(req.UUID),
+			De:          req.De,
+			Ate:         req.Ate,
+			Endpoint:    strings.TrimSpace(req.Endpoint),
+			CodResposta: req.CodResposta,
+			Limite:      req.Limit,
+		}
+		if msg := ValidateWebhookReplayFilter(&filter); msg != "" {
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusBadRequest, models.WebhookReplayResponse{
+				Code:     "001",
+				Message:  msg,
+				DryRun:   req.DryRun,
+				Requests: []models.WebhookReplayRequestInfo{},
+			})
+			return
+		}
+
+		requests, err := db.ListWebhookRequests(filter)
+		if err != nil {
+			logger.Errorw("Erro ao consultar requisições de webhook em WSREQUISICOES", "error", err)
+			respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusInternalServerError, models.WebhookReplayResponse{
+				Code:     "003",
+				Message:  "Falha ao consultar requisições de webhook.",
+				DryRun:   req.DryRun,
+				Requests: []models.WebhookReplayRequestInfo{},
+			})
+			return
+		}
+
+		results := ReplayWebhookRequests(c.Request.Context(), db, logger, requests, req.DryRun)
+		totals := WebhookReplayTotals(results)
+
+		if !req.DryRun {
+			auditAdmin(c, db, reqCtx, "WEBHOOK_REPLAY", filter.UUID, map[string]interface{}{
+				"filtro":      reqParametros,
+				"requisicoes": len(results),
+				"totais":      totals,
+			}, http.StatusOK)
+		}
+
+		respondWithLog(c, cfg, db, reqCtx, reqParametros, nomeProcedure, http.StatusOK, models.WebhookReplayResponse{
+			Code:     "000",
+			Message:  fmt.Sprintf("%d requisições reprocessadas", len(results)),
+			DryRun:   req.DryRun,
+			Totals:   totals,
+			Requests: results,
+		})
+	}
+}
+
+// ValidateWebhookReplayFilter valida o filtro do reprocessamento e aplica o limite padrão
+// Retorna a mensagem de erro de validação ("" se válido)
+func ValidateWebhookReplayFilter(filter *database.WebhookRequestFilter) string {
+	if filter.Limite == 0 {
+		filter.Limite = WebhookReplayDefaultLimit
+	}
+
+	switch {
+	case filter.UUID == "" && filter.De == nil && filter.Ate == nil && filter.Endpoint == "" && filter.CodResposta == 0:
+		return "Informe ao menos um filtro: uuid, de, ate, endpoint ou cod_resposta."
+	case filter.De != nil && filter.Ate != nil && filter.Ate.Before(*filter.De):
+		return "ate deve ser posterior a de."
+	case filter.Limite < 0 || filter.Limite > WebhookReplayMaxLimit:
+		return fmt.Sprintf("limit deve estar entre 1 e %d.", WebhookReplayMaxLimit)
+	}
+	return ""
+}
+
+// ReplayWebhookRequests reprocessa as requisições de webhook gravadas em WSREQUISICOES
+// O provedor é identificado pela procedure gravada (WSREQPROCEDURE); requisições rejeitadas antes
+// do handler (ex: autenticação) não têm provedor e são ignoradas. Em dryRun nada é gravado.
+func ReplayWebhookRequests(ctx context.Context, db *database.Database, logger *zap.SugaredLogger, requests []database.WebhookRequestData, dryRun bool) []models.WebhookReplayRequestInfo {
+	providers := make(map[string]webhook.Provider)
+	for _, provider := range webhook.Providers() {
+		providers[strings.ToUpper(provider.Info().Procedure)] = provider
+	}
+
+	results := make([]models.WebhookReplayRequestInfo, 0, len(requests))
+	for _, request := range requests {
+		result := models.WebhookReplayRequestInfo{
+			UUID:        request.UUID,
+			Data:        request.DataRecebe,
+			Endpoint:    request.Endpoint,
+			CodResposta: request.CodResposta,
+		}
+
+		provider, ok := providers[strings.ToUpper(request.Procedure)]
+		switch {
+		case !ok:
+			result.Resultado = ReplayIgnorado
+			result.Message = fmt.Sprintf("Procedure sem provedor de webhook: %s", request.Procedure)
+		case strings.TrimSpace(request.Parametros) == "":
+			result.Resultado = ReplayIgnorado
+			result.Message = "Payload não gravado em WSREQPARAMETROS"
+		default:
+			events, err := provider.Parse([]byte(request.Parametros))
+			if err != nil {
+				result.Resultado = ReplayErro
+				result.Message = "JSON inválido: " + err.Error()
+				break
+			}
+
+			logger.Infow("Reprocessando requisição de webhook",
+				"uuid", request.UUID,
+				"provider", provider.Info().Name,
+				"eventos", len(events),
+				"dry_run", dryRun)
+
+			for _, event := range events {
+				result.Events = append(result.Events, replayWebhookEvent(ctx, db, logger, provider, event, request.UUID, dryRun))
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// WebhookReplayTotals conta os eventos por resultado (requisições sem eventos contam pelo resultado da requisição)
+func WebhookReplayTotals(results []models.WebhookReplayRequestInfo) map[string]int {
+	totals := make(map[string]int)
+	for _, result := range results {
+		if result.Resultado != "" {
+			totals[result.Resultado]++
+		}
+		for _, event := range result.Events {
+			totals[event.Resultado]++
+		}
+	}
+	return totals
+}
+
+// replayWebhookEvent valida o evento como receiveWebhookEvent e o aplica de forma síncrona
+// (ou apenas o avalia em dryRun), sem fila assíncrona e sem gravação em WSWEBHOOKPENDENTES
+func replayWebhookEvent(ctx context.Context, db *database.Database, logger *zap.SugaredLogger, provider webhook.Provider, event *webhook.Event, uuid string, dryRun bool) models.WebhookReplayEventInfo {
+	info := provider.Info()
+	result := models.WebhookReplayEventInfo{
+		MessageID: event.MessageID,
+		Evento:    event.Code,
+	}
+
+	if tipoCallback := strings.ToLower(event.Type); info.EventType != "" && tipoCallback != info.EventType {
+		result.Resultado = ReplayIgnorado
+		result.Message = fmt.Sprintf("Tipo de mensagem não processado: %s", tipoCallback)
+		return result
+	}
+
+	status, ok := provider.Status(event)
+	if !ok {
+		result.Resultado = ReplayIgnorado
+		result.Message = fmt.Sprintf("Status não processado: %s", event.Code)
+		return result
+	}
+
+	if dryRun {
+		return previewWebhookEvent(db, provider, event, status, result)
+	}
+
+	response, err := applyWebhookEvent(ctx, db, logger, provider, event, uuid)
+	result.Message = response.Message
+	switch {
+	case errors.Is(err, webhook.ErrMessageNotFound):
+		result.Resultado = ReplayNaoEncontrado
+	case err != nil:
+		result.Resultado = ReplayErro
+		result.Message = err.Error()
+	case response.Duplicate:
+		result.Resultado = ReplayDuplicado
+	default:
+		result.Resultado = ReplayAplicado
+	}
+	return result
+}
+
+// previewWebhookEvent avalia o evento sem gravar: deduplicação, busca da mensagem e transição
+// a partir do status atual (mesmas regras de applyWebhookEvent)
+func previewWebhookEvent(db *database.Database, provider webhook.Provider, event *webhook.Event, status webhook.Status, result models.WebhookReplayEventInfo) models.WebhookReplayEventInfo {
+	info := provider.Info()
+
+	if event.MessageID != "" {
+		chave := webhook.EventKey(info.Name, event.MessageID, event.Code, event.Timestamp)
+		exists, err := db.WebhookEventExists(chave)
+		if err != nil {
+			result.Resultado = ReplayErro
+			result.Message = err.Error()
+			return result
+		}
+		if exists {
+			result.Resultado = ReplayDuplicado
+			result.Message = "Evento duplicado - já processado"
+			return result
+		}
+	}
+
+	message, err := provider.FindMessage(db, event)
+	if err != nil {
+		result.Resultado = ReplayNaoEncontrado
+		result.Message = "Mensagem não encontrada no banco de dados"
+		if !errors.Is(err, sql.ErrNoRows) {
+			result.Resultado = ReplayErro
+			result.Message = err.Error()
+		}
+		return result
+	}
+
+	estado, err := db.GetMessageStatus(message.LogsApiId)
+	if err != nil {
+		result.Resultado = ReplayErro
+		result.Message = err.Error()
+		return result
+	}
+
+	if status.HistoryOnly {
+		status.Codigo = estado.Status
+	}
+	transicao := webhook.EvaluateTransition(estado.Status, estado.DataEvento, status.Codigo, webhook.ParseEventTime(event.Timestamp))
+
+	result.Resultado = ReplaySimulado
+	result.Message = fmt.Sprintf("Mensagem %d (%s): status atual %d", message.Numero, info.Table, estado.Status)
+	result.Status = status.Codigo
+	result.Transicao = transicao.String()
+	result.Ocorrencia = status.Ocorrencia && transicao.Advances()
+	return result
+}
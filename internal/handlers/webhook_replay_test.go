@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wsicrmrest/internal/database"
+)
+
+// TestValidateWebhookReplayFilter garante que o replay exige um filtro e respeita os limites
+func TestValidateWebhookReplayFilter(t *testing.T) {
+	de := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	ate := de.Add(-time.Hour)
+
+	tests := []struct {
+		nome     string
+		filter   database.WebhookRequestFilter
+		valido   bool
+		esperado int // limite após a validação
+	}{
+		{"sem filtro", database.WebhookRequestFilter{}, false, WebhookReplayDefaultLimit},
+		{"uuid", database.WebhookRequestFilter{UUID: "uuid-1"}, true, WebhookReplayDefaultLimit},
+		{"código com limite", database.WebhookRequestFilter{CodResposta: 500, Limite: 10}, true, 10},
+		{"período invertido", database.WebhookRequestFilter{De: &de, Ate: &ate}, false, WebhookReplayDefaultLimit},
+		{"limite acima do máximo", database.WebhookRequestFilter{Endpoint: "/webhook/zenvia/sms", Limite: WebhookReplayMaxLimit + 1}, false, WebhookReplayMaxLimit + 1},
+	}
+
+	for _, tt := range tests {
+		filter := tt.filter
+		msg := ValidateWebhookReplayFilter(&filter)
+		if (msg == "") != tt.valido {
+			t.Errorf("%s: mensagem %q, esperado válido = %v", tt.nome, msg, tt.valido)
+		}
+		if filter.Limite != tt.esperado {
+			t.Errorf("%s: limite %d, esperado %d", tt.nome, filter.Limite, tt.esperado)
+		}
+	}
+}
+
+// TestReplayWebhookRequestsIgnored garante que requisições sem provedor ou sem payload não são processadas
+func TestReplayWebhookRequestsIgnored(t *testing.T) {
+	requests := []database.WebhookRequestData{
+		{UUID: "uuid-1", Endpoint: "/WEBHOOK/ZENVIA/EMAIL", CodResposta: 401, Procedure: "WebhookAuth", Parametros: "{}"},
+		{UUID: "uuid-2", Endpoint: "/WEBHOOK/ZENVIA/SMS", CodResposta: 200, Procedure: "ZenviaSMSWebhook"},
+		{UUID: "uuid-3", Endpoint: "/WEBHOOK/SENDGRID/EMAIL", CodResposta: 400, Procedure: "SendGridEmailWebhook", Parametros: "{"},
+	}
+
+	results := ReplayWebhookRequests(context.Background(), nil, nil, requests, true)
+	esperados := []string{ReplayIgnorado, ReplayIgnorado, ReplayErro}
+	for i, result := range results {
+		if result.Resultado != esperados[i] || len(result.Events) != 0 {
+			t.Errorf("%s: resultado %q com %d eventos, esperado %q", result.UUID, result.Resultado, len(result.Events), esperados[i])
+		}
+	}
+
+	totals := WebhookReplayTotals(results)
+	if totals[ReplayIgnorado] != 2 || totals[ReplayErro] != 1 {
+		t.Errorf("totais %v, esperado 2 ignorados e 1 erro", totals)
+	}
+}
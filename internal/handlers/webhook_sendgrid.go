@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SendGridEmailWebhook godoc
+// @Summary SendGrid Email Event Webhook
+// @Description Processes batched email events received from the SendGrid Event Webhook. Each element of the JSON array
+// @Description is matched by `sg_message_id` (the X-Message-Id returned on send) and applied to logsApi/logsApiHistorico.
+// @Description
+// @Description **Behavior for Unknown Message IDs:**
+// @Description - The event is skipped and logged; the batch still returns HTTP 200 (prevents SendGrid retries)
+// @Description - The webhook request is stored in WSREQUISICOES for audit
+// @Description - The event is stored in WSWEBHOOKPENDENTES and retried with backoff until `[webhook] dead_letter_max_age_hours`
+// @Description
+// @Description **Duplicate and out-of-order events:**
+// @Description Replayed events are not processed again, and the current message status only moves forward
+// @Description (121 → 122 → 123, 124/125 on failure); every event is appended to logsApiHistorico.
+// @Description
+// @Description **Supported Events:**
+// @Description - processed (121 - Agendado)
+// @Description - delivered (122 - Entregue)
+// @Description - open/click (123 - Aberto)
+// @Description - bounce (125 - Inconsistente, creates an occurrence and clears the customer's email)
+// @Description - blocked (bounce with type=blocked) and dropped (124 - Não Entregue)
+// @Description - spamreport/unsubscribe (history only, current status unchanged)
+// @Description
+// @Description **Security:** When `[webhook] sendgrid_public_keys` is configured, requests must carry a valid ECDSA
+// @Description signature in `X-Twilio-Email-Event-Webhook-Signature` over `X-Twilio-Email-Event-Webhook-Timestamp`
+// @Description plus the raw body. Rejections return 401 and count toward Fail2Ban.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param body body []models.SendGridEvent true "SendGrid event batch"
+// @Success 200 {object} models.WebhookResponse "Batch processed successfully (includes events whose message ID is not found)"
+// @Failure 400 {object} models.WebhookResponse "Invalid request body or JSON"
+// @Failure 401 {object} models.WebhookResponse "Invalid webhook signature"
+// @Failure 500 {object} models.WebhookResponse "Database error - event not applied, the provider should retry"
+// @Router /webhook/sendgrid/email [post]
+func SendGridEmailWebhook(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return WebhookHandler(cfg, db, logger, webhook.NewSendGridEmailProvider())
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WebhookStatusMapResponse regras de mapeamento de eventos de webhook para status do CRM
+type WebhookStatusMapResponse struct {
+	Code      string                     `json:"code"`
+	Message   string                     `json:"message,omitempty"`
+	StatusMap *webhook.StatusMapSnapshot `json:"status_map,omitempty"`
+}
+
+// WebhookStatusMap godoc
+// @Summary Webhook status mapping rules
+// @Description Returns the rules in use to map provider events to CRM statuses: built-in rules (source `padrao`)
+// @Description overridden or extended by the WSWEBHOOKSTATUS table (source `WSWEBHOOKSTATUS`). Each rule has the
+// @Description provider/channel, event code (or derived key such as `hard_bounce`), status (0 = history only),
+// @Description description template, history tag and whether it creates an inconsistent-contact occurrence.
+// @Tags Administration
+// @Produce json
+// @Success 200 {object} WebhookStatusMapResponse "Rules in use"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Router /connect/v1/admin/webhooks/status-map [get]
+// @Security BearerAuth
+func WebhookStatusMap(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+
+		snapshot := webhook.GetStatusMap().Snapshot()
+		respondWithLog(c, cfg, db, reqCtx, "", "WebhookStatusMap", http.StatusOK, WebhookStatusMapResponse{
+			Code:      "000",
+			StatusMap: &snapshot,
+		})
+	}
+}
+
+// ReloadWebhookStatusMap godoc
+// @Summary Reload webhook status mapping rules
+// @Description Reloads the WSWEBHOOKSTATUS table immediately instead of waiting for the periodic refresh
+// @Description ([webhook] status_map_refresh_seconds). Rules with a status outside the message lifecycle
+// @Description (0 or 121 to 125) are ignored. On failure the rules in use are kept.
+// @Tags Administration
+// @Produce json
+// @Success 200 {object} WebhookStatusMapResponse "Rules reloaded"
+// @Failure 401 {object} models.ErrorResponse "Missing or invalid Bearer token"
+// @Failure 403 {object} models.ErrorResponse "Token without 'sistema' scope"
+// @Failure 500 {object} WebhookStatusMapResponse "Database error"
+// @Router /connect/v1/admin/webhooks/status-map/reload [post]
+// @Security BearerAuth
+func ReloadWebhookStatusMap(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		nomeProcedure := "ReloadWebhookStatusMap"
+
+		statusMap := webhook.GetStatusMap()
+		regras, err := statusMap.Reload()
+		if err != nil {
+			logger.Errorw("Erro ao recarregar WSWEBHOOKSTATUS", "error", err)
+			auditAdmin(c, db, reqCtx, "WEBHOOK_STATUS_RECARREGAR", "WSWEBHOOKSTATUS", nil, http.StatusInternalServerError)
+			respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusInternalServerError, WebhookStatusMapResponse{
+				Code:    "003",
+				Message: "Erro ao recarregar o mapa de status. Regras anteriores mantidas.",
+			})
+			return
+		}
+
+		logger.Infow("Mapa de status de webhooks recarregado",
+			"regras_banco", regras,
+			"client_id", reqCtx.ClientID)
+		auditAdmin(c, db, reqCtx, "WEBHOOK_STATUS_RECARREGAR", "WSWEBHOOKSTATUS", map[string]interface{}{
+			"regras": regras,
+		}, http.StatusOK)
+
+		snapshot := statusMap.Snapshot()
+		respondWithLog(c, cfg, db, reqCtx, "", nomeProcedure, http.StatusOK, WebhookStatusMapResponse{
+			Code:      "000",
+			Message:   fmt.Sprintf("%d regras carregadas de WSWEBHOOKSTATUS.", regras),
+			StatusMap: &snapshot,
+		})
+	}
+}
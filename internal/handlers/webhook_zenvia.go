@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ZenviaEmailWebhook godoc
+// @Summary Zenvia Email Webhook
+// @Description Processes email status events received from Zenvia webhook. Updates email status, logs API history, and creates occurrences for bounced emails.
+// @Description
+// @Description **Behavior for Unknown Message IDs:**
+// @Description - Returns HTTP 200 with success=true (prevents Zenvia retries)
+// @Description - Logs warning with messageId and error details
+// @Description - Stores webhook request in WSREQUISICOES table for audit
+// @Description - Message: "Mensagem não encontrada no banco de dados"
+// @Description - Falls back to the `externalId` (CRM message code) when the Zenvia message ID does not match
+// @Description - Stores the event in WSWEBHOOKPENDENTES and retries it with backoff until `[webhook] dead_letter_max_age_hours`
+// @Description
+// @Description **Duplicate events:**
+// @Description Replays of an already processed event (same messageId, status code and event timestamp) return
+// @Description HTTP 200 with `duplicate: true` and are not processed again.
+// @Description
+// @Description **Out-of-order events:**
+// @Description Every event is appended to logsApiHistorico, but the current message status only moves forward
+// @Description (121 → 122 → 123, 124/125 on failure). Late or regressive events keep the current status and are
+// @Description flagged in the log.
+// @Description
+// @Description **Asynchronous ingestion:**
+// @Description With `[webhook] async = true` valid events are persisted to the local ingestion queue and acknowledged
+// @Description at once with HTTP 200 and `queued: true`; a worker pool applies them in order per message, retrying
+// @Description database failures with exponential backoff.
+// @Description
+// @Description **Supported Events:**
+// @Description - sent (121 - Agendado)
+// @Description - delivered (122 - Entregue)
+// @Description - read/clicked (123 - Aberto)
+// @Description - rejected/not_delivered (124 - Não Entregue)
+// @Description - rejected/not_delivered classified as a hard bounce by the `[bounce]` rules (125 - Inconsistente, creates an occurrence and clears the contact)
+// @Description
+// @Description **Security:** When `[webhook] zenvia_secrets` (or `secrets`) is configured, requests must carry one of the
+// @Description active secrets in the `X-Webhook-Token` header or `?token=` query parameter, or an HMAC-SHA256 of the
+// @Description body in `X-Webhook-Signature` (`sha256=<hex>`). Rejections return 401 and count toward Fail2Ban.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param body body models.ZenviaWebhookRequest true "Zenvia webhook payload"
+// @Success 200 {object} models.WebhookResponse "Webhook processed successfully (includes cases where message ID is not found)"
+// @Failure 400 {object} models.WebhookResponse "Invalid request body or JSON"
+// @Failure 401 {object} models.WebhookResponse "Invalid webhook token or signature"
+// @Failure 500 {object} models.WebhookResponse "Database error - event not applied, the provider should retry"
+// @Router /webhook/zenvia/email [post]
+func ZenviaEmailWebhook(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return WebhookHandler(cfg, db, logger, webhook.NewZenviaEmailProvider())
+}
+
+// ZenviaSMSWebhook godoc
+// @Summary Zenvia SMS Webhook
+// @Description Processes SMS status events received from Zenvia webhook. Updates SMS status, logs API history, and creates occurrences for bounced messages.
+// @Description
+// @Description **Behavior for Unknown Message IDs:**
+// @Description - Returns HTTP 200 with success=true (prevents Zenvia retries)
+// @Description - Logs warning with messageId and error details
+// @Description - Stores webhook request in WSREQUISICOES table for audit
+// @Description - Message: "Mensagem não encontrada no banco de dados"
+// @Description - Falls back to the `externalId` (CRM message code) when the Zenvia message ID does not match
+// @Description - Stores the event in WSWEBHOOKPENDENTES and retries it with backoff until `[webhook] dead_letter_max_age_hours`
+// @Description
+// @Description **Duplicate events:**
+// @Description Replays of an already processed event (same messageId, status code and event timestamp) return
+// @Description HTTP 200 with `duplicate: true` and are not processed again.
+// @Description
+// @Description **Out-of-order events:**
+// @Description Every event is appended to logsApiHistorico, but the current message status only moves forward
+// @Description (121 → 122 → 123, 124/125 on failure). Late or regressive events keep the current status and are
+// @Description flagged in the log.
+// @Description
+// @Description **Asynchronous ingestion:**
+// @Description With `[webhook] async = true` valid events are persisted to the local ingestion queue and acknowledged
+// @Description at once with HTTP 200 and `queued: true`; a worker pool applies them in order per message, retrying
+// @Description database failures with exponential backoff.
+// @Description
+// @Description **Supported Events:**
+// @Description - sent (121 - Agendado)
+// @Description - delivered (122 - Entregue)
+// @Description - read (123 - Aberto)
+// @Description - rejected/not_delivered (124 - Não Entregue)
+// @Description - rejected/not_delivered classified as a hard bounce by the `[bounce]` rules (125 - Inconsistente, creates an occurrence and clears the contact)
+// @Description
+// @Description **Security:** When `[webhook] zenvia_secrets` (or `secrets`) is configured, requests must carry one of the
+// @Description active secrets in the `X-Webhook-Token` header or `?token=` query parameter, or an HMAC-SHA256 of the
+// @Description body in `X-Webhook-Signature` (`sha256=<hex>`). Rejections return 401 and count toward Fail2Ban.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param body body models.ZenviaWebhookRequest true "Zenvia webhook payload"
+// @Success 200 {object} models.WebhookResponse "Webhook processed successfully (includes cases where message ID is not found)"
+// @Failure 400 {object} models.WebhookResponse "Invalid request body or JSON"
+// @Failure 401 {object} models.WebhookResponse "Invalid webhook token or signature"
+// @Failure 500 {object} models.WebhookResponse "Database error - event not applied, the provider should retry"
+// @Router /webhook/zenvia/sms [post]
+func ZenviaSMSWebhook(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return WebhookHandler(cfg, db, logger, webhook.NewZenviaSMSProvider())
+}
+
+// ZenviaWhatsAppWebhook godoc
+// @Summary Zenvia WhatsApp Webhook
+// @Description Processes WhatsApp status events received from Zenvia webhook. Updates the WhatsApp message status, logs API history, and creates occurrences for invalid numbers.
+// @Description
+// @Description **Behavior for Unknown Message IDs:**
+// @Description - Returns HTTP 200 with success=true (prevents Zenvia retries)
+// @Description - Logs warning with messageId and error details
+// @Description - Stores webhook request in WSREQUISICOES table for audit
+// @Description - Message: "Mensagem não encontrada no banco de dados"
+// @Description - Falls back to the `externalId` (CRM message code) when the Zenvia message ID does not match
+// @Description - Stores the event in WSWEBHOOKPENDENTES and retries it with backoff until `[webhook] dead_letter_max_age_hours`
+// @Description
+// @Description **Duplicate and out-of-order events:**
+// @Description Replayed events are not processed again, and the current message status only moves forward
+// @Description (121 → 122 → 123, 124/125 on failure); every event is appended to logsApiHistorico.
+// @Description
+// @Description **Supported Events:**
+// @Description - sent (121 - Agendado)
+// @Description - delivered (122 - Entregue)
+// @Description - read (123 - Lido, read receipt)
+// @Description - rejected/not_delivered classified as a hard bounce by `[bounce] whatsapp_hard`, e.g. invalid number (125 - Inconsistente, creates an occurrence and clears the customer's mobile number)
+// @Description - rejected/not_delivered with a template cause (124 - TemplateRejeitado)
+// @Description - rejected/not_delivered with a WhatsApp policy cause (124 - PoliticaWhatsApp)
+// @Description - other rejected/not_delivered (124 - Não Entregue)
+// @Description
+// @Description **Security:** Same `[webhook] zenvia_secrets` token/HMAC verification as the other Zenvia webhooks.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param body body models.ZenviaWebhookRequest true "Zenvia webhook payload"
+// @Success 200 {object} models.WebhookResponse "Webhook processed successfully (includes cases where message ID is not found)"
+// @Failure 400 {object} models.WebhookResponse "Invalid request body or JSON"
+// @Failure 401 {object} models.WebhookResponse "Invalid webhook token or signature"
+// @Failure 500 {object} models.WebhookResponse "Database error - event not applied, the provider should retry"
+// @Router /webhook/zenvia/whatsapp [post]
+func ZenviaWhatsAppWebhook(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return WebhookHandler(cfg, db, logger, webhook.NewZenviaWhatsAppProvider())
+}
@@ -6,6 +6,7 @@ import (
 	"wsicrmrest/internal/config"
 	reqcontext "wsicrmrest/internal/context"
 	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/middleware"
 	"wsicrmrest/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -14,21 +15,23 @@ import (
 
 // WSTest godoc
 // @Summary Test database connection
-// @Description Tests the database connection and returns organization information
+// @Description Tests the database connection and returns organization information.
+// @Description The Bearer token is optional; when sent it is validated and the calling application is logged.
 // @Tags System
 // @Accept json
 // @Produce json
 // @Success 200 {object} models.WSTestResponse "Connection successful"
+// @Failure 401 {object} models.ErrorResponse "Invalid Bearer token"
 // @Failure 403 {object} models.WSTestResponse "Database connection failed"
 // @Router /connect/v1/wsteste [get]
 func WSTest(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Criar contexto da requisição
-		reqCtx := reqcontext.NewRequestContext()
+		reqCtx := reqcontext.FromGin(c)
 
 		reqMetodo := "GET"
 		reqEndPoint := "/connect/v1/wsteste"
-		reqHeader := getHeadersAsString(c)
+		reqHeader := middleware.HeadersAsString(c)
 		reqParametros := ""
 		var reqResposta string
 		var codError int
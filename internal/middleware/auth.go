@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JWTAuth valida o token Bearer enviado no header Authorization
+// O token precisa estar registrado em WSAPLLOGTOKEN (emitido por esta API, não revogado).
+// Em caso de sucesso, client_id, aplicacao e scope são gravados no RequestContext
+// para que o GravaLogDB registre a aplicação chamadora.
+// Sem token Bearer, a aplicação mapeada ao certificado de cliente (middleware ClientCert)
+// é autenticada diretamente com o escopo de WSAPLSCOPO.
+func JWTAuth(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+		certApp := CertApplication(c)
+
+		authorization := utils.EliminaCaracterNulo(c.GetHeader("Authorization"))
+
+		if certApp != nil && authorization == "" {
+			if !checkIPAllowlist(c, cfg, db, logger, reqCtx, certApp.ClientID, certApp.Nome, utils.Escopo(certApp.Scopo)) {
+				return
+			}
+
+			reqCtx.SetTokenInfo(certApp.ClientID, certApp.Nome, utils.Escopo(certApp.Scopo))
+			c.Next()
+			return
+		}
+
+		if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+			rejectUnauthorized(c, cfg, db, reqCtx, models.ErrorResponse{
+				Code:    "010",
+				Message: "Token de acesso não informado. Utilize o header Authorization: Bearer <token>.",
+			})
+			return
+		}
+
+		token := strings.TrimSpace(authorization[7:])
+
+		claims, err := auth.ParseToken(cfg, token)
+		if err != nil {
+			logger.Warnw("Token Bearer rejeitado",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"error", err)
+
+			rejectUnauthorized(c, cfg, db, reqCtx, tokenErrorResponse(err))
+			return
+		}
+
+		// Token revogado (individualmente, por client_id ou por aplicação desabilitada)
+		if cache := auth.GetRevocationCache(); cache != nil && cache.IsRevoked(token) {
+			logger.Warnw("Token Bearer revogado utilizado",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"client_id", claims.ClientID)
+
+			rejectUnauthorized(c, cfg, db, reqCtx, models.ErrorResponse{
+				Code:    "016",
+				Message: "Token revogado.",
+			})
+			return
+		}
+
+		// Emissão em WSAPLLOGTOKEN: a assinatura sozinha não basta, o token precisa ter sido emitido
+		// por esta API para o mesmo client_id e não estar revogado (mesma regra do introspect)
+		tokenLog, err := lookupTokenLog(db, token)
+		if err != nil {
+			logger.Errorw("Erro ao consultar token em WSAPLLOGTOKEN", "error", err, "client_id", claims.ClientID)
+			abortWithLog(c, cfg, db, reqCtx, "JWTAuth", http.StatusInternalServerError, models.ErrorResponse{
+				Code:    "023",
+				Message: "Falha ao validar token de acesso.",
+			})
+			return
+		}
+		if tokenLog == nil || tokenLog.ClientID != claims.ClientID {
+			logger.Warnw("Token Bearer não emitido por esta API",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"client_id", claims.ClientID)
+
+			rejectUnauthorized(c, cfg, db, reqCtx, models.ErrorResponse{
+				Code:    "022",
+				Message: "Token de acesso não reconhecido.",
+			})
+			return
+		}
+		if tokenLog.Revogado {
+			rejectUnauthorized(c, cfg, db, reqCtx, models.ErrorResponse{
+				Code:    "016",
+				Message: "Token revogado.",
+			})
+			return
+		}
+
+		// Token de outra aplicação apresentado com certificado de cliente mapeado
+		if certApp != nil && certApp.ClientID != claims.ClientID {
+			logger.Warnw("Token Bearer não corresponde ao certificado de cliente",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"client_id", claims.ClientID,
+				"cert_client_id", certApp.ClientID)
+
+			abortWithLog(c, cfg, db, reqCtx, "JWTAuth", http.StatusForbidden, models.ErrorResponse{
+				Code:    "021",
+				Message: "Certificado de cliente não corresponde à aplicação do token.",
+			})
+			return
+		}
+
+		if !checkIPAllowlist(c, cfg, db, logger, reqCtx, claims.ClientID, claims.Aplicacao, claims.Scope) {
+			return
+		}
+
+		reqCtx.SetTokenInfo(claims.ClientID, claims.Aplicacao, claims.Scope)
+
+		c.Next()
+	}
+}
+
+// lookupTokenLog consulta a emissão do token em WSAPLLOGTOKEN (substituída nos testes)
+var lookupTokenLog = func(db *database.Database, token string) (*database.TokenLogData, error) {
+	return db.GetTokenLog(token)
+}
+
+// OptionalJWTAuth autentica a requisição apenas quando há credencial: com header Authorization
+// ou certificado de cliente mapeado, aplica as mesmas validações de JWTAuth (token inválido
+// continua retornando 401) e preenche o RequestContext para o GravaLogDB.
+// Sem credencial a requisição segue anônima.
+func OptionalJWTAuth(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	jwtAuth := JWTAuth(cfg, db, logger)
+
+	return func(c *gin.Context) {
+		if utils.EliminaCaracterNulo(c.GetHeader("Authorization")) == "" && CertApplication(c) == nil {
+			c.Next()
+			return
+		}
+
+		jwtAuth(c)
+	}
+}
+
+// checkIPAllowlist verifica o IP de origem contra a lista de IPs permitidos da aplicação (WSAPLIPPERMITIDOS)
+// Retorna false após encerrar a requisição com 403 (código 018)
+func checkIPAllowlist(c *gin.Context, cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, reqCtx *reqcontext.RequestContext, clientID, aplicacao, scope string) bool {
+	cache := auth.GetIPAllowlistCache()
+	if cache == nil || cache.Allowed(clientID, c.ClientIP()) {
+		return true
+	}
+
+	logger.Warnw("Acesso de IP não permitido para a aplicação",
+		"ip", c.ClientIP(),
+		"path", c.Request.URL.Path,
+		"client_id", clientID)
+
+	reqCtx.SetTokenInfo(clientID, aplicacao, scope)
+	RecordFail2BanAttempt(c, http.StatusForbidden)
+	abortWithLog(c, cfg, db, reqCtx, "JWTAuth", http.StatusForbidden, models.ErrorResponse{
+		Code:    "018",
+		Message: "IP de origem não permitido para a aplicação.",
+	})
+	return false
+}
+
+// tokenErrorResponse converte erros de validação em respostas com código estável
+func tokenErrorResponse(err error) models.ErrorResponse {
+	switch {
+	case errors.Is(err, auth.ErrTokenSignature):
+		return models.ErrorResponse{Code: "012", Message: "Assinatura do token inválida."}
+	case errors.Is(err, auth.ErrTokenIssuer):
+		return models.ErrorResponse{Code: "013", Message: "Emissor do token inválido."}
+	case errors.Is(err, auth.ErrTokenExpired):
+		return models.ErrorResponse{Code: "014", Message: "Token expirado."}
+	case errors.Is(err, auth.ErrTokenNotYetValid):
+		return models.ErrorResponse{Code: "015", Message: "Token ainda não é válido."}
+	case errors.Is(err, auth.ErrTokenKeyID):
+		return models.ErrorResponse{Code: "017", Message: "Chave de assinatura do token desconhecida."}
+	default:
+		return models.ErrorResponse{Code: "011", Message: "Token de acesso inválido."}
+	}
+}
+
+// rejectUnauthorized encerra a requisição com 401 e grava o log no banco
+func rejectUnauthorized(c *gin.Context, cfg *config.Config, db *database.Database, reqCtx *reqcontext.RequestContext, response models.ErrorResponse) {
+	c.Header("WWW-Authenticate", `Bearer realm="wsicrmrest", error="invalid_token"`)
+	abortWithLog(c, cfg, db, reqCtx, "JWTAuth", http.StatusUnauthorized, response)
+}
+
+// abortWithLog encerra a requisição com o código informado e grava o log em WSREQUISICOES
+func abortWithLog(c *gin.Context, cfg *config.Config, db *database.Database, reqCtx *reqcontext.RequestContext, nomeProcedure string, codError int, response interface{}) {
+	respJSON, _ := json.Marshal(response)
+
+	go db.GravaLogDB(
+		reqCtx.UUID,
+		c.Request.Method,
+		c.Request.URL.Path,
+		HeadersAsString(c),
+		"",
+		codError,
+		string(respJSON),
+		nomeProcedure,
+		reqCtx.ClientID,
+		reqCtx.NomeAplicacao,
+		reqCtx.StartTime,
+		c.ClientIP(),
+		cfg.Application.WSGravaLogDB,
+		cfg.Application.WSDetalheLogAPI,
+		reqCtx.DetalheLogAPI,
+		config.Version,
+	)
+
+	c.AbortWithStatusJSON(codError, response)
+}
+
+// sensitiveHeaders headers com credenciais, gravados mascarados em WSREQUISICOES
+var sensitiveHeaders = map[string]bool{
+	"Authorization":    true,
+	"Refresh_token":    true,
+	WebhookTokenHeader: true,
+}
+
+// HeadersAsString retorna todos os headers como string para o log da requisição
+// Credenciais (Authorization, Refresh_token, X-Webhook-Token) são mascaradas; do Authorization
+// apenas o esquema (Basic, Bearer) é mantido.
+func HeadersAsString(c *gin.Context) string {
+	var headers strings.Builder
+	for key, values := range c.Request.Header {
+		for _, value := range values {
+			if sensitiveHeaders[key] {
+				value = maskHeaderValue(key, value)
+			}
+			headers.WriteString(key + ": " + value + "\n")
+		}
+	}
+	return headers.String()
+}
+
+// maskHeaderValue mascara o valor do header sensível
+func maskHeaderValue(key, value string) string {
+	if key == "Authorization" {
+		if scheme, _, ok := strings.Cut(strings.TrimSpace(value), " "); ok {
+			return scheme + " ***"
+		}
+	}
+	return "***"
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// certApplicationKey chave do gin.Context com a aplicação mapeada ao certificado de cliente
+const certApplicationKey = "wsicrmrest.certApplication"
+
+// CertApplication retorna a aplicação mapeada ao certificado de cliente da requisição
+// (preenchida pelo middleware ClientCert) ou nil se não houver
+func CertApplication(c *gin.Context) *database.ApplicationData {
+	if value, ok := c.Get(certApplicationKey); ok {
+		if app, ok := value.(*database.ApplicationData); ok {
+			return app
+		}
+	}
+	return nil
+}
+
+// ClientCert identifica a aplicação pelo certificado de cliente (mTLS) apresentado no handshake
+// O certificado já foi validado contra [tls] client_ca_file; aqui ele é mapeado a uma aplicação
+// habilitada através de WSAPLCERTIFICADO (impressão digital SPKI ou subject).
+//
+// Modos (seção [mtls], por grupo de rotas):
+//   - off: certificados são ignorados
+//   - optional: sem certificado (ou sem mapeamento) a requisição segue para client_secret/Bearer
+//   - require: exige certificado mapeado a uma aplicação habilitada (401 código 019 / 403 código 021)
+func ClientCert(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, mode string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mode != config.MTLSOptional && mode != config.MTLSRequire {
+			c.Next()
+			return
+		}
+
+		reqCtx := reqcontext.FromGin(c)
+
+		if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			if mode == config.MTLSRequire {
+				abortWithLog(c, cfg, db, reqCtx, "ClientCert", http.StatusUnauthorized, models.ErrorResponse{
+					Code:    "019",
+					Message: "Certificado de cliente não informado.",
+				})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		cert := c.Request.TLS.PeerCertificates[0]
+		fingerprint := auth.CertificateFingerprint(cert)
+		subject := auth.CertificateSubject(cert)
+
+		app, err := db.GetApplicationByCertificate(fingerprint, subject)
+		if err != nil {
+			logger.Errorw("Erro ao buscar aplicação do certificado de cliente", "subject", subject, "error", err)
+		}
+
+		if app == nil {
+			logger.Warnw("Certificado de cliente não mapeado a uma aplicação habilitada",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"subject", subject,
+				"fingerprint", fingerprint)
+
+			if mode == config.MTLSRequire {
+				abortWithLog(c, cfg, db, reqCtx, "ClientCert", http.StatusForbidden, models.ErrorResponse{
+					Code:    "021",
+					Message: "Certificado de cliente não autorizado.",
+				})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Set(certApplicationKey, app)
+		reqCtx.SetClientInfo(app.ClientID, app.Nome)
+
+		c.Next()
+	}
+}
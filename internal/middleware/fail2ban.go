@@ -31,6 +31,7 @@ type IPAttempt struct {
 	totalAttempts int // Total de tentativas desde o início
 	firstAttempt  time.Time
 	lastAttempt   time.Time
+	banReason     string // Motivo do banimento manual (vazio = banido por tentativas)
 }
 
 // Fail2Ban gerencia bloqueios por IP
@@ -195,6 +196,7 @@ func (f *Fail2Ban) RecordAttempt(ip string, path string, statusCode int) {
 	if len(attempt.attempts) >= f.config.MaxAttempts {
 		attempt.banned = true
 		attempt.banExpiry = now.Add(f.config.BanDuration)
+		attempt.banReason = ""
 
 		f.logger.Warnw("IP banido por múltiplas tentativas suspeitas",
 			"ip", ip,
@@ -235,6 +237,19 @@ func (f *Fail2Ban) GetIPStats(ip string) map[string]interface{} {
 		"last_attempt":       attempt.lastAttempt,
 		"ban_expiry":         attempt.banExpiry,
 		"ban_time_remaining": remaining.String(),
+		"ban_reason":         attempt.banReason,
+	}
+}
+
+// fail2banRecordedKey indica que a tentativa da requisição já foi registrada explicitamente
+const fail2banRecordedKey = "wsicrmrest.fail2banRecorded"
+
+// RecordFail2BanAttempt registra explicitamente uma tentativa suspeita da requisição atual
+// (ex: IP fora da lista permitida da aplicação). O middleware não conta a mesma requisição novamente.
+func RecordFail2BanAttempt(c *gin.Context, statusCode int) {
+	c.Set(fail2banRecordedKey, true)
+	if f2b := GetFail2Ban(); f2b != nil {
+		f2b.RecordAttempt(c.ClientIP(), c.Request.URL.Path, statusCode)
 	}
 }
 
@@ -281,7 +296,9 @@ func Fail2BanMiddleware(config Fail2BanConfig, logger *zap.SugaredLogger) gin.Ha
 			statusCode == http.StatusUnauthorized ||
 			statusCode == http.StatusForbidden
 
-		if isSensitiveEndpoint && (statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden) {
+		_, alreadyRecorded := c.Get(fail2banRecordedKey)
+
+		if !alreadyRecorded && isSensitiveEndpoint && (statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden) {
 			f2b.RecordAttempt(ip, path, statusCode)
 		}
 	}
@@ -321,6 +338,7 @@ func (f *Fail2Ban) UnbanIP(ip string) bool {
 
 	attempt.banned = false
 	attempt.attempts = []time.Time{}
+	attempt.banReason = ""
 
 	f.logger.Infow("IP desbanido manualmente",
 		"ip", ip,
@@ -329,6 +347,47 @@ func (f *Fail2Ban) UnbanIP(ip string) bool {
 	return true
 }
 
+// BanIP bane manualmente um IP pelo período informado (para administradores)
+// duration <= 0 usa a duração padrão do banimento. IPs da whitelist não podem ser banidos.
+// Retorna a data de expiração do banimento e false se o IP estiver na whitelist.
+func (f *Fail2Ban) BanIP(ip string, duration time.Duration, reason string) (time.Time, bool) {
+	if f.isWhitelisted(ip) {
+		return time.Time{}, false
+	}
+
+	if duration <= 0 {
+		duration = f.config.BanDuration
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+
+	attempt, exists := f.ips[ip]
+	if !exists {
+		attempt = &IPAttempt{
+			attempts:     []time.Time{},
+			firstAttempt: now,
+			lastAttempt:  now,
+		}
+		f.ips[ip] = attempt
+	}
+
+	attempt.banned = true
+	attempt.banExpiry = now.Add(duration)
+	attempt.banReason = reason
+
+	f.logger.Warnw("IP banido manualmente",
+		"ip", ip,
+		"reason", reason,
+		"ban_duration", duration,
+		"ban_expiry", attempt.banExpiry,
+	)
+
+	return attempt.banExpiry, true
+}
+
 // GetBannedIPs retorna lista de IPs banidos
 func (f *Fail2Ban) GetBannedIPs() []string {
 	f.mu.RLock()
@@ -12,11 +12,13 @@ func Logger(logger *zap.SugaredLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
 
 		// Processar requisição
 		c.Next()
 
+		// Query lida após o processamento (WebhookAuth remove o token da URL)
+		query := c.Request.URL.RawQuery
+
 		// Calcular duração
 		duration := time.Since(start)
 
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+	"wsicrmrest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireScope exige que o token Bearer da requisição possua todos os bits de escopo informados
+// Deve ser registrado após JWTAuth, que preenche o escopo no RequestContext.
+// Negações retornam 403, são gravadas em WSREQUISICOES e contabilizadas pelo Fail2Ban
+// (o Fail2BanMiddleware registra toda resposta 403).
+func RequireScope(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, required int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := reqcontext.FromGin(c)
+
+		if reqCtx.ClientID == "" {
+			rejectUnauthorized(c, cfg, db, reqCtx, models.ErrorResponse{
+				Code:    "010",
+				Message: "Token de acesso não informado. Utilize o header Authorization: Bearer <token>.",
+			})
+			return
+		}
+
+		granted := utils.EscopoBits(reqCtx.Scope)
+		if granted&required != required {
+			logger.Warnw("Acesso negado - escopo insuficiente",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"client_id", reqCtx.ClientID,
+				"scope", reqCtx.Scope,
+				"required", utils.Escopo(required))
+
+			abortWithLog(c, cfg, db, reqCtx, "RequireScope", http.StatusForbidden, models.ErrorResponse{
+				Code:    "020",
+				Message: "Escopo insuficiente para acessar este recurso. Necessário: " + utils.Escopo(required) + ".",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Protected retorna a cadeia de middlewares de uma rota protegida:
+// validação do token Bearer seguida da verificação dos bits de escopo exigidos
+// (use 0 para exigir apenas um token válido)
+func Protected(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, required int64) []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		JWTAuth(cfg, db, logger),
+		RequireScope(cfg, db, logger, required),
+	}
+}
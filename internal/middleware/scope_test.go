@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"wsicrmrest/internal/testhelpers"
+	"wsicrmrest/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// TestProtected garante que a cadeia exige token válido e todos os bits de escopo declarados
+func TestProtected(t *testing.T) {
+	cfg := testhelpers.SetupTestConfig()
+	stubTokenLog(t)
+	chain := Protected(cfg, nil, zap.NewNop().Sugar(), utils.EscopoSistema)
+
+	tests := []struct {
+		nome          string
+		authorization string
+		status        int
+	}{
+		{"sem token", "", http.StatusUnauthorized},
+		{"escopo insuficiente", "Bearer " + testToken(t, cfg, "CLIENTE1", utils.EscopoClientes|utils.EscopoLojas), http.StatusForbidden},
+		{"escopo sistema forjado", "Bearer " + signTestToken(t, cfg, "CLIENTE3", utils.EscopoSistema), http.StatusUnauthorized},
+		{"escopo sistema", "Bearer " + testToken(t, cfg, "CLIENTE2", utils.EscopoClientes|utils.EscopoSistema), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		if status, _ := serveTest(chain, tt.authorization); status != tt.status {
+			t.Errorf("%s: status %d, esperado %d", tt.nome, status, tt.status)
+		}
+	}
+}
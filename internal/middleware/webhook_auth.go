@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io"
+	"net/http"
+	"time"
+	"wsicrmrest/internal/auth"
+	"wsicrmrest/internal/config"
+	reqcontext "wsicrmrest/internal/context"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// WebhookTokenHeader header com o token compartilhado do webhook (mascarado nos logs)
+	WebhookTokenHeader = "X-Webhook-Token"
+	// WebhookSignatureHeader header com o HMAC-SHA256 do body ("sha256=<hex>")
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// webhookTokenQuery parâmetro da URL com o token compartilhado
+	webhookTokenQuery = "token"
+
+	// SendGridSignatureHeader assinatura ECDSA (base64) do Signed Event Webhook da SendGrid
+	SendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	// SendGridTimestampHeader timestamp assinado junto com o body
+	SendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// WebhookAuth verifica a origem de um webhook com os secrets de [webhook] do provedor
+// Aceita o token compartilhado (header X-Webhook-Token ou ?token=) ou a assinatura
+// HMAC-SHA256 do body (header X-Webhook-Signature) com qualquer secret ativo.
+// Sem secrets configurados a verificação fica desabilitada.
+// Rejeições retornam 401, são gravadas em WSREQUISICOES e contabilizadas pelo Fail2Ban.
+func WebhookAuth(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger, provider string) gin.HandlerFunc {
+	secrets := cfg.Webhook.SecretsFor(provider)
+	if len(secrets) == 0 {
+		logger.Warnw("Verificação de webhook desabilitada - nenhum secret configurado em [webhook]",
+			"provider", provider)
+	}
+
+	return func(c *gin.Context) {
+		if len(secrets) == 0 {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(WebhookTokenHeader)
+		if token == "" && cfg.Webhook.AllowQueryToken {
+			token = c.Query(webhookTokenQuery)
+		}
+
+		// Token não deve aparecer nos logs de requisição
+		if query := c.Request.URL.Query(); query.Has(webhookTokenQuery) {
+			query.Del(webhookTokenQuery)
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		if auth.VerifyWebhookToken(secrets, token) {
+			c.Next()
+			return
+		}
+
+		if signature := c.GetHeader(WebhookSignatureHeader); signature != "" {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				// Body restaurado para o handler
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				if auth.VerifyWebhookSignature(secrets, body, signature) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.Warnw("Webhook rejeitado - token ou assinatura inválidos",
+			"provider", provider,
+			"ip", c.ClientIP(),
+			"path", c.Request.URL.Path)
+
+		abortWithLog(c, cfg, db, reqcontext.FromGin(c), "WebhookAuth", http.StatusUnauthorized, models.WebhookResponse{
+			Success: false,
+			Message: "Token ou assinatura do webhook inválidos.",
+		})
+	}
+}
+
+// SendGridAuth verifica a assinatura ECDSA do Signed Event Webhook da SendGrid
+// A assinatura (X-Twilio-Email-Event-Webhook-Signature) cobre o timestamp
+// (X-Twilio-Email-Event-Webhook-Timestamp) seguido do body e é verificada com as chaves de
+// [webhook] sendgrid_public_keys (várias chaves permitem a rotação). Sem chaves configuradas a
+// verificação fica desabilitada; chaves inválidas rejeitam todas as requisições.
+// Timestamps fora de [webhook] sendgrid_timestamp_tolerance_seconds são rejeitados (replay).
+// Rejeições retornam 401, são gravadas em WSREQUISICOES e contabilizadas pelo Fail2Ban.
+func SendGridAuth(cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) gin.HandlerFunc {
+	var keys []*ecdsa.PublicKey
+	for _, key := range cfg.Webhook.SendGridPublicKeys {
+		publicKey, err := auth.ParseWebhookPublicKey(key)
+		if err != nil {
+			logger.Errorw("Chave pública da SendGrid inválida em [webhook] sendgrid_public_keys", "error", err)
+			continue
+		}
+		keys = append(keys, publicKey)
+	}
+
+	tolerance := time.Duration(cfg.Webhook.SendGridTimestampTolerance) * time.Second
+	enabled := len(cfg.Webhook.SendGridPublicKeys) > 0
+	if !enabled {
+		logger.Warnw("Verificação do webhook SendGrid desabilitada - nenhuma chave em [webhook] sendgrid_public_keys")
+	}
+
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		timestamp := c.GetHeader(SendGridTimestampHeader)
+		if !auth.VerifyWebhookTimestamp(timestamp, time.Now(), tolerance) {
+			logger.Warnw("Webhook SendGrid rejeitado - timestamp fora da tolerância",
+				"ip", c.ClientIP(),
+				"path", c.Request.URL.Path,
+				"timestamp", timestamp)
+
+			abortWithLog(c, cfg, db, reqcontext.FromGin(c), "SendGridAuth", http.StatusUnauthorized, models.WebhookResponse{
+				Success: false,
+				Message: "Assinatura do webhook inválida.",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			// Body restaurado para o handler
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if auth.VerifyWebhookECDSA(keys, c.GetHeader(SendGridSignatureHeader), timestamp, body) {
+				c.Next()
+				return
+			}
+		}
+
+		logger.Warnw("Webhook SendGrid rejeitado - assinatura inválida",
+			"ip", c.ClientIP(),
+			"path", c.Request.URL.Path)
+
+		abortWithLog(c, cfg, db, reqcontext.FromGin(c), "SendGridAuth", http.StatusUnauthorized, models.WebhookResponse{
+			Success: false,
+			Message: "Assinatura do webhook inválida.",
+		})
+	}
+}
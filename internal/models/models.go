@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // TokenResponse representa a resposta da API de geração de token
 type TokenResponse struct {
 	Code        string `json:"code"`
@@ -10,6 +12,9 @@ type TokenResponse struct {
 	DateTime    int64  `json:"datetime,omitempty"`
 	Scope       string `json:"scope,omitempty"`
 	Modulos     int    `json:"modulos,omitempty"`
+
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in,omitempty"`
 }
 
 // WSTestResponse representa a resposta da API de teste
@@ -35,15 +40,27 @@ type Application struct {
 	Scopo        int64
 	Status       int
 	Nome         string
+
+	RefreshExpiracao int    // Validade do refresh token em segundos (0 = refresh desabilitado)
+	IPPermitidos     string // IPs/faixas CIDR permitidos (WSAPLIPPERMITIDOS, vazio = sem restrição)
 }
 
 // ZenviaWebhookRequest representa o payload recebido do webhook Zenvia para email
 type ZenviaWebhookRequest struct {
 	Type          string              `json:"type"`
+	Timestamp     string              `json:"timestamp"`
 	Message       ZenviaMessage       `json:"message"`
 	MessageStatus ZenviaMessageStatus `json:"messageStatus"`
 }
 
+// EventTimestamp retorna o timestamp do evento (do status ou, na ausência, do callback)
+func (r ZenviaWebhookRequest) EventTimestamp() string {
+	if r.MessageStatus.Timestamp != "" {
+		return r.MessageStatus.Timestamp
+	}
+	return r.Timestamp
+}
+
 // ZenviaMessage representa a mensagem no webhook
 type ZenviaMessage struct {
 	To         string `json:"to"`
@@ -53,6 +70,7 @@ type ZenviaMessage struct {
 
 // ZenviaMessageStatus representa o status da mensagem
 type ZenviaMessageStatus struct {
+	Timestamp   string              `json:"timestamp"`
 	Code        string              `json:"code"`
 	Description string              `json:"description"`
 	Causes      []ZenviaStatusCause `json:"causes"`
@@ -64,8 +82,192 @@ type ZenviaStatusCause struct {
 	Details string `json:"details"`
 }
 
-// ZenviaWebhookResponse representa a resposta do webhook
-type ZenviaWebhookResponse struct {
-	Success bool   `json:"success"`
+// SendGridEvent representa um evento do Event Webhook da SendGrid (enviados em lote, em um array JSON)
+type SendGridEvent struct {
+	Email       string `json:"email"`
+	Timestamp   int64  `json:"timestamp"`
+	Event       string `json:"event"`
+	SGEventID   string `json:"sg_event_id"`
+	SGMessageID string `json:"sg_message_id"`
+	Reason      string `json:"reason"`   // Motivo do bounce/dropped
+	Status      string `json:"status"`   // Código SMTP do bounce
+	Response    string `json:"response"` // Resposta do servidor de destino (delivered)
+	Type        string `json:"type"`     // Tipo do bounce: bounce ou blocked
+	URL         string `json:"url"`      // Link clicado (click)
+}
+
+// WebhookResponse representa a resposta dos webhooks de status
+type WebhookResponse struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty"` // Evento já processado (reenvio do provedor)
+	Queued    bool   `json:"queued,omitempty"`    // Evento gravado na fila de ingestão assíncrona
+}
+
+// WebhookPendingInfo representa um evento de webhook sem mensagem correspondente (WSWEBHOOKPENDENTES)
+type WebhookPendingInfo struct {
+	Numero           int64      `json:"numero"`
+	Provedor         string     `json:"provedor"`
+	MessageID        string     `json:"message_id"`
+	ExternalID       string     `json:"external_id,omitempty"`
+	Status           string     `json:"status"`
+	UUID             string     `json:"uuid"`
+	Data             time.Time  `json:"data"`
+	Tentativas       int        `json:"tentativas"`
+	ProximaTentativa *time.Time `json:"proxima_tentativa,omitempty"`
+	Situacao         int        `json:"situacao"` // 0=aguardando, 1=processado, 2=expirado
+	Erro             string     `json:"erro,omitempty"`
+	DataFim          *time.Time `json:"data_fim,omitempty"`
+}
+
+// WebhookPendingListResponse representa a lista de eventos pendentes de reprocessamento
+type WebhookPendingListResponse struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Pending []WebhookPendingInfo `json:"pending"`
+}
+
+// WebhookPendingResponse representa o resultado do reprocessamento de um evento pendente
+type WebhookPendingResponse struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message,omitempty"`
+	Pending *WebhookPendingInfo `json:"pending,omitempty"`
+}
+
+// WebhookReplayRequest representa o filtro das requisições de webhook a reprocessar (WSREQUISICOES)
+// Ao menos um filtro deve ser informado
+type WebhookReplayRequest struct {
+	UUID        string     `json:"uuid,omitempty"`         // WSREQUUID
+	De          *time.Time `json:"de,omitempty"`           // Recebidas a partir de (RFC 3339)
+	Ate         *time.Time `json:"ate,omitempty"`          // Recebidas até (RFC 3339)
+	Endpoint    string     `json:"endpoint,omitempty"`     // Ex: /webhook/zenvia/email
+	CodResposta int        `json:"cod_resposta,omitempty"` // Código HTTP da resposta original
+	Limit       int        `json:"limit,omitempty"`        // Máximo de requisições (padrão 100)
+	DryRun      bool       `json:"dry_run"`                // Apenas simula, sem gravar no banco
+}
+
+// WebhookReplayEventInfo representa o resultado do reprocessamento de um evento
+type WebhookReplayEventInfo struct {
+	MessageID  string `json:"message_id"`
+	Evento     string `json:"evento"`              // Código do evento no provedor
+	Resultado  string `json:"resultado"`           // aplicado, simulado, duplicado, nao_encontrado, ignorado ou erro
+	Message    string `json:"message,omitempty"`   // Detalhe do resultado
+	Status     int    `json:"status,omitempty"`    // Status do CRM (simulação)
+	Transicao  string `json:"transicao,omitempty"` // Transição do status atual (simulação)
+	Ocorrencia bool   `json:"ocorrencia,omitempty"`
+}
+
+// WebhookReplayRequestInfo representa o resultado do reprocessamento de uma requisição de webhook
+type WebhookReplayRequestInfo struct {
+	UUID        string                   `json:"uuid"`
+	Data        time.Time                `json:"data"`
+	Endpoint    string                   `json:"endpoint"`
+	CodResposta int                      `json:"cod_resposta"`
+	Resultado   string                   `json:"resultado,omitempty"` // ignorado ou erro quando o payload não é processado
+	Message     string                   `json:"message,omitempty"`
+	Events      []WebhookReplayEventInfo `json:"events,omitempty"`
+}
+
+// WebhookReplayResponse representa o resultado do reprocessamento de requisições de webhook
+type WebhookReplayResponse struct {
+	Code     string                     `json:"code"`
+	Message  string                     `json:"message,omitempty"`
+	DryRun   bool                       `json:"dry_run"`
+	Totals   map[string]int             `json:"totals,omitempty"` // Eventos por resultado
+	Requests []WebhookReplayRequestInfo `json:"requests"`
+}
+
+// ErrorResponse representa uma resposta de erro padrão da API
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// RevokeTokenRequest representa a requisição de revogação de um token
+type RevokeTokenRequest struct {
+	Token  string `json:"token,omitempty"`
+	Numero int64  `json:"numero,omitempty"`
+}
+
+// RevokeClientTokensRequest representa a requisição de revogação de todos os tokens de um client_id
+type RevokeClientTokensRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+// RevokeResponse representa a resposta das APIs de revogação
+type RevokeResponse struct {
+	Code    string `json:"code"`
 	Message string `json:"message,omitempty"`
+	Revoked int64  `json:"revoked"`
+}
+
+// IntrospectionResponse representa a resposta de introspecção de token (RFC 7662)
+// Tokens inativos retornam apenas {"active": false}
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Aplicacao string `json:"aplicacao,omitempty"`
+}
+
+// OAuthTokenResponse representa a resposta de token no formato RFC 6749 (POST /connect/v1/token)
+// expires_in é o tempo de vida do token em segundos
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthErrorResponse representa um erro no formato RFC 6749 (seção 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ApplicationRequest representa o corpo de criação/alteração de aplicação (WSAPLICACOES)
+// Campos omitidos na alteração mantêm o valor atual
+type ApplicationRequest struct {
+	ClientID         string  `json:"client_id,omitempty"`
+	Nome             string  `json:"nome,omitempty"`
+	Scopo            *int64  `json:"scopo,omitempty"`
+	JWTExpiracao     *int    `json:"jwt_expiracao,omitempty"`
+	RefreshExpiracao *int    `json:"refresh_expiracao,omitempty"`
+	Status           *int    `json:"status,omitempty"`
+	IPPermitidos     *string `json:"ip_permitidos,omitempty"` // IPs/CIDR separados por vírgula ("" remove a restrição)
+	Certificado      *string `json:"certificado,omitempty"`   // Certificado mTLS: "sha256:<SPKI hex>" ou subject ("" remove o mapeamento)
+}
+
+// ApplicationInfo representa uma aplicação retornada pela API de administração
+type ApplicationInfo struct {
+	ClientID         string `json:"client_id"`
+	Nome             string `json:"nome"`
+	Scopo            int64  `json:"scopo"`
+	Escopo           string `json:"escopo"`
+	JWTExpiracao     int    `json:"jwt_expiracao"`
+	RefreshExpiracao int    `json:"refresh_expiracao"`
+	Status           int    `json:"status"`
+	IPPermitidos     string `json:"ip_permitidos,omitempty"`
+	Certificado      string `json:"certificado,omitempty"`
+}
+
+// ApplicationResponse representa a resposta das operações sobre uma aplicação
+// ClientSecret só é retornado na criação e na rotação do secret
+type ApplicationResponse struct {
+	Code         string           `json:"code"`
+	Message      string           `json:"message,omitempty"`
+	Application  *ApplicationInfo `json:"application,omitempty"`
+	ClientSecret string           `json:"client_secret,omitempty"`
+	Revoked      int64            `json:"revoked,omitempty"`
+}
+
+// ApplicationListResponse representa a listagem de aplicações
+type ApplicationListResponse struct {
+	Code         string            `json:"code"`
+	Message      string            `json:"message,omitempty"`
+	Applications []ApplicationInfo `json:"applications"`
 }
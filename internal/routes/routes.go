@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"time"
+	"wsicrmrest/internal/auth"
 	"wsicrmrest/internal/config"
 	"wsicrmrest/internal/database"
 	"wsicrmrest/internal/handlers"
 	"wsicrmrest/internal/middleware"
+	"wsicrmrest/internal/utils"
+	"wsicrmrest/internal/webhook"
 
 	_ "wsicrmrest/docs/swagger" // Swagger docs
 
@@ -14,42 +18,161 @@ import (
 	"go.uber.org/zap"
 )
 
+// Bits de escopo (WSAPLSCOPO) exigidos pelos grupos de rotas protegidos
+// Em /connect/v1, token e introspect autenticam pelas credenciais da aplicação e wsteste aceita
+// token opcional; /webhook autentica pelo segredo do provedor. Esses grupos não exigem escopo.
+const (
+	scopeFail2Ban = utils.EscopoSistema // /connect/v1/fail2ban
+	scopeAdmin    = utils.EscopoSistema // /connect/v1/admin
+)
+
 // SetupRoutes configura todas as rotas da aplicação
+// Grupos protegidos declaram os bits de escopo (WSAPLSCOPO) exigidos através de
+// middleware.Protected (constantes scope* acima), que valida o token Bearer
+// e retorna 403 (código 020) quando o token não possui os escopos necessários.
+// Cada grupo aplica middleware.ClientCert com o modo de mTLS configurado em [mtls].
 func SetupRoutes(router *gin.Engine, cfg *config.Config, db *database.Database, logger *zap.SugaredLogger) {
 	// Middleware de logging
 	router.Use(middleware.Logger(logger))
 
-	// Grupo de rotas /connect
-	connectGroup := router.Group("/connect/v1")
+	// Limite de verificações argon2id simultâneas na emissão de tokens ([auth] secret_verify_concurrency)
+	auth.SetSecretVerifyConcurrency(cfg.Auth.SecretVerifyConcurrency)
+
+	// Cache de tokens revogados (consultado pelo middleware JWTAuth)
+	auth.NewRevocationCache(db, time.Duration(cfg.Auth.RevocationRefreshSeconds)*time.Second, logger)
+
+	// Cache de IPs permitidos por aplicação (consultado pelo middleware JWTAuth)
+	auth.NewIPAllowlistCache(db, time.Duration(cfg.Auth.IPAllowlistRefreshSeconds)*time.Second, logger)
+
+	// Deduplicação de eventos de webhook (WSWEBHOOKEVENTOS com cache em memória)
+	webhook.NewDeduplicator(db,
+		time.Duration(cfg.Webhook.DedupCacheMinutes)*time.Minute,
+		time.Duration(cfg.Webhook.DedupRetentionHours)*time.Hour,
+		logger)
+
+	// Classificação de bounce permanente/temporário (seção [bounce])
+	webhook.NewBounceClassifier(cfg.Bounce, logger)
+
+	// Mapa de eventos de webhook para status do CRM (regras padrão + WSWEBHOOKSTATUS)
+	webhook.NewStatusMap(db, time.Duration(cfg.Webhook.StatusMapRefreshSeconds)*time.Second, logger)
+
+	// Provedores de webhook de status (pipeline compartilhado em handlers.WebhookHandler)
+	webhookProviders := webhook.Providers()
+
+	// Eventos sem mensagem correspondente: WSWEBHOOKPENDENTES com reprocessamento periódico ([webhook] dead_letter)
+	if cfg.Webhook.DeadLetter {
+		deadLetterHandlers := make(map[string]webhook.DeadLetterHandler)
+		for _, provider := range webhookProviders {
+			deadLetterHandlers[provider.Info().Name] = handlers.WebhookDeadLetterHandler(db, logger, provider)
+		}
+		webhook.NewDeadLetter(db, cfg.Webhook, deadLetterHandlers, logger)
+	}
+
+	// Fila durável de ingestão assíncrona de webhooks ([webhook] async)
+	// Sem a fila os webhooks são processados de forma síncrona
+	if cfg.Webhook.Async {
+		processors := make(map[string]webhook.Processor)
+		for _, provider := range webhookProviders {
+			processors[provider.Info().Name] = handlers.WebhookProcessor(db, logger, provider)
+		}
+		if _, err := webhook.NewQueue(cfg.Webhook, processors, logger); err != nil {
+			logger.Errorw("Fila de ingestão de webhooks indisponível - processamento síncrono", "error", err)
+		}
+	}
+
+	// GET /.well-known/jwks.json - Chaves públicas de assinatura (RS256/ES256)
+	router.GET("/.well-known/jwks.json", handlers.JWKS())
+
+	// Grupo de rotas /connect (certificado de cliente conforme [mtls] connect)
+	connectGroup := router.Group("/connect/v1", middleware.ClientCert(cfg, db, logger, cfg.MTLS.Connect))
 	{
 		// GET /connect/v1/token - Gerar token JWT
 		connectGroup.GET("/token", handlers.GenerateToken(cfg, db, logger))
 
+		// POST /connect/v1/token - Gerar token JWT (OAuth2 client_credentials, form-urlencoded)
+		connectGroup.POST("/token", handlers.OAuthToken(cfg, db, logger))
+
+		// POST /connect/v1/introspect - Introspecção de token (RFC 7662)
+		connectGroup.POST("/introspect", handlers.IntrospectToken(cfg, db, logger))
+
 		// GET /connect/v1/wsteste - Teste de conexão
-		connectGroup.GET("/wsteste", handlers.WSTest(cfg, db, logger))
+		// Token Bearer opcional: quando informado, a aplicação chamadora é registrada em WSREQUISICOES
+		connectGroup.GET("/wsteste", middleware.OptionalJWTAuth(cfg, db, logger), handlers.WSTest(cfg, db, logger))
 	}
 
-	// Grupo de rotas /webhook
-	webhookGroup := router.Group("/webhook")
+	// Grupo de rotas /webhook (certificado de cliente conforme [mtls] webhook)
+	webhookGroup := router.Group("/webhook", middleware.ClientCert(cfg, db, logger, cfg.MTLS.Webhook))
 	{
+		// Token/assinatura dos webhooks Zenvia ([webhook] zenvia_secrets ou secrets)
+		zenviaAuth := middleware.WebhookAuth(cfg, db, logger, "zenvia")
+
 		// POST /webhook/zenvia/email - Webhook Zenvia para eventos de email
-		webhookGroup.POST("/zenvia/email", handlers.ZenviaEmailWebhook(cfg, db, logger))
+		webhookGroup.POST("/zenvia/email", zenviaAuth, handlers.ZenviaEmailWebhook(cfg, db, logger))
 
 		// POST /webhook/zenvia/sms - Webhook Zenvia para eventos de SMS
-		webhookGroup.POST("/zenvia/sms", handlers.ZenviaSMSWebhook(cfg, db, logger))
+		webhookGroup.POST("/zenvia/sms", zenviaAuth, handlers.ZenviaSMSWebhook(cfg, db, logger))
+
+		// POST /webhook/zenvia/whatsapp - Webhook Zenvia para eventos de WhatsApp
+		webhookGroup.POST("/zenvia/whatsapp", zenviaAuth, handlers.ZenviaWhatsAppWebhook(cfg, db, logger))
+
+		// POST /webhook/sendgrid/email - Event Webhook SendGrid (lote de eventos de email)
+		// Assinatura ECDSA verificada com [webhook] sendgrid_public_keys
+		webhookGroup.POST("/sendgrid/email", middleware.SendGridAuth(cfg, db, logger), handlers.SendGridEmailWebhook(cfg, db, logger))
 	}
 
-	// Grupo de rotas /connect/v1/fail2ban - Administração do Fail2Ban
-	fail2banGroup := router.Group("/connect/v1/fail2ban")
+	// Grupo de rotas /connect/v1/fail2ban - Administração do Fail2Ban (exige escopo "sistema")
+	// Mesmo modo de certificado de cliente do grupo admin ([mtls] admin)
+	fail2banGroup := router.Group("/connect/v1/fail2ban", middleware.ClientCert(cfg, db, logger, cfg.MTLS.Admin))
+	fail2banGroup.Use(middleware.Protected(cfg, db, logger, scopeFail2Ban)...)
 	{
 		// GET /connect/v1/fail2ban/status - Lista IPs banidos
-		fail2banGroup.GET("/status", handlers.Fail2BanGetStatus(logger))
+		fail2banGroup.GET("/status", handlers.Fail2BanGetStatus(cfg, db, logger))
 
 		// POST /connect/v1/fail2ban/unban - Desbanir IP manualmente
-		fail2banGroup.POST("/unban", handlers.Fail2BanUnbanIP(logger))
+		fail2banGroup.POST("/unban", handlers.Fail2BanUnbanIP(cfg, db, logger))
+
+		// POST /connect/v1/fail2ban/ban - Banir IP manualmente (motivo e duração)
+		fail2banGroup.POST("/ban", handlers.Fail2BanBanIP(cfg, db, logger))
 
 		// GET /connect/v1/fail2ban/ip/:ip - Estatísticas de IP específico
-		fail2banGroup.GET("/ip/:ip", handlers.Fail2BanGetIPStats(logger))
+		fail2banGroup.GET("/ip/:ip", handlers.Fail2BanGetIPStats(cfg, db, logger))
+	}
+
+	// Grupo de rotas /connect/v1/admin - Administração (exige escopo "sistema")
+	// O certificado de cliente ([mtls] admin) é verificado antes do token Bearer
+	adminGroup := router.Group("/connect/v1/admin", middleware.ClientCert(cfg, db, logger, cfg.MTLS.Admin))
+	adminGroup.Use(middleware.Protected(cfg, db, logger, scopeAdmin)...)
+	{
+		// POST /connect/v1/admin/tokens/revoke - Revogar um token
+		adminGroup.POST("/tokens/revoke", handlers.RevokeToken(cfg, db, logger))
+
+		// POST /connect/v1/admin/tokens/revoke-client - Revogar todos os tokens de um client_id
+		adminGroup.POST("/tokens/revoke-client", handlers.RevokeClientTokens(cfg, db, logger))
+
+		// Aplicações (WSAPLICACOES)
+		adminGroup.GET("/applications", handlers.ListApplications(cfg, db, logger))
+		adminGroup.POST("/applications", handlers.CreateApplication(cfg, db, logger))
+		adminGroup.GET("/applications/:client_id", handlers.GetApplication(cfg, db, logger))
+		adminGroup.PUT("/applications/:client_id", handlers.UpdateApplication(cfg, db, logger))
+		adminGroup.POST("/applications/:client_id/enable", handlers.SetApplicationStatus(cfg, db, logger, 1))
+		adminGroup.POST("/applications/:client_id/disable", handlers.SetApplicationStatus(cfg, db, logger, 0))
+		adminGroup.POST("/applications/:client_id/rotate-secret", handlers.RotateApplicationSecret(cfg, db, logger))
+
+		// GET /connect/v1/admin/webhooks/queue - Métricas da fila de ingestão de webhooks
+		adminGroup.GET("/webhooks/queue", handlers.WebhookQueueStats(cfg, db, logger))
+
+		// GET /connect/v1/admin/webhooks/status-map - Regras de mapeamento de status em uso
+		// POST /connect/v1/admin/webhooks/status-map/reload - Recarregar WSWEBHOOKSTATUS
+		adminGroup.GET("/webhooks/status-map", handlers.WebhookStatusMap(cfg, db, logger))
+		adminGroup.POST("/webhooks/status-map/reload", handlers.ReloadWebhookStatusMap(cfg, db, logger))
+
+		// GET /connect/v1/admin/webhooks/pending - Eventos sem mensagem correspondente (WSWEBHOOKPENDENTES)
+		// POST /connect/v1/admin/webhooks/pending/:numero/reprocess - Reprocessar um evento pendente
+		adminGroup.GET("/webhooks/pending", handlers.ListWebhookPending(cfg, db, logger))
+		adminGroup.POST("/webhooks/pending/:numero/reprocess", handlers.ReprocessWebhookPending(cfg, db, logger))
+
+		// POST /connect/v1/admin/webhooks/replay - Reprocessar requisições de webhook gravadas em WSREQUISICOES
+		adminGroup.POST("/webhooks/replay", handlers.ReplayWebhooks(cfg, db, logger))
 	}
 
 	// Swagger documentation
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"wsicrmrest/internal/auth"
 	"wsicrmrest/internal/config"
 	"wsicrmrest/internal/database"
 	"wsicrmrest/internal/logger"
@@ -91,6 +92,13 @@ func (ws *WindowsService) runHTTPServer() {
 	router := gin.New()
 	router.Use(gin.Recovery())
 
+	// Proxies confiáveis ([server] trusted_proxies); sem proxies o IP de origem é o da conexão
+	if err := router.SetTrustedProxies(ws.config.Server.TrustedProxies); err != nil {
+		ws.log.Error("Erro ao configurar proxies confiáveis", "error", err)
+		ws.serverDone <- fmt.Errorf("erro ao configurar proxies confiáveis: %w", err)
+		return
+	}
+
 	// Aplicar middlewares de segurança
 	router.Use(middleware.SecurityMiddleware(ws.config))
 
@@ -154,6 +162,17 @@ func (ws *WindowsService) runHTTPServer() {
 			return
 		}
 
+		// Habilitar verificação de certificados de cliente (mTLS), se configurado
+		if ws.config.TLS.ClientCAFile != "" {
+			if err := tlsloader.ConfigureClientCA(tlsConfig, ws.config.TLS.ClientCAFile); err != nil {
+				ws.log.Error("Erro ao carregar CA de clientes", "error", err)
+				ws.serverDone <- fmt.Errorf("erro ao carregar CA de clientes: %w", err)
+				return
+			}
+			ws.log.Info("🔐 mTLS habilitado", "client_ca_file", ws.config.TLS.ClientCAFile,
+				"connect", ws.config.MTLS.Connect, "admin", ws.config.MTLS.Admin, "webhook", ws.config.MTLS.Webhook)
+		}
+
 		// Criar servidor HTTP customizado com TLS
 		server := &http.Server{
 			Addr:      ":" + tlsPort,
@@ -278,6 +297,21 @@ func RunAsWindowsService() error {
 		"version_date", config.VersionDate,
 		"build_time", config.BuildTime)
 
+	// Carregar chaves de assinatura JWT (RS256/ES256)
+	keyStore, err := auth.LoadKeyStore(cfg)
+	if err != nil {
+		log.Error("Erro ao carregar chaves JWT", "error", err)
+		if elog != nil {
+			elog.Error(1, fmt.Sprintf("Erro ao carregar chaves JWT: %v", err))
+		}
+		return fmt.Errorf("erro ao carregar chaves JWT: %v", err)
+	}
+	log.Info("Assinatura JWT configurada",
+		"signing_algorithm", cfg.JWT.SigningAlgorithm,
+		"signing_kid", cfg.JWT.SigningKeyID,
+		"keys", len(keyStore.JWKS().Keys),
+		"legacy_hs256", cfg.JWT.LegacyHS256)
+
 	// Inicializar conexão com banco de dados
 	db, err := database.NewDatabase(cfg, log)
 	if err != nil {
@@ -304,6 +338,9 @@ func RunAsWindowsService() error {
 		"codigo", cfg.Organization.Codigo,
 		"nome", cfg.Organization.Nome)
 
+	// Estratégia de geração de chave de Ocorrencia e LogsApiHistorico (sequence, lock ou retry)
+	db.InitKeyAllocation()
+
 	// Log configurações CORS
 	if len(cfg.CORS.AllowedOrigins) == 0 {
 		log.Info("CORS configurado para permitir TODAS as origens (*) - Modo Desenvolvimento")
@@ -1,6 +1,7 @@
 package tls
 
 import (
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
@@ -33,12 +34,62 @@ func LoadEncryptedTLSConfig(certFile, keyFile, password string) (*tls.Config, er
 		return nil, fmt.Errorf("erro ao ler certificado %s: %w", certFile, err)
 	}
 
-	// Ler arquivo de chave privada
+	// Ler, descriptografar (se necessário) e parsear a chave privada
+	privateKey, err := LoadPrivateKey(keyFile, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsear certificado
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("falha ao decodificar bloco PEM do certificado")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao parsear certificado: %w", err)
+	}
+
+	// Criar tls.Certificate
+	tlsCert := tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  privateKey,
+	}
+
+	// Criar configuração TLS
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		MinVersion:   tls.VersionTLS12, // TLS 1.2 mínimo (segurança)
+		CipherSuites: []uint16{
+			// Cipher suites recomendadas (seguras e compatíveis)
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		},
+	}
+
+	return tlsConfig, nil
+}
+
+// LoadPrivateKey lê um arquivo PEM de chave privada (criptografada ou não) e retorna a chave parseada
+//
+// Suporta os mesmos formatos de LoadEncryptedTLSConfig (PKCS#1, PKCS#8 e EC, com ou sem senha).
+// Também é utilizada para carregar as chaves de assinatura JWT (RS256/ES256).
+func LoadPrivateKey(keyFile, password string) (crypto.PrivateKey, error) {
 	keyPEM, err := os.ReadFile(keyFile)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao ler chave privada %s: %w", keyFile, err)
 	}
 
+	return ParsePrivateKeyPEM(keyPEM, password)
+}
+
+// ParsePrivateKeyPEM decodifica um bloco PEM de chave privada (criptografada ou não)
+func ParsePrivateKeyPEM(keyPEM []byte, password string) (crypto.PrivateKey, error) {
 	// Decodificar bloco PEM da chave
 	keyBlock, _ := pem.Decode(keyPEM)
 	if keyBlock == nil {
@@ -53,7 +104,7 @@ func LoadEncryptedTLSConfig(certFile, keyFile, password string) (*tls.Config, er
 	if isEncrypted {
 		// Chave criptografada - requer senha
 		if password == "" {
-			return nil, fmt.Errorf("chave privada está criptografada mas nenhuma senha foi fornecida. Configure key_password em [tls] no dbinit.ini")
+			return nil, fmt.Errorf("chave privada está criptografada mas nenhuma senha foi fornecida. Configure key_password ([tls] ou [jwt]) no dbinit.ini")
 		}
 
 		// Descriptografar usando x509.DecryptPEMBlock (funciona para PKCS#1 e PKCS#8)
@@ -73,6 +124,7 @@ func LoadEncryptedTLSConfig(certFile, keyFile, password string) (*tls.Config, er
 
 	// Tentar parsear a chave descriptografada como diferentes formatos
 	var privateKey interface{}
+	var err error
 
 	// Tentar PKCS#8 primeiro (formato mais moderno)
 	privateKey, err = x509.ParsePKCS8PrivateKey(keyDER)
@@ -88,37 +140,27 @@ func LoadEncryptedTLSConfig(certFile, keyFile, password string) (*tls.Config, er
 		}
 	}
 
-	// Parsear certificado
-	certBlock, _ := pem.Decode(certPEM)
-	if certBlock == nil {
-		return nil, fmt.Errorf("falha ao decodificar bloco PEM do certificado")
-	}
+	return privateKey, nil
+}
 
-	cert, err := x509.ParseCertificate(certBlock.Bytes)
+// ConfigureClientCA habilita a verificação de certificados de cliente (mTLS)
+// usando as CAs do arquivo PEM informado.
+//
+// O handshake usa tls.VerifyClientCertIfGiven: clientes sem certificado continuam
+// sendo aceitos e a exigência (optional/require) é decidida por grupo de rotas
+// no middleware ClientCert. Certificados apresentados são sempre validados contra a CA.
+func ConfigureClientCA(tlsConfig *tls.Config, caFile string) error {
+	caPEM, err := os.ReadFile(caFile)
 	if err != nil {
-		return nil, fmt.Errorf("falha ao parsear certificado: %w", err)
+		return fmt.Errorf("erro ao ler CA de clientes %s: %w", caFile, err)
 	}
 
-	// Criar tls.Certificate
-	tlsCert := tls.Certificate{
-		Certificate: [][]byte{cert.Raw},
-		PrivateKey:  privateKey,
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("nenhum certificado válido encontrado em %s", caFile)
 	}
 
-	// Criar configuração TLS
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{tlsCert},
-		MinVersion:   tls.VersionTLS12, // TLS 1.2 mínimo (segurança)
-		CipherSuites: []uint16{
-			// Cipher suites recomendadas (seguras e compatíveis)
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-		},
-	}
-
-	return tlsConfig, nil
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return nil
 }
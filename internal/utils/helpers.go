@@ -38,6 +38,43 @@ func FormatDateTimeOracle(date time.Time, withMilliseconds bool) string {
 	return "TO_TIMESTAMP('" + dateStr + "', 'MM/DD/YYYY HH24:MI:SS')"
 }
 
+// Bits de escopo armazenados em WSAPLICACOES.WSAPLSCOPO
+const (
+	EscopoClientes  int64 = 1
+	EscopoLojas     int64 = 2
+	EscopoOfertas   int64 = 4
+	EscopoProdutos  int64 = 8
+	EscopoPontos    int64 = 16
+	EscopoPrivate   int64 = 32
+	EscopoConvenio  int64 = 64
+	EscopoGiftcard  int64 = 128
+	EscopoCobranca  int64 = 256
+	EscopoBasico    int64 = 512
+	EscopoSistema   int64 = 1024
+	EscopoTerceiros int64 = 2048
+	EscopoTotem     int64 = 4096
+)
+
+// escopoNomes associa cada bit de escopo ao nome gravado no token, na ordem do pgScopo
+var escopoNomes = []struct {
+	bit  int64
+	nome string
+}{
+	{EscopoClientes, "clientes"},
+	{EscopoLojas, "lojas"},
+	{EscopoOfertas, "ofertas"},
+	{EscopoProdutos, "produtos"},
+	{EscopoPontos, "pontos"},
+	{EscopoPrivate, "private"},
+	{EscopoConvenio, "convenio"},
+	{EscopoGiftcard, "giftcard"},
+	{EscopoCobranca, "cobranca"},
+	{EscopoBasico, "basico"},
+	{EscopoSistema, "sistema"},
+	{EscopoTerceiros, "terceiros"},
+	{EscopoTotem, "totem"},
+}
+
 // Escopo retorna a string de escopo baseada no código bitwise
 // Equivalente a pgScopo do WinDev
 func Escopo(codigo int64) string {
@@ -46,75 +83,30 @@ func Escopo(codigo int64) string {
 	}
 
 	scopes := []string{}
-
-	// Bit 1 (1) - clientes
-	if codigo&1 != 0 {
-		scopes = append(scopes, "clientes")
-	}
-
-	// Bit 2 (2) - lojas
-	if codigo&2 != 0 {
-		scopes = append(scopes, "lojas")
-	}
-
-	// Bit 3 (4) - ofertas
-	if codigo&4 != 0 {
-		scopes = append(scopes, "ofertas")
-	}
-
-	// Bit 4 (8) - produtos
-	if codigo&8 != 0 {
-		scopes = append(scopes, "produtos")
-	}
-
-	// Bit 5 (16) - pontos
-	if codigo&16 != 0 {
-		scopes = append(scopes, "pontos")
-	}
-
-	// Bit 6 (32) - private
-	if codigo&32 != 0 {
-		scopes = append(scopes, "private")
-	}
-
-	// Bit 7 (64) - convenio
-	if codigo&64 != 0 {
-		scopes = append(scopes, "convenio")
-	}
-
-	// Bit 8 (128) - giftcard
-	if codigo&128 != 0 {
-		scopes = append(scopes, "giftcard")
-	}
-
-	// Bit 9 (256) - cobranca
-	if codigo&256 != 0 {
-		scopes = append(scopes, "cobranca")
-	}
-
-	// Bit 10 (512) - basico
-	if codigo&512 != 0 {
-		scopes = append(scopes, "basico")
-	}
-
-	// Bit 11 (1024) - sistema
-	if codigo&1024 != 0 {
-		scopes = append(scopes, "sistema")
-	}
-
-	// Bit 12 (2048) - terceiros
-	if codigo&2048 != 0 {
-		scopes = append(scopes, "terceiros")
-	}
-
-	// Bit 13 (4096) - totem
-	if codigo&4096 != 0 {
-		scopes = append(scopes, "totem")
+	for _, escopo := range escopoNomes {
+		if codigo&escopo.bit != 0 {
+			scopes = append(scopes, escopo.nome)
+		}
 	}
 
 	return strings.Join(scopes, " ")
 }
 
+// EscopoBits converte a string de escopo do token (nomes separados por espaço)
+// de volta para o código bitwise. Nomes desconhecidos são ignorados.
+func EscopoBits(scope string) int64 {
+	var codigo int64
+	for _, nome := range strings.Fields(scope) {
+		for _, escopo := range escopoNomes {
+			if strings.EqualFold(nome, escopo.nome) {
+				codigo |= escopo.bit
+				break
+			}
+		}
+	}
+	return codigo
+}
+
 // RemoveBase64Padding remove padding de strings base64
 func RemoveBase64Padding(s string) string {
 	return strings.TrimRight(s, "=")
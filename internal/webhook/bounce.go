@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"strings"
+	"sync"
+	"wsicrmrest/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// BounceClass classificação de uma falha de entrega
+type BounceClass int
+
+const (
+	BounceUnknown BounceClass = iota // Motivo não reconhecido (tratado como temporário)
+	BounceSoft                       // Falha temporária (caixa cheia, timeout): contato mantido
+	BounceHard                       // Falha permanente (caixa ou número inexistente): contato inconsistente
+)
+
+// String nome da classificação (logs)
+func (b BounceClass) String() string {
+	switch b {
+	case BounceSoft:
+		return "soft"
+	case BounceHard:
+		return "hard"
+	default:
+		return "unknown"
+	}
+}
+
+// channelNames nome do canal nas chaves da seção [bounce]
+var channelNames = map[int]string{
+	ChannelEmail:    "email",
+	ChannelSMS:      "sms",
+	ChannelWhatsApp: "whatsapp",
+}
+
+// defaultHardBounce padrões de bounce permanente por canal
+var defaultHardBounce = map[int][]string{
+	ChannelEmail: {
+		"MAILBOX_NOT_FOUND", "MAILBOX_DOES_NOT_EXIST", "INVALID_EMAIL", "INVALID_ADDRESS",
+		"INVALID_RECIPIENT", "USER_UNKNOWN", "USER UNKNOWN", "NO SUCH USER", "DOES NOT EXIST",
+		"DOMAIN_NOT_FOUND", "5.1.1", "5.1.2",
+	},
+	ChannelSMS: {
+		"INVALID_NUMBER", "UNKNOWN_NUMBER", "NUMBER_NOT_FOUND", "INVALID_DESTINATION",
+		"INVALID_RECIPIENT", "UNKNOWN_SUBSCRIBER", "DEACTIVATED_NUMBER",
+	},
+	ChannelWhatsApp: {
+		"INVALID_NUMBER", "INVALID_RECIPIENT",
+	},
+}
+
+// defaultSoftBounce padrões de bounce temporário por canal
+var defaultSoftBounce = map[int][]string{
+	ChannelEmail: {
+		"MAILBOX_FULL", "MAILBOX FULL", "QUOTA", "TIMEOUT", "TEMPORARY", "TRY AGAIN", "4.2.2", "4.4.",
+	},
+	ChannelSMS: {
+		"TIMEOUT", "TEMPORARY", "ABSENT_SUBSCRIBER", "NETWORK_ERROR", "THROTTLED",
+	},
+	ChannelWhatsApp: {
+		"TIMEOUT", "TEMPORARY", "NETWORK_ERROR",
+	},
+}
+
+// BounceClassifier separa bounces permanentes (hard) de temporários (soft) pelo motivo da falha
+// Os padrões são comparados, sem diferenciar maiúsculas, com o motivo, a causa (motivo e
+// detalhes) e a descrição do evento. Padrões soft são avaliados primeiro: na dúvida o contato é mantido.
+type BounceClassifier struct {
+	hard map[int][]string
+	soft map[int][]string
+}
+
+var (
+	bounceClassifier     *BounceClassifier
+	bounceClassifierOnce sync.Once
+	defaultClassifier    = newBounceClassifier(config.BounceConfig{})
+)
+
+// NewBounceClassifier cria (uma única vez) o classificador com as regras da seção [bounce]
+// Canais sem regras configuradas usam os padrões embutidos
+func NewBounceClassifier(cfg config.BounceConfig, logger *zap.SugaredLogger) *BounceClassifier {
+	bounceClassifierOnce.Do(func() {
+		bounceClassifier = newBounceClassifier(cfg)
+
+		logger.Infow("Classificação de bounce inicializada",
+			"canais_hard_configurados", len(cfg.Hard),
+			"canais_soft_configurados", len(cfg.Soft))
+	})
+
+	return bounceClassifier
+}
+
+// GetBounceClassifier retorna o classificador configurado (regras padrão se não inicializado)
+func GetBounceClassifier() *BounceClassifier {
+	if bounceClassifier != nil {
+		return bounceClassifier
+	}
+	return defaultClassifier
+}
+
+// newBounceClassifier monta as regras de cada canal (configuradas ou padrão)
+func newBounceClassifier(cfg config.BounceConfig) *BounceClassifier {
+	c := &BounceClassifier{
+		hard: make(map[int][]string),
+		soft: make(map[int][]string),
+	}
+
+	for channel, name := range channelNames {
+		c.hard[channel] = upperAll(defaultHardBounce[channel])
+		if patterns, ok := cfg.Hard[name]; ok {
+			c.hard[channel] = upperAll(patterns)
+		}
+		c.soft[channel] = upperAll(defaultSoftBounce[channel])
+		if patterns, ok := cfg.Soft[name]; ok {
+			c.soft[channel] = upperAll(patterns)
+		}
+	}
+
+	return c
+}
+
+// Classify classifica a falha de entrega de um evento do canal
+func (c *BounceClassifier) Classify(channel int, event *Event) BounceClass {
+	text := strings.ToUpper(strings.Join([]string{event.Reason, event.Cause, event.Description}, " "))
+
+	if containsAny(text, c.soft[channel]) {
+		return BounceSoft
+	}
+	if containsAny(text, c.hard[channel]) {
+		return BounceHard
+	}
+	return BounceUnknown
+}
+
+// containsAny indica se o texto contém algum dos padrões
+func containsAny(text string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(text, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// upperAll converte os padrões para maiúsculas
+func upperAll(patterns []string) []string {
+	result := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		result[i] = strings.ToUpper(pattern)
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"testing"
+	"wsicrmrest/internal/config"
+)
+
+// TestBounceClassifier garante a separação de bounces permanentes e temporários
+func TestBounceClassifier(t *testing.T) {
+	classifier := newBounceClassifier(config.BounceConfig{
+		Hard: map[string][]string{"sms": {"número cancelado"}},
+	})
+
+	tests := []struct {
+		channel  int
+		event    Event
+		esperado BounceClass
+	}{
+		{ChannelEmail, Event{Cause: "MAILBOX_NOT_FOUND (Caixa inexistente)"}, BounceHard},
+		{ChannelEmail, Event{Description: "550 5.1.1 User unknown"}, BounceHard},
+		{ChannelEmail, Event{Cause: "MAILBOX_FULL (Caixa cheia)"}, BounceSoft},
+		{ChannelEmail, Event{Description: "452 4.2.2 mailbox full, user unknown"}, BounceSoft},
+		{ChannelEmail, Event{Cause: "SPAM (Conteúdo bloqueado)"}, BounceUnknown},
+		{ChannelWhatsApp, Event{Reason: "INVALID_NUMBER"}, BounceHard},
+		// Número sem WhatsApp não invalida o celular (segue válido para SMS)
+		{ChannelWhatsApp, Event{Reason: "RECIPIENT_NOT_ON_WHATSAPP"}, BounceUnknown},
+		// Regras configuradas substituem as padrão do canal
+		{ChannelSMS, Event{Cause: "DELIVERY_FAILED (Número cancelado)"}, BounceHard},
+		{ChannelSMS, Event{Reason: "INVALID_NUMBER"}, BounceUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := classifier.Classify(tt.channel, &tt.event); got != tt.esperado {
+			t.Errorf("canal %d %+v: %s, esperado %s", tt.channel, tt.event, got, tt.esperado)
+		}
+	}
+}
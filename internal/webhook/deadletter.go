@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"wsicrmrest/internal/config"
+	"wsicrmrest/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// ErrMessageNotFound a mensagem do evento não foi encontrada (o evento é gravado em WSWEBHOOKPENDENTES)
+var ErrMessageNotFound = errors.New("mensagem não encontrada no banco de dados")
+
+// deadLetterBatch eventos pendentes reprocessados a cada ciclo
+const deadLetterBatch = 100
+
+// DeadLetterHandler reaplica um evento pendente pelo pipeline do provedor
+// Retorna ErrMessageNotFound se a mensagem continua sem correspondência
+type DeadLetterHandler func(event *Event, uuid string) error
+
+// DeadLetter eventos de webhook cuja mensagem não foi encontrada (WSWEBHOOKPENDENTES)
+// O webhook costuma chegar antes do commit da mensagem pelo envio: os eventos são reprocessados
+// com backoff exponencial até serem aplicados ou atingirem a idade máxima.
+type DeadLetter struct {
+	db        *database.Database
+	logger    *zap.SugaredLogger
+	handlers  map[string]DeadLetterHandler // provedor -> pipeline
+	retryBase time.Duration
+	maxAge    time.Duration
+	mu        sync.Mutex // Um reprocessamento por vez (ciclo periódico e API de administração)
+}
+
+var (
+	deadLetter     *DeadLetter
+	deadLetterOnce sync.Once
+)
+
+// NewDeadLetter cria (uma única vez) o armazenamento de eventos pendentes e inicia o reprocessamento periódico
+func NewDeadLetter(db *database.Database, cfg config.WebhookConfig, handlers map[string]DeadLetterHandler, logger *zap.SugaredLogger) *DeadLetter {
+	deadLetterOnce.Do(func() {
+		deadLetter = &DeadLetter{
+			db:        db,
+			logger:    logger,
+			handlers:  handlers,
+			retryBase: time.Duration(cfg.DeadLetterRetrySeconds) * time.Second,
+			maxAge:    time.Duration(cfg.DeadLetterMaxAgeHours) * time.Hour,
+		}
+
+		go deadLetter.retryRoutine()
+
+		logger.Infow("Reprocessamento de eventos de webhook pendentes inicializado",
+			"retry_base", deadLetter.retryBase,
+			"max_age", deadLetter.maxAge)
+	})
+
+	return deadLetter
+}
+
+// GetDeadLetter retorna a instância singleton (nil se não inicializada)
+func GetDeadLetter() *DeadLetter {
+	return deadLetter
+}
+
+// Add grava um evento sem mensagem correspondente para reprocessamento
+func (d *DeadLetter) Add(event *Event, uuid string) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.db.InsertWebhookPending(database.WebhookPendingData{
+		Provedor:   event.Provider,
+		MessageID:  event.MessageID,
+		ExternalID: event.ExternalID,
+		Status:     event.Code,
+		Evento:     string(payload),
+		UUID:       uuid,
+	}, time.Now().Add(retryDelay(d.retryBase, 1)))
+}
+
+// retryRoutine reprocessa periodicamente os eventos com tentativa vencida
+func (d *DeadLetter) retryRoutine() {
+	ticker := time.NewTicker(d.retryBase)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.processDue()
+	}
+}
+
+// processDue reprocessa um lote de eventos com tentativa vencida
+func (d *DeadLetter) processDue() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	pending, err := d.db.ListDueWebhookPendings(now, deadLetterBatch)
+	if err != nil {
+		d.logger.Errorw("Falha ao consultar eventos de webhook pendentes", "error", err)
+		return
+	}
+
+	for i := range pending {
+		d.reprocess(&pending[i], now)
+	}
+}
+
+// Reprocess reprocessa imediatamente um evento pendente ou expirado (API de administração)
+// Retorna nil, nil se o evento não existir
+func (d *DeadLetter) Reprocess(numero int64) (*database.WebhookPendingData, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, err := d.db.GetWebhookPending(numero)
+	if err != nil || pending == nil {
+		return nil, err
+	}
+	if pending.Situacao == database.PendenteProcessado {
+		return pending, nil
+	}
+
+	if err := d.reprocess(pending, time.Now()); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// reprocess reaplica o evento e registra o resultado: processado, nova tentativa com backoff
+// ou expirado (idade máxima atingida). Eventos expirados reprocessados manualmente continuam
+// expirados se a mensagem não for encontrada.
+func (d *DeadLetter) reprocess(pending *database.WebhookPendingData, now time.Time) error {
+	pending.Tentativas++
+
+	var event Event
+	err := json.Unmarshal([]byte(pending.Evento), &event)
+	if err == nil {
+		handler, ok := d.handlers[pending.Provedor]
+		if !ok {
+			err = errors.New("provedor sem processador: " + pending.Provedor)
+		} else {
+			err = handler(&event, pending.UUID)
+		}
+	}
+
+	pending.Erro = ""
+	pending.ProximaTentativa = nil
+	switch {
+	case err == nil:
+		pending.Situacao = database.PendenteProcessado
+		d.logger.Infow("Evento de webhook pendente aplicado",
+			"numero", pending.Numero,
+			"provider", pending.Provedor,
+			"messageId", pending.MessageID,
+			"tentativas", pending.Tentativas)
+	case pending.Situacao == database.PendenteExpirado || now.Sub(pending.Data) >= d.maxAge:
+		pending.Situacao = database.PendenteExpirado
+		pending.Erro = err.Error()
+		d.logger.Warnw("Evento de webhook pendente expirado - mensagem não encontrada",
+			"numero", pending.Numero,
+			"provider", pending.Provedor,
+			"messageId", pending.MessageID,
+			"tentativas", pending.Tentativas,
+			"error", err)
+	default:
+		next := now.Add(retryDelay(d.retryBase, pending.Tentativas+1))
+		pending.ProximaTentativa = &next
+		pending.Erro = err.Error()
+	}
+
+	if err := d.db.UpdateWebhookPending(pending.Numero, pending.Tentativas, pending.ProximaTentativa, pending.Situacao, pending.Erro); err != nil {
+		d.logger.Errorw("Falha ao atualizar evento de webhook pendente", "numero", pending.Numero, "error", err)
+		return err
+	}
+	return nil
+}
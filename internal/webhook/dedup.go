@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+	"wsicrmrest/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// EventKey calcula a chave de deduplicação de um evento de webhook
+// (provedor, message id, código de status e timestamp do evento informado pelo provedor)
+func EventKey(provider, messageID, status, timestamp string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.ToLower(provider),
+		messageID,
+		strings.ToLower(status),
+		timestamp,
+	}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Deduplicator descarta eventos de webhook já processados (reenvios do provedor)
+// WSWEBHOOKEVENTOS é a fonte da verdade entre instâncias; o cache em memória evita
+// a ida ao banco para reenvios recentes.
+type Deduplicator struct {
+	db        *database.Database
+	logger    *zap.SugaredLogger
+	cacheTTL  time.Duration
+	retention time.Duration
+	seen      map[string]time.Time // chave -> expiração no cache
+	mu        sync.Mutex
+}
+
+var (
+	deduplicator     *Deduplicator
+	deduplicatorOnce sync.Once
+)
+
+// NewDeduplicator cria (uma única vez) o deduplicador e inicia a limpeza periódica
+func NewDeduplicator(db *database.Database, cacheTTL, retention time.Duration, logger *zap.SugaredLogger) *Deduplicator {
+	deduplicatorOnce.Do(func() {
+		deduplicator = &Deduplicator{
+			db:        db,
+			logger:    logger,
+			cacheTTL:  cacheTTL,
+			retention: retention,
+			seen:      make(map[string]time.Time),
+		}
+
+		go deduplicator.cleanupRoutine()
+
+		logger.Infow("Deduplicação de eventos de webhook inicializada",
+			"cache_ttl", cacheTTL,
+			"retention", retention)
+	})
+
+	return deduplicator
+}
+
+// GetDeduplicator retorna a instância singleton (nil se não inicializada)
+func GetDeduplicator() *Deduplicator {
+	return deduplicator
+}
+
+// cleanupRoutine remove do cache as chaves expiradas e purga WSWEBHOOKEVENTOS
+func (d *Deduplicator) cleanupRoutine() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+	for now := range ticker.C {
+		d.mu.Lock()
+		for key, expiry := range d.seen {
+			if now.After(expiry) {
+				delete(d.seen, key)
+			}
+		}
+		d.mu.Unlock()
+
+		if now.Sub(lastPurge) >= time.Hour {
+			lastPurge = now
+			if purged, err := d.db.PurgeWebhookEvents(now.Add(-d.retention)); err != nil {
+				d.logger.Errorw("Falha ao purgar eventos de webhook", "error", err)
+			} else if purged > 0 {
+				d.logger.Infow("Eventos de webhook antigos removidos", "quantidade", purged)
+			}
+		}
+	}
+}
+
+// Seen indica se o evento foi aplicado recentemente (cache em memória, sem ida ao banco)
+func (d *Deduplicator) Seen(event *database.WebhookEventData) bool {
+	if !d.keyEvent(event) {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expiry, ok := d.seen[event.Chave]
+	return ok && time.Now().Before(expiry)
+}
+
+// Claim registra o evento em WSWEBHOOKEVENTOS na transação tx e retorna true se ele já havia sido
+// registrado (duplicado). Executar dentro do WithTx que grava o evento: se a transação for desfeita
+// o registro também é, e um reenvio do provedor é processado novamente. Uma requisição simultânea
+// com o mesmo evento aguarda o commit desta e recebe a violação de chave única (duplicado).
+// Falhas no banco não bloqueiam o processamento: o evento é tratado como novo.
+func (d *Deduplicator) Claim(tx *database.Database, event *database.WebhookEventData) bool {
+	if !d.keyEvent(event) {
+		return false
+	}
+
+	registered, err := tx.RegisterWebhookEvent(*event)
+	if err != nil {
+		d.logger.Errorw("Falha ao registrar evento de webhook - processando sem deduplicação",
+			"provedor", event.Provedor,
+			"messageId", event.MessageID,
+			"error", err)
+		return false
+	}
+
+	return !registered
+}
+
+// Remember grava o evento no cache em memória (após o commit da transação que o registrou)
+func (d *Deduplicator) Remember(event *database.WebhookEventData) {
+	if !d.keyEvent(event) {
+		return
+	}
+
+	d.mu.Lock()
+	d.seen[event.Chave] = time.Now().Add(d.cacheTTL)
+	d.mu.Unlock()
+}
+
+// keyEvent calcula a chave do evento; retorna false para eventos sem message id (não deduplicados)
+func (d *Deduplicator) keyEvent(event *database.WebhookEventData) bool {
+	if event.MessageID == "" {
+		return false
+	}
+	if event.Chave == "" {
+		event.Chave = EventKey(event.Provedor, event.MessageID, event.Status, event.DataEvento)
+	}
+	return true
+}
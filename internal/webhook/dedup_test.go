@@ -0,0 +1,24 @@
+package webhook
+
+import "testing"
+
+// TestEventKey garante que a chave diferencia provedor, mensagem, status e timestamp
+func TestEventKey(t *testing.T) {
+	base := EventKey("zenvia-email", "msg-1", "delivered", "2025-11-26T14:30:00Z")
+
+	if base != EventKey("ZENVIA-EMAIL", "msg-1", "DELIVERED", "2025-11-26T14:30:00Z") {
+		t.Error("chave deveria ignorar maiúsculas/minúsculas em provedor e status")
+	}
+
+	distintas := []string{
+		EventKey("zenvia-sms", "msg-1", "delivered", "2025-11-26T14:30:00Z"),
+		EventKey("zenvia-email", "msg-2", "delivered", "2025-11-26T14:30:00Z"),
+		EventKey("zenvia-email", "msg-1", "read", "2025-11-26T14:30:00Z"),
+		EventKey("zenvia-email", "msg-1", "delivered", "2025-11-26T14:31:00Z"),
+	}
+	for _, key := range distintas {
+		if key == base {
+			t.Errorf("chave %s deveria ser diferente de %s", key, base)
+		}
+	}
+}
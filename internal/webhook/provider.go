@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"wsicrmrest/internal/database"
+)
+
+// Canais de mensagem do CRM (logsApi.logsapitipmensagem)
+const (
+	ChannelEmail    = 1
+	ChannelSMS      = 2
+	ChannelWhatsApp = 3
+)
+
+// Event evento de status normalizado, independente do provedor
+type Event struct {
+	Provider    string `json:"provider"`              // Provedor e canal (ex: zenvia-email)
+	Type        string `json:"type,omitempty"`        // Tipo de callback informado pelo provedor
+	MessageID   string `json:"message_id"`            // ID da mensagem no provedor
+	ExternalID  string `json:"external_id,omitempty"` // ID informado no envio
+	Recipient   string `json:"recipient,omitempty"`   // Email ou celular do destinatário
+	Code        string `json:"code"`                  // Código do status no provedor (minúsculo)
+	Description string `json:"description,omitempty"` // Descrição do status
+	Cause       string `json:"cause,omitempty"`       // Causa da falha de entrega, ex: "motivo (detalhes)"
+	Reason      string `json:"reason,omitempty"`      // Código do motivo da falha informado pelo provedor
+	Timestamp   string `json:"timestamp,omitempty"`   // Timestamp do evento informado pelo provedor
+	Raw         string `json:"raw"`                   // Payload original do evento (gravado em logsApi)
+}
+
+// Message mensagem do CRM correspondente a um evento
+type Message struct {
+	Numero    int // emsgcodigo / smscodigo / wppcodigo
+	CliCodigo int
+	LogsApiId int
+}
+
+// Status status do CRM correspondente a um evento
+type Status struct {
+	Codigo    int    // logsApiStatus
+	Descricao string // Descrição gravada em logsApiHistorico
+	Tag       string // Tag gravada em logsApiHistorico
+	// Ocorrencia gera a ocorrência de contato inconsistente (e a limpeza do contato)
+	Ocorrencia bool
+	// HistoryOnly evento registrado apenas no histórico, sem alterar o status atual
+	// (ex: denúncia de spam e descadastro)
+	HistoryOnly bool
+}
+
+// ProviderInfo identificação de um provedor de webhook
+type ProviderInfo struct {
+	Name      string // Provedor e canal (deduplicação, fila e logs), ex: zenvia-email
+	Label     string // Nome nos logs, ex: WebHookZenvia Email
+	Procedure string // Procedure gravada em WSREQUISICOES
+	Channel   int    // Canal do CRM (ChannelEmail, ChannelSMS, ChannelWhatsApp)
+	Table     string // Tabela de mensagens do canal (logs)
+	EventType string // Tipo de callback tratado (vazio = qualquer tipo)
+	Source    string // Provedor registrado nas ocorrências de contato inconsistente
+}
+
+// Provider provedor de webhook de status de mensagens
+// O pipeline compartilhado (handlers.WebhookHandler) cuida do log da requisição, da resposta,
+// da deduplicação, da fila assíncrona e da gravação no banco; o provedor apenas interpreta o
+// payload, normaliza os eventos, localiza a mensagem e mapeia o status do CRM.
+type Provider interface {
+	// Info identifica o provedor
+	Info() ProviderInfo
+	// Parse interpreta o body recebido e retorna os eventos normalizados
+	Parse(body []byte) ([]*Event, error)
+	// Status mapeia o evento para o status do CRM (false para eventos fora do escopo)
+	Status(event *Event) (Status, bool)
+	// FindMessage localiza a mensagem do CRM (sql.ErrNoRows se não encontrada)
+	FindMessage(db *database.Database, event *Event) (*Message, error)
+}
+
+// Providers provedores de webhook de status suportados (rotas, fila, pendentes e replay)
+func Providers() []Provider {
+	return []Provider{
+		NewZenviaEmailProvider(),
+		NewZenviaSMSProvider(),
+		NewZenviaWhatsAppProvider(),
+		NewSendGridEmailProvider(),
+	}
+}
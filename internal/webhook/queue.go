@@ -0,0 +1,564 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wsicrmrest/internal/config"
+
+	"go.uber.org/zap"
+)
+
+const (
+	queueFileName       = "webhook-queue.log"
+	queueFailedFileName = "webhook-queue-failed.log"
+
+	// queueMaxRetryDelay limite do backoff exponencial entre tentativas
+	queueMaxRetryDelay = 15 * time.Minute
+	// queueCompactThreshold registros obsoletos no arquivo que disparam a compactação
+	queueCompactThreshold = 1000
+	// queueMaxRecordSize tamanho máximo de uma linha do arquivo da fila
+	queueMaxRecordSize = 16 * 1024 * 1024
+)
+
+// Operações gravadas no arquivo da fila (append log)
+const (
+	queueOpAdd    = "add"    // evento recebido (ou estado completo após compactação)
+	queueOpRetry  = "retry"  // nova tentativa agendada
+	queueOpDone   = "done"   // evento processado
+	queueOpFailed = "failed" // evento descartado após esgotar as tentativas
+	queueOpSeq    = "seq"    // próximo ID (preserva a sequência após a compactação)
+)
+
+// Processor aplica um evento da fila
+// Erro indica falha transitória: o evento é reprocessado com backoff exponencial
+type Processor func(event *QueuedEvent) error
+
+// QueuedEvent evento de webhook persistido na fila de ingestão
+type QueuedEvent struct {
+	ID          uint64    `json:"id"`
+	Provider    string    `json:"provider"`
+	MessageID   string    `json:"message_id"`
+	UUID        string    `json:"uuid"` // UUID da requisição que recebeu o evento (WSREQUISICOES)
+	Payload     string    `json:"payload"`
+	ReceivedAt  time.Time `json:"received_at"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// orderKey eventos com a mesma chave são processados em ordem de chegada, um de cada vez
+func (e *QueuedEvent) orderKey() string {
+	if e.MessageID == "" {
+		return "#" + strconv.FormatUint(e.ID, 10)
+	}
+	return e.Provider + "|" + e.MessageID
+}
+
+// queueRecord linha do arquivo da fila
+type queueRecord struct {
+	Op    string       `json:"op"`
+	ID    uint64       `json:"id"`
+	Event *QueuedEvent `json:"event,omitempty"`
+}
+
+// QueueStats métricas da fila de ingestão
+type QueueStats struct {
+	Depth         int    `json:"depth"`          // Eventos pendentes (inclui os em processamento)
+	InFlight      int    `json:"in_flight"`      // Eventos em processamento
+	Retrying      int    `json:"retrying"`       // Eventos aguardando nova tentativa
+	OldestSeconds int64  `json:"oldest_seconds"` // Idade do evento pendente mais antigo
+	Workers       int    `json:"workers"`
+	Enqueued      uint64 `json:"enqueued"`  // Recebidos desde a inicialização
+	Processed     uint64 `json:"processed"` // Processados desde a inicialização
+	Retried       uint64 `json:"retried"`   // Tentativas que falharam e foram reagendadas
+	Failed        uint64 `json:"failed"`    // Descartados após esgotar as tentativas
+}
+
+// Queue fila durável de ingestão de webhooks
+// Os eventos são gravados (fsync) em um append log local antes da resposta ao provedor e
+// processados por um pool de workers. Eventos da mesma mensagem são processados em ordem,
+// um de cada vez; falhas são reagendadas com backoff exponencial. Na inicialização o
+// arquivo é relido e os eventos pendentes são retomados.
+type Queue struct {
+	path        string
+	failedPath  string
+	file        *os.File
+	logger      *zap.SugaredLogger
+	workers     int
+	maxAttempts int
+	retryBase   time.Duration
+	processors  map[string]Processor
+
+	pending  map[uint64]*QueuedEvent
+	inFlight map[string]uint64 // chave de ordenação -> ID do evento em processamento
+	nextID   uint64
+	obsolete int // registros no arquivo que não representam mais o estado atual
+	mu       sync.Mutex
+
+	jobs chan *QueuedEvent
+	wake chan struct{}
+
+	enqueued  uint64
+	processed uint64
+	retried   uint64
+	failed    uint64
+}
+
+var (
+	queue     *Queue
+	queueOnce sync.Once
+)
+
+// NewQueue cria (uma única vez) a fila de ingestão, retoma os eventos pendentes e inicia os workers
+// processors associa cada provedor (ex: zenvia-email) à função que aplica seus eventos
+func NewQueue(cfg config.WebhookConfig, processors map[string]Processor, logger *zap.SugaredLogger) (*Queue, error) {
+	var err error
+	queueOnce.Do(func() {
+		var q *Queue
+		q, err = openQueue(cfg, processors, logger)
+		if err != nil {
+			return
+		}
+		q.start()
+		queue = q
+
+		logger.Infow("Fila de ingestão de webhooks inicializada",
+			"arquivo", q.path,
+			"workers", q.workers,
+			"max_attempts", q.maxAttempts,
+			"retry_base", q.retryBase,
+			"pendentes", len(q.pending))
+	})
+
+	return queue, err
+}
+
+// GetQueue retorna a instância singleton (nil se a ingestão assíncrona não estiver ativa)
+func GetQueue() *Queue {
+	return queue
+}
+
+// openQueue abre o arquivo da fila e reconstrói os eventos pendentes
+func openQueue(cfg config.WebhookConfig, processors map[string]Processor, logger *zap.SugaredLogger) (*Queue, error) {
+	if err := os.MkdirAll(cfg.QueueDir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório da fila %s: %w", cfg.QueueDir, err)
+	}
+
+	q := &Queue{
+		path:        filepath.Join(cfg.QueueDir, queueFileName),
+		failedPath:  filepath.Join(cfg.QueueDir, queueFailedFileName),
+		logger:      logger,
+		workers:     cfg.Workers,
+		maxAttempts: cfg.QueueMaxAttempts,
+		retryBase:   time.Duration(cfg.QueueRetrySeconds) * time.Second,
+		processors:  processors,
+		pending:     make(map[uint64]*QueuedEvent),
+		inFlight:    make(map[string]uint64),
+		nextID:      1,
+		jobs:        make(chan *QueuedEvent, cfg.Workers),
+		wake:        make(chan struct{}, 1),
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	// Compactar na abertura descarta os eventos já concluídos e eventuais linhas incompletas
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// load relê o arquivo da fila
+// Uma linha inválida (ex: gravação interrompida por queda do processo) é ignorada
+func (q *Queue) load() error {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao abrir fila %s: %w", q.path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), queueMaxRecordSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec queueRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			q.logger.Warnw("Registro inválido na fila de webhooks ignorado", "linha", line, "error", err)
+			continue
+		}
+
+		if rec.Op != queueOpSeq && rec.ID >= q.nextID {
+			q.nextID = rec.ID + 1
+		}
+
+		switch rec.Op {
+		case queueOpAdd, queueOpRetry:
+			if rec.Event != nil {
+				q.pending[rec.ID] = rec.Event
+			}
+		case queueOpDone, queueOpFailed:
+			delete(q.pending, rec.ID)
+		case queueOpSeq:
+			if rec.ID > q.nextID {
+				q.nextID = rec.ID
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("erro ao ler fila %s: %w", q.path, err)
+	}
+
+	return nil
+}
+
+// compact reescreve o arquivo apenas com os eventos pendentes
+// Deve ser chamado com q.mu bloqueado (ou antes do início dos workers)
+func (q *Queue) compact() error {
+	tmpPath := q.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("erro ao criar %s: %w", tmpPath, err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	encoder.Encode(queueRecord{Op: queueOpSeq, ID: q.nextID})
+	for _, id := range q.pendingIDs() {
+		encoder.Encode(queueRecord{Op: queueOpAdd, ID: id, Event: q.pending[id]})
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao gravar %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao gravar %s: %w", tmpPath, err)
+	}
+	tmp.Close()
+
+	// O arquivo atual precisa estar fechado para o rename no Windows
+	if q.file != nil {
+		q.file.Close()
+		q.file = nil
+	}
+
+	renameErr := os.Rename(tmpPath, q.path)
+
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir fila %s: %w", q.path, err)
+	}
+	q.file = file
+
+	if renameErr != nil {
+		return fmt.Errorf("erro ao compactar fila %s: %w", q.path, renameErr)
+	}
+
+	q.obsolete = 0
+	return nil
+}
+
+// pendingIDs retorna os IDs pendentes em ordem de chegada
+func (q *Queue) pendingIDs() []uint64 {
+	ids := make([]uint64, 0, len(q.pending))
+	for id := range q.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// appendRecord grava um registro no arquivo da fila (com q.mu bloqueado)
+// sync força a gravação em disco antes de retornar
+func (q *Queue) appendRecord(rec queueRecord, sync bool) error {
+	if q.file == nil {
+		return fmt.Errorf("fila fechada")
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if sync {
+		return q.file.Sync()
+	}
+	return nil
+}
+
+// Enqueue persiste o evento na fila e retorna o ID atribuído
+// Ao retornar sem erro o evento já está gravado em disco e será processado mesmo após um reinício
+func (q *Queue) Enqueue(provider, messageID, uuid string, payload []byte) (uint64, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	event := &QueuedEvent{
+		ID:          q.nextID,
+		Provider:    provider,
+		MessageID:   messageID,
+		UUID:        uuid,
+		Payload:     string(payload),
+		ReceivedAt:  now,
+		NextAttempt: now,
+	}
+
+	if err := q.appendRecord(queueRecord{Op: queueOpAdd, ID: event.ID, Event: event}, true); err != nil {
+		q.mu.Unlock()
+		return 0, fmt.Errorf("erro ao gravar evento na fila: %w", err)
+	}
+
+	q.nextID++
+	q.pending[event.ID] = event
+	q.mu.Unlock()
+
+	atomic.AddUint64(&q.enqueued, 1)
+	q.notify()
+
+	return event.ID, nil
+}
+
+// notify acorda o dispatcher
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start inicia o dispatcher e os workers
+func (q *Queue) start() {
+	for i := 0; i < q.workers; i++ {
+		go q.worker()
+	}
+	go q.dispatch()
+}
+
+// dispatch entrega aos workers os eventos prontos para processamento
+// Acorda a cada novo evento, a cada evento concluído e a cada segundo (fim do backoff)
+func (q *Queue) dispatch() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.wake:
+		case <-ticker.C:
+		}
+
+		for _, event := range q.ready(time.Now()) {
+			q.jobs <- event
+		}
+	}
+}
+
+// ready seleciona os eventos que podem ser processados agora e os marca como em processamento
+// Apenas o evento mais antigo de cada mensagem é elegível, preservando a ordem de chegada.
+// O total em processamento nunca excede o número de workers (o envio para jobs não bloqueia).
+func (q *Queue) ready(now time.Time) []*QueuedEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	free := q.workers - len(q.inFlight)
+	if free <= 0 {
+		return nil
+	}
+
+	var events []*QueuedEvent
+	seen := make(map[string]bool)
+	for _, id := range q.pendingIDs() {
+		event := q.pending[id]
+		key := event.orderKey()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, busy := q.inFlight[key]; busy || now.Before(event.NextAttempt) {
+			continue
+		}
+
+		q.inFlight[key] = id
+		events = append(events, event)
+		if len(events) == free {
+			break
+		}
+	}
+
+	return events
+}
+
+// worker processa os eventos entregues pelo dispatcher
+func (q *Queue) worker() {
+	for event := range q.jobs {
+		q.complete(event, q.process(event), time.Now())
+	}
+}
+
+// process executa o processador do provedor do evento
+func (q *Queue) process(event *QueuedEvent) (err error) {
+	processor, ok := q.processors[event.Provider]
+	if !ok {
+		return fmt.Errorf("provedor sem processador registrado: %s", event.Provider)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic ao processar evento: %v", r)
+		}
+	}()
+
+	return processor(event)
+}
+
+// complete registra o resultado do processamento de um evento
+func (q *Queue) complete(event *QueuedEvent, err error, now time.Time) {
+	q.mu.Lock()
+	defer q.notify()
+	defer q.mu.Unlock()
+
+	delete(q.inFlight, event.orderKey())
+
+	var rec queueRecord
+	if err == nil {
+		delete(q.pending, event.ID)
+		rec = queueRecord{Op: queueOpDone, ID: event.ID}
+		atomic.AddUint64(&q.processed, 1)
+	} else {
+		event.Attempts++
+		event.LastError = err.Error()
+
+		if event.Attempts >= q.maxAttempts {
+			delete(q.pending, event.ID)
+			rec = queueRecord{Op: queueOpFailed, ID: event.ID}
+			atomic.AddUint64(&q.failed, 1)
+
+			q.logger.Errorw("Evento de webhook descartado após esgotar as tentativas",
+				"id", event.ID,
+				"provider", event.Provider,
+				"messageId", event.MessageID,
+				"uuid", event.UUID,
+				"tentativas", event.Attempts,
+				"error", err)
+			q.writeFailed(event)
+		} else {
+			event.NextAttempt = now.Add(retryDelay(q.retryBase, event.Attempts))
+			rec = queueRecord{Op: queueOpRetry, ID: event.ID, Event: event}
+			atomic.AddUint64(&q.retried, 1)
+
+			q.logger.Warnw("Falha ao processar evento de webhook - nova tentativa agendada",
+				"id", event.ID,
+				"provider", event.Provider,
+				"messageId", event.MessageID,
+				"tentativa", event.Attempts,
+				"proxima", event.NextAttempt,
+				"error", err)
+		}
+	}
+
+	// done/retry não exigem fsync: após uma queda o evento é reprocessado (a deduplicação evita efeito duplo)
+	if err := q.appendRecord(rec, false); err != nil {
+		q.logger.Errorw("Falha ao gravar registro na fila de webhooks", "id", event.ID, "op", rec.Op, "error", err)
+	}
+
+	q.obsolete++
+	if q.obsolete >= queueCompactThreshold && q.obsolete > len(q.pending) {
+		if err := q.compact(); err != nil {
+			q.logger.Errorw("Falha ao compactar fila de webhooks", "error", err)
+		}
+	}
+}
+
+// writeFailed preserva o evento descartado em webhook-queue-failed.log para reprocessamento manual
+func (q *Queue) writeFailed(event *QueuedEvent) {
+	f, err := os.OpenFile(q.failedPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		q.logger.Errorw("Falha ao gravar evento descartado", "arquivo", q.failedPath, "error", err)
+		return
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(event); err != nil {
+		q.logger.Errorw("Falha ao gravar evento descartado", "arquivo", q.failedPath, "error", err)
+	}
+}
+
+// retryDelay calcula o backoff exponencial da tentativa (base, 2x base, 4x base... até 15 minutos)
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= queueMaxRetryDelay {
+			return queueMaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Stats retorna as métricas atuais da fila
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	stats := QueueStats{
+		Depth:    len(q.pending),
+		InFlight: len(q.inFlight),
+		Workers:  q.workers,
+	}
+
+	now := time.Now()
+	var oldest time.Time
+	for _, event := range q.pending {
+		if event.Attempts > 0 {
+			stats.Retrying++
+		}
+		if oldest.IsZero() || event.ReceivedAt.Before(oldest) {
+			oldest = event.ReceivedAt
+		}
+	}
+	q.mu.Unlock()
+
+	if !oldest.IsZero() {
+		stats.OldestSeconds = int64(now.Sub(oldest).Seconds())
+	}
+	stats.Enqueued = atomic.LoadUint64(&q.enqueued)
+	stats.Processed = atomic.LoadUint64(&q.processed)
+	stats.Retried = atomic.LoadUint64(&q.retried)
+	stats.Failed = atomic.LoadUint64(&q.failed)
+
+	return stats
+}
+
+// Close fecha o arquivo da fila
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wsicrmrest/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// TestRetryDelay garante o backoff exponencial limitado a queueMaxRetryDelay
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		esperado time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{20, queueMaxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(5*time.Second, tt.attempts); got != tt.esperado {
+			t.Errorf("retryDelay(5s, %d) = %v, esperado %v", tt.attempts, got, tt.esperado)
+		}
+	}
+}
+
+// TestQueueOrderAndResume garante a ordem por mensagem e a retomada dos pendentes após reabrir a fila
+func TestQueueOrderAndResume(t *testing.T) {
+	cfg := config.WebhookConfig{
+		QueueDir:          t.TempDir(),
+		Workers:           4,
+		QueueMaxAttempts:  3,
+		QueueRetrySeconds: 5,
+	}
+	logger := zap.NewNop().Sugar()
+
+	q, err := openQueue(cfg, nil, logger)
+	if err != nil {
+		t.Fatalf("openQueue: %v", err)
+	}
+
+	q.Enqueue("zenvia-email", "msg-1", "uuid-1", []byte(`{"status":"sent"}`))
+	q.Enqueue("zenvia-email", "msg-1", "uuid-2", []byte(`{"status":"delivered"}`))
+	q.Enqueue("zenvia-email", "msg-2", "uuid-3", []byte(`{"status":"sent"}`))
+
+	now := time.Now()
+	ready := q.ready(now)
+	if len(ready) != 2 || ready[0].UUID != "uuid-1" || ready[1].UUID != "uuid-3" {
+		t.Fatalf("esperado apenas o primeiro evento de cada mensagem, obtido %d eventos", len(ready))
+	}
+
+	// Falha no primeiro evento de msg-1: o segundo aguarda o backoff
+	q.complete(ready[0], errors.New("banco indisponível"), now)
+	q.complete(ready[1], nil, now)
+	if got := q.ready(now); len(got) != 0 {
+		t.Errorf("esperado nenhum evento pronto durante o backoff, obtido %d", len(got))
+	}
+	q.Close()
+
+	// Reabrir a fila retoma os pendentes com as tentativas já realizadas
+	q, err = openQueue(cfg, nil, logger)
+	if err != nil {
+		t.Fatalf("openQueue: %v", err)
+	}
+	defer q.Close()
+
+	if len(q.pending) != 2 {
+		t.Fatalf("esperado 2 eventos pendentes, obtido %d", len(q.pending))
+	}
+	if q.pending[1].Attempts != 1 {
+		t.Errorf("esperado 1 tentativa registrada, obtido %d", q.pending[1].Attempts)
+	}
+	if q.nextID != 4 {
+		t.Errorf("esperado próximo ID 4, obtido %d", q.nextID)
+	}
+
+	ready = q.ready(now.Add(time.Minute))
+	if len(ready) != 1 || ready[0].ID != 1 {
+		t.Errorf("esperado o evento 1 pronto após o backoff")
+	}
+}
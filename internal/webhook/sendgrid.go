@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+)
+
+// sendGridProvider Event Webhook da SendGrid (email)
+type sendGridProvider struct {
+	info ProviderInfo
+}
+
+// NewSendGridEmailProvider provedor do webhook SendGrid de email (POST /webhook/sendgrid/email)
+func NewSendGridEmailProvider() Provider {
+	return &sendGridProvider{info: ProviderInfo{
+		Name:      "sendgrid-email",
+		Label:     "WebHookSendGrid",
+		Procedure: "SendGridEmailWebhook",
+		Channel:   ChannelEmail,
+		Table:     "emailmensagem",
+		Source:    "WebHookSendGrid",
+	}}
+}
+
+// Info identifica o provedor
+func (p *sendGridProvider) Info() ProviderInfo {
+	return p.info
+}
+
+// Parse interpreta o lote de eventos SendGrid (array JSON)
+func (p *sendGridProvider) Parse(body []byte) ([]*Event, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(body, &elements); err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, 0, len(elements))
+	for _, element := range elements {
+		var sgEvent models.SendGridEvent
+		if err := json.Unmarshal(element, &sgEvent); err != nil {
+			return nil, err
+		}
+
+		// sg_message_id = <X-Message-Id retornado no envio>.<sufixo do servidor de entrega>
+		messageID := sgEvent.SGMessageID
+		if i := strings.Index(messageID, "."); i > 0 {
+			messageID = messageID[:i]
+		}
+
+		code := strings.ToLower(sgEvent.Event)
+		if code == "bounce" && strings.EqualFold(sgEvent.Type, "blocked") {
+			code = "blocked"
+		}
+
+		event := &Event{
+			Provider:  p.info.Name,
+			Type:      sgEvent.Event,
+			MessageID: messageID,
+			Recipient: sgEvent.Email,
+			Code:      code,
+			Raw:       string(element),
+		}
+		switch {
+		case sgEvent.Reason != "":
+			event.Description = sgEvent.Reason
+		case sgEvent.Response != "":
+			event.Description = sgEvent.Response
+		case sgEvent.URL != "":
+			event.Description = sgEvent.URL
+		}
+		if sgEvent.Type != "" || sgEvent.Status != "" {
+			event.Cause = fmt.Sprintf("%s (%s)", sgEvent.Type, sgEvent.Status)
+		}
+		if sgEvent.Timestamp > 0 {
+			event.Timestamp = time.Unix(sgEvent.Timestamp, 0).UTC().Format(time.RFC3339)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Status mapeia o evento SendGrid para o status do CRM pelo mapa de status (StatusMap)
+// A SendGrid só envia bounce para falhas permanentes; motivos classificados como temporários
+// (regras [bounce] email_soft) usam a regra soft_bounce
+func (p *sendGridProvider) Status(event *Event) (Status, bool) {
+	key := event.Code
+	if key == "bounce" && GetBounceClassifier().Classify(p.info.Channel, event) == BounceSoft {
+		key = KeySoftBounce
+	}
+	return GetStatusMap().Resolve(p.info.Name, key, event)
+}
+
+// FindMessage localiza a mensagem pelo X-Message-Id da SendGrid (Emsgapimsgid)
+func (p *sendGridProvider) FindMessage(db *database.Database, event *Event) (*Message, error) {
+	emailData, err := db.GetEmailByAPIMessageID(event.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Numero: emailData.EmailNumero, CliCodigo: emailData.CliCodigo, LogsApiId: emailData.LogsApiId}, nil
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import "testing"
+
+// TestSendGridProvider garante a leitura do lote SendGrid e o mapeamento para os status do CRM
+func TestSendGridProvider(t *testing.T) {
+	body := []byte(`[` +
+		`{"email":"cliente@exemplo.com","timestamp":1700000000,"event":"delivered","sg_message_id":"abc123.filter0001.1.0","response":"250 OK"},` +
+		`{"email":"cliente@exemplo.com","timestamp":1700000060,"event":"bounce","sg_message_id":"abc123.filter0001.1.0","reason":"550 User unknown","type":"bounce","status":"5.1.1"},` +
+		`{"email":"cliente@exemplo.com","timestamp":1700000090,"event":"bounce","sg_message_id":"def456.filter0001.1.0","reason":"Blocked","type":"blocked","status":"4.0.0"}]`)
+
+	provider := NewSendGridEmailProvider()
+	events, err := provider.Parse(body)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("esperado 3 eventos, obtido %d (erro: %v)", len(events), err)
+	}
+	if events[0].MessageID != "abc123" || events[0].Timestamp != "2023-11-14T22:13:20Z" || events[0].Description != "250 OK" {
+		t.Errorf("evento normalizado incorreto: %+v", events[0])
+	}
+	if events[2].Code != "blocked" {
+		t.Errorf("esperado código blocked para bounce do tipo blocked, obtido %s", events[2].Code)
+	}
+
+	status, _ := provider.Status(events[1])
+	if esperado := "125 - Inconsistente: [550 User unknown][ bounce (5.1.1)]"; status.Codigo != StatusInconsistente || status.Descricao != esperado {
+		t.Errorf("status %d %q, esperado %d %q", status.Codigo, status.Descricao, StatusInconsistente, esperado)
+	}
+
+	tests := []struct {
+		code        string
+		esperado    int
+		historyOnly bool
+	}{
+		{"processed", StatusAgendado, false},
+		{"open", StatusAberto, false},
+		{"click", StatusAberto, false},
+		{"dropped", StatusNaoEntregue, false},
+		{"blocked", StatusNaoEntregue, false},
+		{"spamreport", 0, true},
+		{"unsubscribe", 0, true},
+	}
+
+	for _, tt := range tests {
+		status, ok := provider.Status(&Event{Code: tt.code})
+		if !ok || status.Codigo != tt.esperado || status.HistoryOnly != tt.historyOnly {
+			t.Errorf("%s: status %d (historyOnly=%v), esperado %d (historyOnly=%v)", tt.code, status.Codigo, status.HistoryOnly, tt.esperado, tt.historyOnly)
+		}
+	}
+
+	if _, ok := provider.Status(&Event{Code: "deferred"}); ok {
+		t.Errorf("esperado evento deferred fora do escopo")
+	}
+}
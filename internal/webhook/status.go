@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"strings"
+	"time"
+)
+
+// Status do ciclo de vida da mensagem (logsApiStatus / EMsgStsEnvio / smsstsenvio)
+const (
+	StatusAgendado      = 121
+	StatusEntregue      = 122
+	StatusAberto        = 123
+	StatusNaoEntregue   = 124
+	StatusInconsistente = 125
+)
+
+// statusTransitions transições permitidas a partir de cada status do ciclo de vida
+// Status fora da tabela (ex: mensagem ainda não enviada ao provedor) aceitam qualquer evento.
+var statusTransitions = map[int][]int{
+	StatusAgendado:      {StatusEntregue, StatusAberto, StatusNaoEntregue, StatusInconsistente},
+	StatusEntregue:      {StatusAberto, StatusInconsistente},
+	StatusAberto:        {},
+	StatusNaoEntregue:   {StatusInconsistente},
+	StatusInconsistente: {},
+}
+
+// Transition resultado da avaliação de um evento de status contra o status atual da mensagem
+type Transition int
+
+const (
+	// TransitionAdvance o status atual avança para o status do evento
+	TransitionAdvance Transition = iota
+	// TransitionRepeated o evento repete o status atual
+	TransitionRepeated
+	// TransitionOutOfOrder transição permitida, mas o evento é anterior ao último evento aplicado
+	TransitionOutOfOrder
+	// TransitionRegression transição não permitida (ex: 121 depois de 122) - anomalia
+	TransitionRegression
+)
+
+// String retorna a descrição da transição para os logs
+func (t Transition) String() string {
+	switch t {
+	case TransitionAdvance:
+		return "avanço"
+	case TransitionRepeated:
+		return "repetido"
+	case TransitionOutOfOrder:
+		return "fora de ordem"
+	case TransitionRegression:
+		return "regressão"
+	}
+	return "desconhecida"
+}
+
+// Advances indica se o status atual da mensagem deve ser atualizado
+func (t Transition) Advances() bool {
+	return t == TransitionAdvance
+}
+
+// EvaluateTransition avalia o evento (novo, dataNovo) contra o status atual da mensagem
+// dataAtual é o timestamp do provedor do último evento aplicado; datas zero não são comparadas.
+func EvaluateTransition(atual int, dataAtual time.Time, novo int, dataNovo time.Time) Transition {
+	if novo == atual {
+		return TransitionRepeated
+	}
+
+	if allowed, known := statusTransitions[atual]; known {
+		permitted := false
+		for _, status := range allowed {
+			if status == novo {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return TransitionRegression
+		}
+	}
+
+	if !dataAtual.IsZero() && !dataNovo.IsZero() && dataNovo.Before(dataAtual) {
+		return TransitionOutOfOrder
+	}
+
+	return TransitionAdvance
+}
+
+// ParseEventTime converte o timestamp do evento informado pelo provedor (RFC 3339)
+// Retorna zero se o timestamp estiver ausente ou em formato desconhecido
+func ParseEventTime(timestamp string) time.Time {
+	timestamp = strings.TrimSpace(timestamp)
+	if timestamp == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		return t
+	}
+	return time.Time{}
+}
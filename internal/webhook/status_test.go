@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEvaluateTransition garante as transições permitidas e a comparação dos timestamps do provedor
+func TestEvaluateTransition(t *testing.T) {
+	t1 := time.Date(2025, 11, 26, 14, 30, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+
+	tests := []struct {
+		nome      string
+		atual     int
+		dataAtual time.Time
+		novo      int
+		dataNovo  time.Time
+		esperado  Transition
+	}{
+		{"primeiro evento", 0, time.Time{}, StatusAgendado, t1, TransitionAdvance},
+		{"agendado para entregue", StatusAgendado, t1, StatusEntregue, t2, TransitionAdvance},
+		{"entregue para aberto sem timestamp", StatusEntregue, time.Time{}, StatusAberto, time.Time{}, TransitionAdvance},
+		{"agendado tardio após entregue", StatusEntregue, t2, StatusAgendado, t1, TransitionRegression},
+		{"agendado após aberto", StatusAberto, t1, StatusAgendado, t2, TransitionRegression},
+		{"não entregue para inconsistente", StatusNaoEntregue, t1, StatusInconsistente, t2, TransitionAdvance},
+		{"entregue com timestamp anterior", StatusAgendado, t2, StatusEntregue, t1, TransitionOutOfOrder},
+		{"status repetido", StatusEntregue, t1, StatusEntregue, t2, TransitionRepeated},
+	}
+
+	for _, tt := range tests {
+		if got := EvaluateTransition(tt.atual, tt.dataAtual, tt.novo, tt.dataNovo); got != tt.esperado {
+			t.Errorf("%s: EvaluateTransition = %s, esperado %s", tt.nome, got, tt.esperado)
+		}
+	}
+}
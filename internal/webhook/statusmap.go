@@ -0,0 +1,275 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wsicrmrest/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// Chaves derivadas do mapa de status (além do código do evento no provedor)
+const (
+	KeyHardBounce       = "hard_bounce"       // Falha classificada como bounce permanente
+	KeySoftBounce       = "soft_bounce"       // Bounce classificado como temporário (SendGrid)
+	KeyTemplateRejected = "template_rejected" // Template WhatsApp rejeitado
+	KeyPolicyFailure    = "policy_failure"    // Falha de política do WhatsApp
+	KeyNotOnWhatsApp    = "not_on_whatsapp"   // Número válido, mas sem conta no WhatsApp
+)
+
+// StatusRule regra de mapeamento de um evento de webhook para o status do CRM
+type StatusRule struct {
+	Provider   string `json:"provider"`    // Provedor e canal, ex: zenvia-email
+	Event      string `json:"event"`       // Código do evento (minúsculo) ou chave derivada, ex: hard_bounce
+	Codigo     int    `json:"status"`      // Status do CRM (0 = apenas histórico)
+	Descricao  string `json:"description"` // Modelo da descrição ({descricao}, {detalhes})
+	Tag        string `json:"tag"`         // Tag gravada em logsApiHistorico
+	Ocorrencia bool   `json:"occurrence"`  // Gera ocorrência de contato inconsistente
+	Ativo      bool   `json:"active"`      // Regras inativas ignoram o evento
+	Origem     string `json:"source"`      // padrao ou WSWEBHOOKSTATUS
+}
+
+// statusRule monta uma regra padrão ativa
+func statusRule(provider, event string, codigo int, descricao, tag string, ocorrencia bool) StatusRule {
+	return StatusRule{Provider: provider, Event: event, Codigo: codigo, Descricao: descricao, Tag: tag,
+		Ocorrencia: ocorrencia, Ativo: true, Origem: "padrao"}
+}
+
+// Modelos de descrição padrão
+const (
+	descAgendado      = "121 - Agendado: [{descricao}]"
+	descEntregue      = "122 - Entregue: [{descricao}]"
+	descAberto        = "123 - Aberto: [{descricao}]"
+	descNaoEntregue   = "124 - Não Entregue: [{descricao}][{detalhes}]"
+	descInconsistente = "125 - Inconsistente: [{descricao}][{detalhes}]"
+)
+
+// defaultStatusRules regras embutidas, usadas quando WSWEBHOOKSTATUS não possui a regra
+var defaultStatusRules = []StatusRule{
+	statusRule("zenvia-email", "sent", StatusAgendado, descAgendado, "AgendadoProvedor", false),
+	statusRule("zenvia-email", "delivered", StatusEntregue, descEntregue, "Entregue", false),
+	statusRule("zenvia-email", "read", StatusAberto, descAberto, "Aberto", false),
+	statusRule("zenvia-email", "clicked", StatusAberto, descAberto, "Aberto", false),
+	statusRule("zenvia-email", "rejected", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-email", "not_delivered", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-email", KeyHardBounce, StatusInconsistente, descInconsistente, "Inconsistente", true),
+
+	statusRule("zenvia-sms", "sent", StatusAgendado, descAgendado, "AgendadoProvedor", false),
+	statusRule("zenvia-sms", "delivered", StatusEntregue, descEntregue, "Entregue", false),
+	statusRule("zenvia-sms", "rejected", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-sms", "not_delivered", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-sms", KeyHardBounce, StatusInconsistente, descInconsistente, "Inconsistente", true),
+
+	statusRule("zenvia-whatsapp", "sent", StatusAgendado, descAgendado, "AgendadoProvedor", false),
+	statusRule("zenvia-whatsapp", "delivered", StatusEntregue, descEntregue, "Entregue", false),
+	statusRule("zenvia-whatsapp", "read", StatusAberto, "123 - Lido: [{descricao}]", "Lido", false),
+	statusRule("zenvia-whatsapp", "rejected", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-whatsapp", "not_delivered", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("zenvia-whatsapp", KeyTemplateRejected, StatusNaoEntregue, "124 - Template Rejeitado: [{descricao}][{detalhes}]", "TemplateRejeitado", false),
+	statusRule("zenvia-whatsapp", KeyPolicyFailure, StatusNaoEntregue, "124 - Política WhatsApp: [{descricao}][{detalhes}]", "PoliticaWhatsApp", false),
+	statusRule("zenvia-whatsapp", KeyNotOnWhatsApp, StatusNaoEntregue, "124 - Sem WhatsApp: [{descricao}][{detalhes}]", "SemWhatsApp", false),
+	statusRule("zenvia-whatsapp", KeyHardBounce, StatusInconsistente, descInconsistente, "Inconsistente", true),
+
+	statusRule("sendgrid-email", "processed", StatusAgendado, descAgendado, "AgendadoProvedor", false),
+	statusRule("sendgrid-email", "delivered", StatusEntregue, descEntregue, "Entregue", false),
+	statusRule("sendgrid-email", "open", StatusAberto, descAberto, "Aberto", false),
+	statusRule("sendgrid-email", "click", StatusAberto, descAberto, "Aberto", false),
+	statusRule("sendgrid-email", "bounce", StatusInconsistente, descInconsistente, "Inconsistente", true),
+	statusRule("sendgrid-email", KeySoftBounce, StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("sendgrid-email", "blocked", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("sendgrid-email", "dropped", StatusNaoEntregue, descNaoEntregue, "NãoEntregue", false),
+	statusRule("sendgrid-email", "spamreport", 0, "Spam reportado pelo destinatário", "SpamReportado", false),
+	statusRule("sendgrid-email", "unsubscribe", 0, "Descadastro solicitado pelo destinatário", "Descadastro", false),
+}
+
+// StatusMap mapa de eventos de webhook para status do CRM
+// As regras embutidas podem ser substituídas ou complementadas pela tabela WSWEBHOOKSTATUS,
+// recarregada periodicamente e sob demanda pela API de administração.
+type StatusMap struct {
+	db       *database.Database
+	logger   *zap.SugaredLogger
+	interval time.Duration
+	rules    map[string]StatusRule // provedor|evento -> regra
+	loadedAt time.Time
+	mu       sync.RWMutex
+}
+
+var (
+	statusMap        *StatusMap
+	statusMapOnce    sync.Once
+	defaultStatusMap = &StatusMap{rules: buildStatusRules(nil, nil)}
+)
+
+// NewStatusMap cria (uma única vez) o mapa de status e inicia a recarga periódica de WSWEBHOOKSTATUS
+func NewStatusMap(db *database.Database, interval time.Duration, logger *zap.SugaredLogger) *StatusMap {
+	statusMapOnce.Do(func() {
+		statusMap = &StatusMap{
+			db:       db,
+			logger:   logger,
+			interval: interval,
+			rules:    buildStatusRules(nil, nil),
+		}
+
+		if _, err := statusMap.Reload(); err != nil {
+			logger.Errorw("Falha ao carregar WSWEBHOOKSTATUS - usando mapa de status padrão", "error", err)
+		}
+		go statusMap.refreshRoutine()
+
+		logger.Infow("Mapa de status de webhooks inicializado",
+			"refresh_interval", interval)
+	})
+
+	return statusMap
+}
+
+// GetStatusMap retorna o mapa de status configurado (regras padrão se não inicializado)
+func GetStatusMap() *StatusMap {
+	if statusMap != nil {
+		return statusMap
+	}
+	return defaultStatusMap
+}
+
+// refreshRoutine recarrega o mapa periodicamente
+func (m *StatusMap) refreshRoutine() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := m.Reload(); err != nil {
+			m.logger.Errorw("Falha ao atualizar mapa de status de webhooks", "error", err)
+		}
+	}
+}
+
+// Reload recarrega as regras de WSWEBHOOKSTATUS e retorna quantas foram aplicadas
+// Em caso de falha o mapa anterior é mantido
+func (m *StatusMap) Reload() (int, error) {
+	if m.db == nil {
+		return 0, errors.New("mapa de status não inicializado")
+	}
+
+	rows, err := m.db.ListWebhookStatusRules()
+	if err != nil {
+		return 0, err
+	}
+
+	var invalid []string
+	rules := buildStatusRules(rows, func(row database.WebhookStatusRuleData) {
+		invalid = append(invalid, row.Provedor+"|"+row.Evento)
+	})
+	if len(invalid) > 0 {
+		m.logger.Warnw("Regras de WSWEBHOOKSTATUS ignoradas - status inválido",
+			"regras", invalid)
+	}
+
+	m.mu.Lock()
+	m.rules = rules
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+
+	return len(rows) - len(invalid), nil
+}
+
+// buildStatusRules aplica as regras do banco sobre as regras padrão
+// Regras com status fora do ciclo de vida (0 ou 121 a 125) são ignoradas e informadas a onInvalid
+func buildStatusRules(rows []database.WebhookStatusRuleData, onInvalid func(database.WebhookStatusRuleData)) map[string]StatusRule {
+	rules := make(map[string]StatusRule, len(defaultStatusRules)+len(rows))
+	for _, rule := range defaultStatusRules {
+		rules[rule.Provider+"|"+rule.Event] = rule
+	}
+
+	for _, row := range rows {
+		if _, ok := statusTransitions[row.Status]; !ok && row.Status != 0 {
+			if onInvalid != nil {
+				onInvalid(row)
+			}
+			continue
+		}
+
+		provider := strings.ToLower(strings.TrimSpace(row.Provedor))
+		event := strings.ToLower(strings.TrimSpace(row.Evento))
+		rules[provider+"|"+event] = StatusRule{
+			Provider:   provider,
+			Event:      event,
+			Codigo:     row.Status,
+			Descricao:  row.Descricao,
+			Tag:        row.Tag,
+			Ocorrencia: row.Ocorrencia,
+			Ativo:      row.Ativo,
+			Origem:     "WSWEBHOOKSTATUS",
+		}
+	}
+
+	return rules
+}
+
+// Resolve mapeia o evento para o status do CRM pela regra do provedor e da chave
+// (false se não houver regra ativa - evento fora do escopo)
+func (m *StatusMap) Resolve(provider, key string, event *Event) (Status, bool) {
+	m.mu.RLock()
+	rule, ok := m.rules[provider+"|"+key]
+	m.mu.RUnlock()
+
+	if !ok || !rule.Ativo {
+		return Status{}, false
+	}
+
+	sDescricaoEvento := event.Description
+	if len(sDescricaoEvento) > 1000 {
+		sDescricaoEvento = sDescricaoEvento[:1000]
+	}
+	sDetalhes := ""
+	if event.Cause != "" {
+		sDetalhes = " " + event.Cause
+	}
+
+	descricao := strings.NewReplacer(
+		"{descricao}", sDescricaoEvento,
+		"{detalhes}", sDetalhes,
+	).Replace(rule.Descricao)
+	if descricao == "" {
+		descricao = fmt.Sprintf("%d - %s: [%s]", rule.Codigo, rule.Tag, sDescricaoEvento)
+	}
+
+	return Status{
+		Codigo:      rule.Codigo,
+		Descricao:   descricao,
+		Tag:         rule.Tag,
+		Ocorrencia:  rule.Ocorrencia,
+		HistoryOnly: rule.Codigo == 0,
+	}, true
+}
+
+// StatusMapSnapshot regras em uso (API de administração)
+type StatusMapSnapshot struct {
+	LoadedAt *time.Time   `json:"loaded_at,omitempty"` // Última carga de WSWEBHOOKSTATUS
+	Rules    []StatusRule `json:"rules"`
+}
+
+// Snapshot retorna as regras em uso ordenadas por provedor e evento
+func (m *StatusMap) Snapshot() StatusMapSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := StatusMapSnapshot{Rules: make([]StatusRule, 0, len(m.rules))}
+	if !m.loadedAt.IsZero() {
+		loadedAt := m.loadedAt
+		snapshot.LoadedAt = &loadedAt
+	}
+	for _, rule := range m.rules {
+		snapshot.Rules = append(snapshot.Rules, rule)
+	}
+	sort.Slice(snapshot.Rules, func(i, j int) bool {
+		if snapshot.Rules[i].Provider != snapshot.Rules[j].Provider {
+			return snapshot.Rules[i].Provider < snapshot.Rules[j].Provider
+		}
+		return snapshot.Rules[i].Event < snapshot.Rules[j].Event
+	})
+
+	return snapshot
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"testing"
+	"wsicrmrest/internal/database"
+)
+
+// TestStatusMapRules garante a sobreposição das regras de WSWEBHOOKSTATUS sobre as regras padrão
+func TestStatusMapRules(t *testing.T) {
+	var invalidas int
+	m := &StatusMap{rules: buildStatusRules([]database.WebhookStatusRuleData{
+		// Novo evento para SMS
+		{Provedor: "zenvia-sms", Evento: "read", Status: StatusAberto, Descricao: "123 - Lido: [{descricao}]", Tag: "Lido", Ativo: true},
+		// Cliques deixam de ser processados
+		{Provedor: "zenvia-email", Evento: "clicked", Status: StatusAberto, Tag: "Aberto", Ativo: false},
+		// Rejeição passa a gerar ocorrência
+		{Provedor: "ZENVIA-EMAIL", Evento: "Rejected", Status: StatusInconsistente, Descricao: "125 - Rejeitado:{detalhes}", Tag: "Inconsistente", Ocorrencia: true, Ativo: true},
+		// Status fora do ciclo de vida
+		{Provedor: "zenvia-sms", Evento: "sent", Status: 999, Ativo: true},
+	}, func(database.WebhookStatusRuleData) { invalidas++ })}
+
+	if invalidas != 1 {
+		t.Errorf("esperado 1 regra inválida, obtido %d", invalidas)
+	}
+
+	event := &Event{Description: "Lida", Cause: "MAILBOX (Recusado)"}
+	tests := []struct {
+		provider   string
+		key        string
+		esperado   int
+		ok         bool
+		ocorrencia bool
+		descricao  string
+	}{
+		{"zenvia-sms", "read", StatusAberto, true, false, "123 - Lido: [Lida]"},
+		{"zenvia-sms", "sent", StatusAgendado, true, false, "121 - Agendado: [Lida]"},
+		{"zenvia-email", "clicked", 0, false, false, ""},
+		{"zenvia-email", "rejected", StatusInconsistente, true, true, "125 - Rejeitado: MAILBOX (Recusado)"},
+		{"zenvia-email", KeyHardBounce, StatusInconsistente, true, true, "125 - Inconsistente: [Lida][ MAILBOX (Recusado)]"},
+		{"sendgrid-email", "spamreport", 0, true, false, "Spam reportado pelo destinatário"},
+		{"zenvia-whatsapp", "unknown", 0, false, false, ""},
+	}
+
+	for _, tt := range tests {
+		status, ok := m.Resolve(tt.provider, tt.key, event)
+		if ok != tt.ok || status.Codigo != tt.esperado || status.Ocorrencia != tt.ocorrencia || status.Descricao != tt.descricao {
+			t.Errorf("%s %s: status %d %q (ok=%v, ocorrencia=%v), esperado %d %q (ok=%v, ocorrencia=%v)",
+				tt.provider, tt.key, status.Codigo, status.Descricao, ok, status.Ocorrencia,
+				tt.esperado, tt.descricao, tt.ok, tt.ocorrencia)
+		}
+	}
+}
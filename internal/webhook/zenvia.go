@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"wsicrmrest/internal/database"
+	"wsicrmrest/internal/models"
+)
+
+// zenviaProvider webhooks de status da Zenvia (email e SMS)
+type zenviaProvider struct {
+	info ProviderInfo
+}
+
+// NewZenviaEmailProvider provedor do webhook Zenvia de email (POST /webhook/zenvia/email)
+func NewZenviaEmailProvider() Provider {
+	return &zenviaProvider{info: ProviderInfo{
+		Name:      "zenvia-email",
+		Label:     "WebHookZenvia Email",
+		Procedure: "ZenviaEmailWebhook",
+		Channel:   ChannelEmail,
+		Table:     "emailmensagem",
+		EventType: "message_status",
+		Source:    "Zenvia",
+	}}
+}
+
+// NewZenviaSMSProvider provedor do webhook Zenvia de SMS (POST /webhook/zenvia/sms)
+func NewZenviaSMSProvider() Provider {
+	return &zenviaProvider{info: ProviderInfo{
+		Name:      "zenvia-sms",
+		Label:     "WebHookZenvia",
+		Procedure: "ZenviaSMSWebhook",
+		Channel:   ChannelSMS,
+		Table:     "smsmensagem",
+		EventType: "message_status",
+		Source:    "Zenvia",
+	}}
+}
+
+// NewZenviaWhatsAppProvider provedor do webhook Zenvia de WhatsApp (POST /webhook/zenvia/whatsapp)
+func NewZenviaWhatsAppProvider() Provider {
+	return &zenviaProvider{info: ProviderInfo{
+		Name:      "zenvia-whatsapp",
+		Label:     "WebHookZenvia WhatsApp",
+		Procedure: "ZenviaWhatsAppWebhook",
+		Channel:   ChannelWhatsApp,
+		Table:     "whatsappmensagem",
+		EventType: "message_status",
+		Source:    "Zenvia",
+	}}
+}
+
+// Info identifica o provedor
+func (p *zenviaProvider) Info() ProviderInfo {
+	return p.info
+}
+
+// Parse interpreta o callback Zenvia (um evento por requisição)
+func (p *zenviaProvider) Parse(body []byte) ([]*Event, error) {
+	var request models.ZenviaWebhookRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+
+	event := &Event{
+		Provider:    p.info.Name,
+		Type:        request.Type,
+		MessageID:   request.Message.MessageId,
+		ExternalID:  request.Message.ExternalID,
+		Recipient:   request.Message.To,
+		Code:        strings.ToLower(request.MessageStatus.Code),
+		Description: request.MessageStatus.Description,
+		Timestamp:   request.EventTimestamp(),
+		Raw:         string(body),
+	}
+	if len(request.MessageStatus.Causes) > 0 {
+		cause := request.MessageStatus.Causes[0]
+		event.Cause = fmt.Sprintf("%s (%s)", cause.Reason, cause.Details)
+		event.Reason = strings.ToUpper(cause.Reason)
+	}
+
+	return []*Event{event}, nil
+}
+
+// Status mapeia o evento Zenvia para o status do CRM pelo mapa de status (StatusMap)
+func (p *zenviaProvider) Status(event *Event) (Status, bool) {
+	return GetStatusMap().Resolve(p.info.Name, p.statusKey(event), event)
+}
+
+// statusKey chave do evento no mapa de status: o código do status Zenvia ou, nas falhas de
+// entrega, a classificação do motivo (bounce permanente, template ou política do WhatsApp)
+func (p *zenviaProvider) statusKey(event *Event) string {
+	if event.Code != "rejected" && event.Code != "not_delivered" {
+		return event.Code
+	}
+
+	// Bounce permanente (caixa ou número inexistente): contato inconsistente
+	if GetBounceClassifier().Classify(p.info.Channel, event) == BounceHard {
+		return KeyHardBounce
+	}
+	if p.info.Channel == ChannelWhatsApp {
+		if key := whatsAppFailureKey(event.Reason); key != "" {
+			return key
+		}
+	}
+	return event.Code
+}
+
+// FindMessage localiza a mensagem pelo ID da Zenvia (Emsgapimsgid / SMSAPIID / WPPAPIID)
+// Se o ID não corresponder, tenta o externalId informado no envio (código da mensagem no CRM)
+func (p *zenviaProvider) FindMessage(db *database.Database, event *Event) (*Message, error) {
+	message, err := p.findByMessageID(db, event.MessageID)
+	if !errors.Is(err, sql.ErrNoRows) || event.ExternalID == "" {
+		return message, err
+	}
+
+	codigo, convErr := strconv.Atoi(strings.TrimSpace(event.ExternalID))
+	if convErr != nil {
+		return nil, err
+	}
+	return p.findByCodigo(db, codigo)
+}
+
+// findByMessageID localiza a mensagem do canal pelo ID da Zenvia
+func (p *zenviaProvider) findByMessageID(db *database.Database, messageID string) (*Message, error) {
+	switch p.info.Channel {
+	case ChannelSMS:
+		smsData, err := db.GetSMSByMessageID(messageID)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Numero: smsData.SMSNumero, CliCodigo: smsData.CliCodigo, LogsApiId: smsData.LogsApiId}, nil
+	case ChannelWhatsApp:
+		whatsAppData, err := db.GetWhatsAppByMessageID(messageID)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Numero: whatsAppData.WhatsAppNumero, CliCodigo: whatsAppData.CliCodigo, LogsApiId: whatsAppData.LogsApiId}, nil
+	}
+
+	emailData, err := db.GetEmailByAPIMessageID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Numero: emailData.EmailNumero, CliCodigo: emailData.CliCodigo, LogsApiId: emailData.LogsApiId}, nil
+}
+
+// findByCodigo localiza a mensagem do canal pelo código no CRM (externalId)
+func (p *zenviaProvider) findByCodigo(db *database.Database, codigo int) (*Message, error) {
+	switch p.info.Channel {
+	case ChannelSMS:
+		smsData, err := db.GetSMSByCodigo(codigo)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Numero: smsData.SMSNumero, CliCodigo: smsData.CliCodigo, LogsApiId: smsData.LogsApiId}, nil
+	case ChannelWhatsApp:
+		whatsAppData, err := db.GetWhatsAppByCodigo(codigo)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Numero: whatsAppData.WhatsAppNumero, CliCodigo: whatsAppData.CliCodigo, LogsApiId: whatsAppData.LogsApiId}, nil
+	}
+
+	emailData, err := db.GetEmailByCodigo(codigo)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Numero: emailData.EmailNumero, CliCodigo: emailData.CliCodigo, LogsApiId: emailData.LogsApiId}, nil
+}
+
+// whatsAppPolicyReasons falhas de política do WhatsApp (janela de 24 horas, opt-out, limites)
+var whatsAppPolicyReasons = []string{"POLICY", "WINDOW", "OPT_OUT", "OPTOUT", "SPAM", "RATE_LIMIT", "BLOCKED"}
+
+// whatsAppNotRegisteredReasons número sem conta no WhatsApp: o celular segue válido para SMS
+var whatsAppNotRegisteredReasons = []string{"NOT_ON_WHATSAPP", "NOT_A_WHATSAPP_NUMBER"}
+
+// whatsAppFailureKey classifica a falha de entrega do WhatsApp que não é bounce permanente
+// Template rejeitado, violação de política e número sem WhatsApp têm regras próprias para os relatórios
+func whatsAppFailureKey(reason string) string {
+	for _, notRegistered := range whatsAppNotRegisteredReasons {
+		if strings.Contains(reason, notRegistered) {
+			return KeyNotOnWhatsApp
+		}
+	}
+
+	if strings.Contains(reason, "TEMPLATE") {
+		return KeyTemplateRejected
+	}
+
+	for _, policy := range whatsAppPolicyReasons {
+		if strings.Contains(reason, policy) {
+			return KeyPolicyFailure
+		}
+	}
+	return ""
+}
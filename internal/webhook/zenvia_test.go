@@ -0,0 +1,70 @@
+package webhook
+
+import "testing"
+
+// TestZenviaProviderStatus garante o mapeamento dos eventos Zenvia para os status do CRM
+func TestZenviaProviderStatus(t *testing.T) {
+	body := []byte(`{"type":"MESSAGE_STATUS","message":{"id":"msg-1","to":"cliente@exemplo.com"},` +
+		`"messageStatus":{"code":"NOT_DELIVERED","description":"Falha","causes":[{"reason":"MAILBOX_FULL","details":"Caixa cheia"}]}}`)
+
+	events, err := NewZenviaEmailProvider().Parse(body)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("esperado 1 evento, obtido %d (erro: %v)", len(events), err)
+	}
+	if events[0].MessageID != "msg-1" || events[0].Code != "not_delivered" || events[0].Raw != string(body) {
+		t.Errorf("evento normalizado incorreto: %+v", events[0])
+	}
+
+	tests := []struct {
+		provider Provider
+		code     string
+		esperado int
+	}{
+		{NewZenviaEmailProvider(), "sent", StatusAgendado},
+		{NewZenviaEmailProvider(), "delivered", StatusEntregue},
+		{NewZenviaEmailProvider(), "clicked", StatusAberto},
+		{NewZenviaEmailProvider(), "not_delivered", StatusNaoEntregue},
+		{NewZenviaSMSProvider(), "rejected", StatusNaoEntregue},
+		{NewZenviaSMSProvider(), "read", 0},
+		{NewZenviaEmailProvider(), "unknown", 0},
+		{NewZenviaWhatsAppProvider(), "read", StatusAberto},
+		{NewZenviaWhatsAppProvider(), "clicked", 0},
+	}
+
+	for _, tt := range tests {
+		status, ok := tt.provider.Status(&Event{Code: tt.code, Cause: "MAILBOX_FULL (Caixa cheia)"})
+		if ok != (tt.esperado != 0) || status.Codigo != tt.esperado {
+			t.Errorf("%s %s: status %d (ok=%v), esperado %d", tt.provider.Info().Name, tt.code, status.Codigo, ok, tt.esperado)
+		}
+	}
+
+	status, _ := NewZenviaEmailProvider().Status(events[0])
+	if esperado := "124 - Não Entregue: [Falha][ MAILBOX_FULL (Caixa cheia)]"; status.Descricao != esperado {
+		t.Errorf("descrição %q, esperado %q", status.Descricao, esperado)
+	}
+}
+
+// TestWhatsAppFailureStatus garante a classificação das falhas de entrega do WhatsApp
+func TestWhatsAppFailureStatus(t *testing.T) {
+	tests := []struct {
+		reason   string
+		esperado int
+		tag      string
+	}{
+		{"INVALID_NUMBER", StatusInconsistente, "Inconsistente"},
+		{"RECIPIENT_NOT_ON_WHATSAPP", StatusNaoEntregue, "SemWhatsApp"},
+		{"NOT_A_WHATSAPP_NUMBER", StatusNaoEntregue, "SemWhatsApp"},
+		{"TEMPLATE_REJECTED", StatusNaoEntregue, "TemplateRejeitado"},
+		{"OUTSIDE_24H_WINDOW", StatusNaoEntregue, "PoliticaWhatsApp"},
+		{"POLICY_VIOLATION", StatusNaoEntregue, "PoliticaWhatsApp"},
+		{"", StatusNaoEntregue, "NãoEntregue"},
+	}
+
+	provider := NewZenviaWhatsAppProvider()
+	for _, tt := range tests {
+		status, ok := provider.Status(&Event{Code: "not_delivered", Reason: tt.reason})
+		if !ok || status.Codigo != tt.esperado || status.Tag != tt.tag {
+			t.Errorf("%q: status %d %s, esperado %d %s", tt.reason, status.Codigo, status.Tag, tt.esperado, tt.tag)
+		}
+	}
+}